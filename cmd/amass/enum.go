@@ -29,11 +29,13 @@ import (
 	"github.com/OWASP/Amass/v3/datasrcs"
 	"github.com/OWASP/Amass/v3/enum"
 	"github.com/OWASP/Amass/v3/format"
+	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/netmap"
 	"github.com/caffix/stringset"
 	"github.com/fatih/color"
+	"github.com/google/uuid"
 )
 
 const enumUsageMsg = "enum [options] -d DOMAIN"
@@ -54,18 +56,27 @@ type enumArgs struct {
 	MaxDNSQueries     int
 	ResolverQPS       int
 	TrustedQPS        int
+	DoHQPS            int
+	DoTQPS            int
+	AuthQPS           int
 	MaxDepth          int
 	MinForRecursive   int
+	Metrics           string
 	Names             *stringset.Set
 	Ports             format.ParseInts
+	RecordTypes       format.ParseStrings
 	Resolvers         *stringset.Set
+	Resume            string
+	Sinks             format.ParseStrings
 	Trusted           *stringset.Set
 	Timeout           int
 	Options           struct {
 		Active          bool
 		Alterations     bool
+		Authoritative   bool
 		BruteForcing    bool
 		DemoMode        bool
+		DNSSEC          bool
 		IPs             bool
 		IPv4            bool
 		IPv6            bool
@@ -87,11 +98,14 @@ type enumArgs struct {
 		ConfigFile       string
 		Directory        string
 		Domains          format.ParseStrings
+		EventsOutput     string
 		ExcludedSrcs     string
 		IncludedSrcs     string
 		JSONOutput       string
 		LogFile          string
 		Names            format.ParseStrings
+		RecordArchive    string
+		ReplayArchive    string
 		Resolvers        format.ParseStrings
 		Trusted          format.ParseStrings
 		ScriptsDirectory string
@@ -114,19 +128,27 @@ func defineEnumArgumentFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	enumFlags.IntVar(&args.MaxDNSQueries, "dns-qps", 0, "Maximum number of DNS queries per second across all resolvers")
 	enumFlags.IntVar(&args.ResolverQPS, "rqps", 0, "Maximum number of DNS queries per second for each untrusted resolver")
 	enumFlags.IntVar(&args.TrustedQPS, "trqps", 0, "Maximum number of DNS queries per second for each trusted resolver")
+	enumFlags.IntVar(&args.DoHQPS, "doh-qps", 0, "Maximum number of DNS queries per second for each DNS-over-HTTPS resolver")
+	enumFlags.IntVar(&args.DoTQPS, "dot-qps", 0, "Maximum number of DNS queries per second for each DNS-over-TLS resolver")
+	enumFlags.IntVar(&args.AuthQPS, "aqps", 0, "Maximum number of DNS queries per second for each authoritative nameserver")
 	enumFlags.IntVar(&args.MaxDepth, "max-depth", 0, "Maximum number of subdomain labels for brute forcing")
 	enumFlags.IntVar(&args.MinForRecursive, "min-for-recursive", 1, "Subdomain labels seen before recursive brute forcing (Default: 1)")
+	enumFlags.StringVar(&args.Metrics, "metrics", "", "Address (e.g. 127.0.0.1:9090) that serves the Prometheus metrics")
 	enumFlags.Var(&args.Ports, "p", "Ports separated by commas (default: 80, 443)")
-	enumFlags.Var(args.Resolvers, "r", "IP addresses of untrusted DNS resolvers (can be used multiple times)")
-	enumFlags.Var(args.Resolvers, "tr", "IP addresses of trusted DNS resolvers (can be used multiple times)")
+	enumFlags.Var(args.Resolvers, "r", "IP addresses or DoH/DoT URLs of untrusted DNS resolvers (can be used multiple times)")
+	enumFlags.Var(&args.RecordTypes, "rt", "Additional DNS record types queried for each discovered name, separated by commas")
+	enumFlags.StringVar(&args.Resume, "resume", "", "UUID of an interrupted enumeration to continue from its last checkpoint")
+	enumFlags.Var(args.Trusted, "tr", "IP addresses or DoH/DoT URLs of trusted DNS resolvers (can be used multiple times)")
 	enumFlags.IntVar(&args.Timeout, "timeout", 0, "Number of minutes to let enumeration run before quitting")
 }
 
 func defineEnumOptionFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	var placeholder bool
 	enumFlags.BoolVar(&args.Options.Active, "active", false, "Attempt zone transfers and certificate name grabs")
+	enumFlags.BoolVar(&args.Options.Authoritative, "authoritative", false, "Validate names against the authoritative nameservers instead of the trusted resolvers")
 	enumFlags.BoolVar(&args.Options.BruteForcing, "brute", false, "Execute brute forcing after searches")
 	enumFlags.BoolVar(&args.Options.DemoMode, "demo", false, "Censor output to make it suitable for demonstrations")
+	enumFlags.BoolVar(&args.Options.DNSSEC, "dnssec", false, "Validate DNSSEC for the zones of resolved names and report their status")
 	enumFlags.BoolVar(&args.Options.IPs, "ip", false, "Show the IP addresses for discovered names")
 	enumFlags.BoolVar(&args.Options.IPv4, "ipv4", false, "Show the IPv4 addresses for discovered names")
 	enumFlags.BoolVar(&args.Options.IPv6, "ipv6", false, "Show the IPv6 addresses for discovered names")
@@ -172,6 +194,16 @@ func runEnumCommand(clArgs []string) {
 	}
 	createOutputDirectory(cfg)
 
+	if err := openHTTPArchive(args, cfg); err != nil {
+		r.Fprintf(color.Error, "Failed to open the HTTP archive: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := http.CloseArchive(); err != nil {
+			r.Fprintf(color.Error, "Failed to close the HTTP archive: %v\n", err)
+		}
+	}()
+
 	rLog, wLog := io.Pipe()
 	// Setup logging so that messages can be written to the file and used by the program
 	cfg.Log = log.New(wLog, "", log.Lmicroseconds)
@@ -181,6 +213,8 @@ func runEnumCommand(clArgs []string) {
 	}
 	// Start handling the log messages
 	go writeLogsAndMessages(rLog, logfile, args.Options.Verbose)
+	// The queries and timeouts of each resolver are reported with the metrics
+	cfg.ResolverMetrics = args.Metrics != ""
 	// Create the System that will provide architecture to this enumeration
 	sys, err := systems.NewLocalSystem(cfg)
 	if err != nil {
@@ -193,6 +227,12 @@ func runEnumCommand(clArgs []string) {
 		r.Fprintf(color.Error, "%v\n", err)
 		os.Exit(1)
 	}
+	if args.Metrics != "" {
+		if err := startMetricsServer(args.Metrics, sys); err != nil {
+			r.Fprintf(color.Error, "Failed to start the metrics server: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	// Expand data source category names into the associated source names
 	initializeSourceTags(sys.DataSources())
 	cfg.SourceFilter.Sources = expandCategoryNames(cfg.SourceFilter.Sources, generateCategoryMap(sys))
@@ -210,8 +250,8 @@ func runEnumCommand(clArgs []string) {
 	var outChans []chan *requests.Output
 	// This channel sends the signal for goroutines to terminate
 	done := make(chan struct{})
-	// Print output only if JSONOutput is not meant for STDOUT
-	if args.Filepaths.JSONOutput != "-" {
+	// Print output only if JSONOutput and EventsOutput are not meant for STDOUT
+	if args.Filepaths.JSONOutput != "-" && args.Filepaths.EventsOutput != "-" {
 		wg.Add(1)
 		// This goroutine will handle printing the output
 		printOutChan := make(chan *requests.Output, 10)
@@ -231,6 +271,31 @@ func runEnumCommand(clArgs []string) {
 	go saveJSONOutput(e, args, jsonOutChan, &wg)
 	outChans = append(outChans, jsonOutChan)
 
+	var recs *sinkRecords
+	if len(cfg.OutputSinks) > 0 {
+		sinks, err := openOutputSinks(cfg.OutputSinks)
+		if err != nil {
+			r.Fprintf(color.Error, "Failed to open the output sinks: %v\n", err)
+			os.Exit(1)
+		}
+		// The raw DNS records are obtained from the enumeration event stream
+		recs = newSinkRecords()
+
+		wg.Add(1)
+		// This goroutine will handle delivering the output to the sinks
+		sinkOutChan := make(chan *requests.Output, 10)
+		go writeSinkOutput(sinks, recs, sinkOutChan, &wg)
+		outChans = append(outChans, sinkOutChan)
+	}
+
+	eventsDone := make(chan struct{})
+	if recs != nil || args.Filepaths.EventsOutput != "" {
+		// This goroutine will handle the enumeration events as soon as the graph is written
+		go processEvents(e.Events(), args, recs, eventsDone)
+	} else {
+		close(eventsDone)
+	}
+
 	var ctx context.Context
 	var cancel context.CancelFunc
 	if args.Timeout == 0 {
@@ -260,9 +325,20 @@ func runEnumCommand(clArgs []string) {
 		r.Println(err)
 		os.Exit(1)
 	}
+	// Make sure the sinks have the records of the last names before they are delivered
+	<-eventsDone
 	// Let all the output goroutines know that the enumeration has finished
 	close(done)
 	wg.Wait()
+	// Report the activity of the data sources
+	stats := e.SourceStats()
+	format.FprintSourceStats(color.Error, stats)
+	saveSourceStats(e, args, stats)
+	// Report the DNSSEC posture of the zones
+	format.FprintDNSSECStatus(color.Error, e.DNSSECStatus())
+	if ctx.Err() != nil {
+		fmt.Fprintf(color.Error, "\n%s%s\n", yellow("The enumeration can be continued using -resume "), yellow(cfg.UUID.String()))
+	}
 	// If necessary, handle graph database migration
 	if len(e.Sys.GraphDatabases()) > 0 {
 		fmt.Fprintf(color.Error, "\n%s\n", green("The enumeration has finished"))
@@ -316,6 +392,10 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 	defineEnumArgumentFlags(enumCommand, &args)
 	defineEnumOptionFlags(enumCommand, &args)
 	defineEnumFilepathFlags(enumCommand, &args)
+	enumCommand.StringVar(&args.Filepaths.EventsOutput, "events", "", "Path to the NDJSON file where the findings are streamed as typed records")
+	enumCommand.StringVar(&args.Filepaths.RecordArchive, "record", "", "Path to the archive file where the HTTP requests and responses are recorded")
+	enumCommand.StringVar(&args.Filepaths.ReplayArchive, "replay", "", "Path to an archive file that HTTP responses are served from without network access")
+	enumCommand.Var(&args.Sinks, "sink", "Output sinks (type:target) that receive the findings (can be used multiple times)")
 
 	if len(clArgs) < 1 {
 		commandUsage(enumUsageMsg, enumCommand, enumBuf)
@@ -341,6 +421,10 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 			os.Exit(1)
 		}
 	}
+	if args.Filepaths.RecordArchive != "" && args.Filepaths.ReplayArchive != "" {
+		r.Fprintln(color.Error, "HTTP responses cannot be recorded and replayed at the same time")
+		os.Exit(1)
+	}
 	if args.Options.NoColor {
 		color.NoColor = true
 	}
@@ -397,7 +481,7 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 		r.Fprintln(color.Error, "Ports can only be scanned in the active mode")
 		os.Exit(1)
 	}
-	if len(cfg.Domains()) == 0 {
+	if len(cfg.Domains()) == 0 && !cfg.Resume {
 		r.Fprintln(color.Error, "Configuration error: No root domain names were provided")
 		os.Exit(1)
 	}
@@ -527,6 +611,22 @@ func saveJSONOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.O
 	}
 }
 
+func saveSourceStats(e *enum.Enumeration, args *enumArgs, stats []*requests.SourceStats) {
+	statsfile := filepath.Join(config.OutputDirectory(e.Config.Dir), "amass_sources.json")
+	if args.Filepaths.AllFilePrefix != "" {
+		statsfile = args.Filepaths.AllFilePrefix + "_sources.json"
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to encode the data source statistics: %v\n", err)
+		return
+	}
+	if err := ioutil.WriteFile(statsfile, data, 0644); err != nil {
+		r.Fprintf(color.Error, "Failed to save the data source statistics: %v\n", err)
+	}
+}
+
 func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, outputs []chan *requests.Output, done chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer func() {
@@ -545,8 +645,9 @@ func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, ou
 			if !o.Complete(e.Config.Passive) || !e.Config.IsDomainInScope(o.Name) {
 				continue
 			}
+			// Each output goroutine receives a copy that can be modified
 			for _, ch := range outputs {
-				ch <- o
+				ch <- o.Clone().(*requests.Output)
 			}
 		}
 	}
@@ -567,6 +668,36 @@ func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, ou
 	}
 }
 
+func processEvents(events <-chan enum.Event, args *enumArgs, recs *sinkRecords, done chan struct{}) {
+	defer close(done)
+
+	var rw *enum.RecordWriter
+	if path := args.Filepaths.EventsOutput; path == "-" {
+		rw = enum.NewRecordWriter(os.Stdout)
+	} else if path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			r.Fprintf(color.Error, "Failed to open the events output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			_ = f.Sync()
+			_ = f.Close()
+		}()
+
+		rw = enum.NewRecordWriter(f)
+	}
+
+	for ev := range events {
+		if recs != nil {
+			recs.observe(ev)
+		}
+		if rw != nil {
+			_ = rw.Write(ev)
+		}
+	}
+}
+
 func writeLogsAndMessages(logs *io.PipeReader, logfile string, verbose bool) {
 	wildcard := regexp.MustCompile("DNS wildcard")
 	queries := regexp.MustCompile("Querying")
@@ -613,6 +744,18 @@ func writeLogsAndMessages(logs *io.PipeReader, logfile string, verbose bool) {
 	}
 }
 
+// Places the HTTP archive under the requests sent by the data sources. The credentials
+// provided by the configuration are never written to the archive.
+func openHTTPArchive(args *enumArgs, cfg *config.Config) error {
+	if path := args.Filepaths.RecordArchive; path != "" {
+		return http.RecordResponses(path, cfg.CredentialSecrets()...)
+	}
+	if path := args.Filepaths.ReplayArchive; path != "" {
+		return http.ReplayResponses(path, cfg.CredentialSecrets()...)
+	}
+	return nil
+}
+
 // Obtain parameters from provided input files
 func processEnumInputFiles(args *enumArgs) error {
 	if args.Options.BruteForcing && len(args.Filepaths.BruteWordlist) > 0 {
@@ -681,6 +824,15 @@ func processEnumInputFiles(args *enumArgs) error {
 			args.Resolvers.InsertMany(list...)
 		}
 	}
+	if len(args.Filepaths.Trusted) > 0 {
+		for _, f := range args.Filepaths.Trusted {
+			list, err := config.GetListFromFile(f)
+			if err != nil {
+				return fmt.Errorf("failed to parse the trusted resolver file: %v", err)
+			}
+			args.Trusted.InsertMany(list...)
+		}
+	}
 	return nil
 }
 
@@ -701,6 +853,23 @@ func (e enumArgs) OverrideConfig(conf *config.Config) error {
 	if e.Filepaths.Directory != "" {
 		conf.Dir = e.Filepaths.Directory
 	}
+	if len(e.Sinks) > 0 {
+		conf.AddOutputSinks(e.Sinks...)
+	}
+	if len(e.RecordTypes) > 0 {
+		if err := conf.SetRecordTypes(e.RecordTypes...); err != nil {
+			return err
+		}
+	}
+	if e.Resume != "" {
+		id, err := uuid.Parse(e.Resume)
+		if err != nil {
+			return fmt.Errorf("the resume argument is not a valid enumeration UUID: %v", err)
+		}
+
+		conf.UUID = id
+		conf.Resume = true
+	}
 	if e.Filepaths.ScriptsDirectory != "" {
 		conf.ScriptsDirectory = e.Filepaths.ScriptsDirectory
 	}
@@ -744,12 +913,27 @@ func (e enumArgs) OverrideConfig(conf *config.Config) error {
 	if e.Options.Verbose {
 		conf.Verbose = true
 	}
+	if e.Options.DNSSEC {
+		conf.DNSSEC = true
+	}
+	if e.Options.Authoritative {
+		conf.Authoritative = true
+	}
 	if e.ResolverQPS > 0 {
 		conf.ResolversQPS = e.ResolverQPS
 	}
 	if e.TrustedQPS > 0 {
 		conf.TrustedQPS = e.TrustedQPS
 	}
+	if e.DoHQPS > 0 {
+		conf.DoHQPS = e.DoHQPS
+	}
+	if e.DoTQPS > 0 {
+		conf.DoTQPS = e.DoTQPS
+	}
+	if e.AuthQPS > 0 {
+		conf.AuthoritativeQPS = e.AuthQPS
+	}
 	if e.Resolvers.Len() > 0 {
 		conf.SetResolvers(e.Resolvers.Slice()...)
 	}
@@ -25,6 +25,12 @@ func runHelpCommand(clArgs []string) {
 		runTrackCommand(help)
 	case "viz":
 		runVizCommand(help)
+	case "monitor":
+		runMonitorCommand(help)
+	case "serve":
+		runServeCommand(help)
+	case "script":
+		runScriptCommand(append([]string{"test"}, help...))
 	default:
 		commandUsage(mainUsageMsg, helpCommand, helpBuf)
 		return
@@ -191,6 +191,7 @@ func initializeSourceTags(srcs []service.Service) {
 	sourceTags["DNS"] = requests.DNS
 	sourceTags["Reverse DNS"] = requests.DNS
 	sourceTags["NSEC Walk"] = requests.DNS
+	sourceTags["NSEC3 Walk"] = requests.NSEC3
 	sourceTags["DNS Zone XFR"] = requests.AXFR
 	sourceTags["Active Crawl"] = requests.CRAWL
 	sourceTags["Active Cert"] = requests.CERT
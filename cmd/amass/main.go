@@ -36,6 +36,7 @@ import (
 
 	"github.com/OWASP/Amass/v3/config"
 	"github.com/OWASP/Amass/v3/datasrcs"
+	"github.com/OWASP/Amass/v3/datasrcs/scripting"
 	"github.com/OWASP/Amass/v3/format"
 	amassnet "github.com/OWASP/Amass/v3/net"
 	"github.com/OWASP/Amass/v3/requests"
@@ -48,7 +49,7 @@ import (
 )
 
 const (
-	mainUsageMsg         = "intel|enum|viz|track|db [options]"
+	mainUsageMsg         = "intel|enum|viz|track|monitor|db|serve|script [options]"
 	exampleConfigFileURL = "https://github.com/OWASP/Amass/blob/master/examples/config.ini"
 	userGuideURL         = "https://github.com/OWASP/Amass/blob/master/doc/user_guide.md"
 	tutorialURL          = "https://github.com/OWASP/Amass/blob/master/doc/tutorial.md"
@@ -79,7 +80,10 @@ func commandUsage(msg string, cmdFlagSet *flag.FlagSet, errBuf *bytes.Buffer) {
 		g.Fprintf(color.Error, "\t%-11s - Perform enumerations and network mapping\n", "amass enum")
 		g.Fprintf(color.Error, "\t%-11s - Visualize enumeration results\n", "amass viz")
 		g.Fprintf(color.Error, "\t%-11s - Track differences between enumerations\n", "amass track")
+		g.Fprintf(color.Error, "\t%-11s - Alert on changes found by scheduled enumerations\n", "amass monitor")
 		g.Fprintf(color.Error, "\t%-11s - Manipulate the Amass graph database\n", "amass db")
+		g.Fprintf(color.Error, "\t%-11s - Run jobs submitted through the HTTP API\n", "amass serve")
+		g.Fprintf(color.Error, "\t%-11s - Test ADS scripts using canned responses\n", "amass script")
 	}
 
 	g.Fprintln(color.Error)
@@ -127,6 +131,12 @@ func main() {
 		runTrackCommand(os.Args[2:])
 	case "viz":
 		runVizCommand(os.Args[2:])
+	case "monitor":
+		runMonitorCommand(os.Args[2:])
+	case "serve":
+		runServeCommand(os.Args[2:])
+	case "script":
+		runScriptCommand(os.Args[2:])
 	case "help":
 		runHelpCommand(os.Args[2:])
 	default:
@@ -178,11 +188,46 @@ func DataSourceInfo(all []service.Service, sys systems.System) []string {
 
 		names = append(names, fmt.Sprintf("%-35s  %-35s  %s",
 			green(src.String()), yellow(src.Description()), yellow(avail)))
+		if script, ok := src.(*scripting.Script); ok {
+			names = append(names, scriptMetadataInfo(script)...)
+		}
 	}
 
 	return names
 }
 
+// Returns the lines describing the metadata declared by the script and the reason it is not available.
+func scriptMetadataInfo(script *scripting.Script) []string {
+	var lines []string
+
+	meta := script.Metadata()
+	if len(meta.Requests) > 0 {
+		lines = append(lines, fmt.Sprintf("    %s %s", blue("Requests:"), strings.Join(meta.Requests, ", ")))
+	}
+	if len(meta.Credentials) > 0 {
+		lines = append(lines, fmt.Sprintf("    %s %s", blue("Credentials:"), strings.Join(meta.Credentials, ", ")))
+	}
+	if len(meta.Endpoints) > 0 {
+		lines = append(lines, fmt.Sprintf("    %s %s", blue("Endpoints:"), strings.Join(meta.Endpoints, ", ")))
+	}
+
+	info := fmt.Sprintf("    %s %ds", blue("Rate Limit:"), meta.RateLimit)
+	// Nothing is shown when the script did not declare whether it is passive
+	if meta.Passive != nil {
+		passive := "yes"
+		if !*meta.Passive {
+			passive = "no"
+		}
+		info += fmt.Sprintf("  %s %s", blue("Passive:"), passive)
+	}
+	lines = append(lines, info)
+
+	if reason := script.Unavailable(); reason != "" {
+		lines = append(lines, fmt.Sprintf("    %s %s", blue("Unavailable:"), red(reason)))
+	}
+	return lines
+}
+
 func createOutputDirectory(cfg *config.Config) {
 	// Prepare output file paths
 	dir := config.OutputDirectory(cfg.Dir)
@@ -0,0 +1,56 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/OWASP/Amass/v3/enum"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/fatih/color"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsHandler returns the HTTP handler that exposes the telemetry of the enumerations using the System.
+func metricsHandler(sys systems.System) (http.Handler, error) {
+	reg := prometheus.NewRegistry()
+
+	if err := reg.Register(collectors.NewGoCollector()); err != nil {
+		return nil, err
+	}
+	if err := reg.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return nil, err
+	}
+	if err := enum.RegisterMetrics(reg, sys); err != nil {
+		return nil, err
+	}
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}), nil
+}
+
+// startMetricsServer serves the Prometheus metrics on the provided address until the program exits.
+func startMetricsServer(addr string, sys systems.System) error {
+	handler, err := metricsHandler(sys)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	go func() {
+		if err := http.Serve(l, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.Fprintf(color.Error, "The metrics server failed: %v\n", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,543 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/datasrcs"
+	"github.com/OWASP/Amass/v3/enum"
+	"github.com/OWASP/Amass/v3/format"
+	"github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/netmap"
+	"github.com/caffix/stringset"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+)
+
+const (
+	monitorUsageMsg = "monitor [options] -schedule spec -d domain"
+	defaultSchedule = "@daily"
+)
+
+type monitorArgs struct {
+	Enum     enumArgs
+	Schedule string
+	Webhooks format.ParseStrings
+	Notify   struct {
+		File   string
+		Stdout bool
+	}
+}
+
+// monitorReport is provided to the notifiers when an enumeration has differences from the previous one.
+type monitorReport struct {
+	UUID     string      `json:"uuid"`
+	Previous string      `json:"previous_uuid"`
+	Domains  []string    `json:"domains"`
+	Start    time.Time   `json:"start"`
+	Finish   time.Time   `json:"finish"`
+	Changes  []*enumDiff `json:"changes"`
+}
+
+// monitorNotifier is implemented by each destination for the monitor change alerts.
+type monitorNotifier interface {
+	fmt.Stringer
+	Notify(ctx context.Context, report *monitorReport) error
+}
+
+type stdoutNotifier struct {
+	sync.Mutex
+	enc *json.Encoder
+}
+
+type fileNotifier struct {
+	sync.Mutex
+	path string
+}
+
+type webhookNotifier struct {
+	url string
+}
+
+type monitor struct {
+	cfg       *config.Config
+	sys       *systems.LocalSystem
+	timeout   int
+	notifiers []monitorNotifier
+	prevUUID  string
+	prev      []*requests.Output
+}
+
+func runMonitorCommand(clArgs []string) {
+	args := monitorArgs{
+		Enum: enumArgs{
+			AltWordList:       stringset.New(),
+			AltWordListMask:   stringset.New(),
+			BruteWordList:     stringset.New(),
+			BruteWordListMask: stringset.New(),
+			Blacklist:         stringset.New(),
+			Domains:           stringset.New(),
+			Excluded:          stringset.New(),
+			Included:          stringset.New(),
+			Names:             stringset.New(),
+			Resolvers:         stringset.New(),
+			Trusted:           stringset.New(),
+		},
+	}
+	var help1, help2 bool
+	monitorCommand := flag.NewFlagSet("monitor", flag.ContinueOnError)
+
+	monitorBuf := new(bytes.Buffer)
+	monitorCommand.SetOutput(monitorBuf)
+
+	monitorCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	monitorCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	monitorCommand.StringVar(&args.Schedule, "schedule", defaultSchedule, "Cron expression, @hourly, @daily, @weekly or @every duration for the enumerations")
+	monitorCommand.Var(&args.Webhooks, "webhook", "URLs that receive the changes as JSON in a POST request (can be used multiple times)")
+	monitorCommand.StringVar(&args.Notify.File, "notify-file", "", "Path to the file where the changes are appended as JSON lines")
+	monitorCommand.BoolVar(&args.Notify.Stdout, "notify-stdout", false, "Write the changes as JSON lines to stdout (default when no other notifier is selected)")
+	defineEnumArgumentFlags(monitorCommand, &args.Enum)
+	defineEnumOptionFlags(monitorCommand, &args.Enum)
+	defineEnumFilepathFlags(monitorCommand, &args.Enum)
+
+	if len(clArgs) < 1 {
+		commandUsage(monitorUsageMsg, monitorCommand, monitorBuf)
+		return
+	}
+	if err := monitorCommand.Parse(clArgs); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		commandUsage(monitorUsageMsg, monitorCommand, monitorBuf)
+		return
+	}
+	if args.Enum.Options.NoColor {
+		color.NoColor = true
+	}
+	if args.Enum.Options.Silent {
+		color.Output = ioutil.Discard
+		color.Error = ioutil.Discard
+	}
+	if args.Enum.Resume != "" {
+		r.Fprintln(color.Error, "The resume flag cannot be used with the monitor subcommand")
+		os.Exit(1)
+	}
+
+	sched, err := parseSchedule(args.Schedule)
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to parse the schedule: %v\n", err)
+		os.Exit(1)
+	}
+
+	rand.Seed(time.Now().UTC().UnixNano())
+	// Extract the correct config from the user provided arguments and/or configuration file
+	cfg, err := enumArgsConfig(&args.Enum)
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	createOutputDirectory(cfg)
+
+	rLog, wLog := io.Pipe()
+	// Setup logging so that messages can be written to the file and used by the program
+	cfg.Log = log.New(wLog, "", log.Lmicroseconds)
+	logfile := filepath.Join(config.OutputDirectory(cfg.Dir), "amass.log")
+	if args.Enum.Filepaths.LogFile != "" {
+		logfile = args.Enum.Filepaths.LogFile
+	}
+	go writeLogsAndMessages(rLog, logfile, args.Enum.Options.Verbose)
+	// The queries and timeouts of each resolver are reported with the metrics
+	cfg.ResolverMetrics = args.Enum.Metrics != ""
+	// Create the System shared by all the enumerations
+	sys, err := systems.NewLocalSystem(cfg)
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	if err := sys.SetDataSources(datasrcs.GetAllSources(sys)); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if args.Enum.Metrics != "" {
+		if err := startMetricsServer(args.Enum.Metrics, sys); err != nil {
+			r.Fprintf(color.Error, "Failed to start the metrics server: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	initializeSourceTags(sys.DataSources())
+	cfg.SourceFilter.Sources = expandCategoryNames(cfg.SourceFilter.Sources, generateCategoryMap(sys))
+
+	m := &monitor{
+		cfg:     cfg,
+		sys:     sys,
+		timeout: args.Enum.Timeout,
+	}
+	for _, u := range args.Webhooks {
+		m.notifiers = append(m.notifiers, &webhookNotifier{url: u})
+	}
+	if args.Notify.File != "" {
+		m.notifiers = append(m.notifiers, &fileNotifier{path: args.Notify.File})
+	}
+	if args.Notify.Stdout || len(m.notifiers) == 0 {
+		m.notifiers = append(m.notifiers, &stdoutNotifier{enc: json.NewEncoder(os.Stdout)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Monitor for cancellation by the user
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(quit)
+
+		<-quit
+		cancel()
+	}()
+
+	m.loadPrevious(ctx)
+	for {
+		next := sched.next(time.Now())
+		fmt.Fprintf(color.Error, "%s%s\n", blue("The next enumeration will start at "), yellow(next.Format(timeFormat)))
+
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		if err := m.enumerate(ctx); err != nil {
+			r.Fprintf(color.Error, "%v\n", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Use the most recent enumeration of the domains in the graph database as the baseline.
+func (m *monitor) loadPrevious(ctx context.Context) {
+	dbs := m.sys.GraphDatabases()
+	if len(dbs) == 0 {
+		return
+	}
+
+	domains := m.cfg.Domains()
+	memDB, err := memGraphForScope(ctx, domains, dbs[0])
+	if err != nil {
+		r.Fprintln(color.Error, err.Error())
+		return
+	}
+	defer memDB.Close()
+
+	uuids := memDB.EventsInScope(ctx, domains...)
+	if len(uuids) == 0 {
+		return
+	}
+
+	uuids, _, _ = orderedEvents(ctx, uuids, memDB)
+	m.prevUUID = uuids[len(uuids)-1]
+	m.prev = getScopedOutput([]string{m.prevUUID}, domains, memDB, nil)
+}
+
+func (m *monitor) enumerate(ctx context.Context) error {
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(m.timeout)*time.Minute)
+		defer cancel()
+	}
+	// Each enumeration is a new event in the graph database
+	m.cfg.UUID = uuid.New()
+	id := m.cfg.UUID.String()
+	// Create the in-memory graph database used to store enumeration findings
+	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer graph.Close()
+
+	fmt.Fprintf(color.Error, "%s%s\n", blue("Starting the enumeration "), yellow(id))
+	events, err := enum.Run(ctx, m.cfg, enum.WithSystem(m.sys), enum.WithGraph(graph))
+	if err != nil {
+		return err
+	}
+
+	var finished *enum.FinishedEvent
+	for ev := range events {
+		if f, ok := ev.(*enum.FinishedEvent); ok {
+			finished = f
+		}
+	}
+	if finished != nil && finished.Err != nil {
+		return fmt.Errorf("the enumeration %s failed: %v", id, finished.Err)
+	}
+	// Copy the graph of findings into the system graph databases
+	for _, g := range m.sys.GraphDatabases() {
+		if err := graph.Migrate(context.Background(), g); err != nil {
+			r.Fprintf(color.Error, "The database migration to %s failed: %v\n", g.String(), err)
+		}
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("the enumeration %s did not complete: %v", id, ctx.Err())
+	}
+
+	out := getScopedOutput([]string{id}, m.cfg.Domains(), graph, nil)
+	if m.prevUUID == "" {
+		fmt.Fprintf(color.Error, "%s\n", green("The enumeration is the baseline for detecting changes"))
+	} else if changes := compareEnumOutput(m.prev, out); len(changes) > 0 {
+		start, finish := graph.EventDateRange(context.Background(), id)
+
+		m.notify(&monitorReport{
+			UUID:     id,
+			Previous: m.prevUUID,
+			Domains:  m.cfg.Domains(),
+			Start:    start,
+			Finish:   finish,
+			Changes:  changes,
+		})
+	} else {
+		g.Fprintln(color.Error, "No differences discovered")
+	}
+
+	m.prevUUID = id
+	m.prev = out
+	return nil
+}
+
+func (m *monitor) notify(report *monitorReport) {
+	fmt.Fprintf(color.Error, "%s%s\n", yellow(strconv.Itoa(len(report.Changes))), yellow(" changes were discovered"))
+
+	for _, n := range m.notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+
+		if err := n.Notify(ctx, report); err != nil {
+			r.Fprintf(color.Error, "%s: failed to send the changes: %v\n", n.String(), err)
+		}
+		cancel()
+	}
+}
+
+func (n *stdoutNotifier) String() string {
+	return "stdout"
+}
+
+// Notify implements the monitorNotifier interface.
+func (n *stdoutNotifier) Notify(ctx context.Context, report *monitorReport) error {
+	n.Lock()
+	defer n.Unlock()
+
+	return n.enc.Encode(report)
+}
+
+func (n *fileNotifier) String() string {
+	return n.path
+}
+
+// Notify implements the monitorNotifier interface.
+func (n *fileNotifier) Notify(ctx context.Context, report *monitorReport) error {
+	n.Lock()
+	defer n.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(report)
+}
+
+func (n *webhookNotifier) String() string {
+	return n.url
+}
+
+// Notify implements the monitorNotifier interface.
+func (n *webhookNotifier) Notify(ctx context.Context, report *monitorReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	hdr := map[string]string{"Content-Type": "application/json"}
+	_, err = http.RequestWebPage(ctx, n.url, bytes.NewReader(body), hdr, nil)
+	return err
+}
+
+// schedule provides the start times of the monitor enumerations.
+type schedule interface {
+	next(t time.Time) time.Time
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// cronSchedule has a bit set for each minute, hour, day of month, month and day of week that matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDOM, anyDOW                bool
+}
+
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, err
+		}
+		if d < time.Minute {
+			return nil, errors.New("the interval must be at least one minute")
+		}
+		return &intervalSchedule{interval: d}, nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q must have five fields: minute hour day-of-month month day-of-week", spec)
+	}
+
+	bounds := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("the field %q is invalid: %v", field, err)
+		}
+		bits[i] = b
+	}
+	// Sunday can be provided as either zero or seven
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		anyDOM: fields[2] == "*",
+		anyDOW: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx != -1 {
+			s, err := strconv.Atoi(part[idx+1:])
+			if err != nil || s < 1 {
+				return 0, fmt.Errorf("%q is not a valid step", part[idx+1:])
+			}
+			step = s
+			part = part[:idx]
+		}
+
+		low, high := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			l, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("%q is not a number", bounds[0])
+			}
+			low, high = l, l
+			if len(bounds) == 2 {
+				h, err := strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("%q is not a number", bounds[1])
+				}
+				high = h
+			} else if step > 1 {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("the values must be between %d and %d", min, max)
+		}
+
+		for i := low; i <= high; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func (s *intervalSchedule) next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Give up on expressions that never match, such as the 31st of February
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return limit
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	// Following cron, either day field can match when both have been restricted
+	if !s.anyDOM && !s.anyDOW {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,133 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func cronBits(values ...int) uint64 {
+	var bits uint64
+
+	for _, v := range values {
+		bits |= 1 << uint(v)
+	}
+	return bits
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		min, max int
+		expected uint64
+		err      bool
+	}{
+		{"wildcard", "*", 1, 12, cronBits(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12), false},
+		{"single value", "5", 0, 59, cronBits(5), false},
+		{"range", "1-3", 0, 59, cronBits(1, 2, 3), false},
+		{"wildcard step", "*/15", 0, 59, cronBits(0, 15, 30, 45), false},
+		{"range step", "10-20/5", 0, 59, cronBits(10, 15, 20), false},
+		{"value step", "5/20", 0, 59, cronBits(5, 25, 45), false},
+		{"list", "1,3,5", 0, 6, cronBits(1, 3, 5), false},
+		{"list of ranges", "1-2,10-11,20", 0, 23, cronBits(1, 2, 10, 11, 20), false},
+		{"above the maximum", "60", 0, 59, 0, true},
+		{"below the minimum", "0", 1, 31, 0, true},
+		{"reversed range", "5-1", 0, 59, 0, true},
+		{"zero step", "*/0", 0, 59, 0, true},
+		{"invalid step", "*/a", 0, 59, 0, true},
+		{"not a number", "a", 0, 59, 0, true},
+		{"invalid range end", "1-b", 0, 59, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bits, err := parseCronField(tt.field, tt.min, tt.max)
+			if (err != nil) != tt.err {
+				t.Fatalf("parseCronField(%q) returned the error: %v", tt.field, err)
+			}
+			if bits != tt.expected {
+				t.Errorf("parseCronField(%q) returned %b, expected %b", tt.field, bits, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"0 0 32 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"@every 30s",
+		"@every tomorrow",
+	} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("parseSchedule(%q) did not return an error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"minute", "30 * * * *", date(2022, time.March, 10, 10, 45), date(2022, time.March, 10, 11, 30)},
+		{"strictly after", "@hourly", date(2022, time.March, 10, 10, 0), date(2022, time.March, 10, 11, 0)},
+		{"seconds are ignored", "@hourly", date(2022, time.March, 10, 10, 59).Add(30 * time.Second), date(2022, time.March, 10, 11, 0)},
+		{"hour range step", "0 9-17/4 * * *", date(2022, time.March, 10, 10, 0), date(2022, time.March, 10, 13, 0)},
+		{"minute list", "0,20,40 * * * *", date(2022, time.March, 10, 10, 21), date(2022, time.March, 10, 10, 40)},
+		{"daily rollover", "@daily", date(2022, time.March, 10, 23, 59), date(2022, time.March, 11, 0, 0)},
+		// The tenth of March 2022 was a Thursday
+		{"sunday as zero", "0 0 * * 0", date(2022, time.March, 10, 12, 0), date(2022, time.March, 13, 0, 0)},
+		{"sunday as seven", "0 0 * * 7", date(2022, time.March, 10, 12, 0), date(2022, time.March, 13, 0, 0)},
+		{"weekday range", "0 8 * * 1-5", date(2022, time.March, 11, 9, 0), date(2022, time.March, 14, 8, 0)},
+		{"day of week only", "0 0 * * 1", date(2022, time.March, 10, 12, 0), date(2022, time.March, 14, 0, 0)},
+		{"day of month only", "0 0 15 * *", date(2022, time.March, 10, 12, 0), date(2022, time.March, 15, 0, 0)},
+		{"either day matches by weekday", "0 0 1 * 1", date(2022, time.March, 10, 12, 0), date(2022, time.March, 14, 0, 0)},
+		{"either day matches by date", "0 0 1 * 1", date(2022, time.March, 28, 12, 0), date(2022, time.April, 1, 0, 0)},
+		{"day of month step with weekday", "0 0 */2 * 1", date(2022, time.March, 10, 0, 30), date(2022, time.March, 11, 0, 0)},
+		{"year rollover", "@monthly", date(2022, time.December, 15, 0, 0), date(2023, time.January, 1, 0, 0)},
+		{"short months skipped", "0 12 31 * *", date(2022, time.April, 1, 0, 0), date(2022, time.May, 31, 12, 0)},
+		{"month list rollover", "15 10 * 1,6 *", date(2022, time.June, 30, 10, 16), date(2023, time.January, 1, 10, 15)},
+		{"leap day", "0 0 29 2 *", date(2022, time.March, 1, 0, 0), date(2024, time.February, 29, 0, 0)},
+		{"interval", "@every 90m", date(2022, time.March, 10, 10, 0), date(2022, time.March, 10, 11, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("parseSchedule(%q) returned the error: %v", tt.spec, err)
+			}
+			if next := s.next(tt.from); !next.Equal(tt.expected) {
+				t.Errorf("The schedule %q returned %v after %v, expected %v", tt.spec, next, tt.from, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScheduleNeverMatches(t *testing.T) {
+	s, err := parseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parseSchedule returned the error: %v", err)
+	}
+
+	from := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	// The search gives up after five years
+	if next := s.next(from); !next.Equal(from.Add(time.Minute).AddDate(5, 0, 0)) {
+		t.Errorf("The schedule that never matches returned %v", next)
+	}
+}
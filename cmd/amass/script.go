@@ -0,0 +1,135 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/datasrcs/scripting"
+	"github.com/OWASP/Amass/v3/format"
+	"github.com/fatih/color"
+)
+
+const scriptUsageMsg = "script test [options] -script PATH"
+
+type scriptArgs struct {
+	Script   string
+	Fixtures format.ParseStrings
+	Options  struct {
+		NoColor bool
+		Silent  bool
+		Verbose bool
+	}
+	Filepaths struct {
+		ConfigFile string
+	}
+}
+
+func runScriptCommand(clArgs []string) {
+	var args scriptArgs
+	var help1, help2 bool
+	scriptCommand := flag.NewFlagSet("script", flag.ContinueOnError)
+
+	scriptBuf := new(bytes.Buffer)
+	scriptCommand.SetOutput(scriptBuf)
+
+	scriptCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	scriptCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	scriptCommand.StringVar(&args.Script, "script", "", "Path to the ADS script that will be tested")
+	scriptCommand.Var(&args.Fixtures, "fixture", "Path to a JSON test fixture (default: the script path with the .json extension)")
+	scriptCommand.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
+	scriptCommand.BoolVar(&args.Options.Silent, "silent", false, "Disable all output during execution")
+	scriptCommand.BoolVar(&args.Options.Verbose, "v", false, "Print the findings and the script log messages")
+	scriptCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
+
+	if len(clArgs) < 1 || clArgs[0] != "test" {
+		commandUsage(scriptUsageMsg, scriptCommand, scriptBuf)
+		return
+	}
+	if err := scriptCommand.Parse(clArgs[1:]); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		commandUsage(scriptUsageMsg, scriptCommand, scriptBuf)
+		return
+	}
+	if args.Options.NoColor {
+		color.NoColor = true
+	}
+	if args.Options.Silent {
+		color.Output = ioutil.Discard
+		color.Error = ioutil.Discard
+	}
+	if args.Script == "" {
+		r.Fprintln(color.Error, "No ADS script was provided")
+		os.Exit(1)
+	}
+	if len(args.Fixtures) == 0 {
+		args.Fixtures = format.ParseStrings{strings.TrimSuffix(args.Script, filepath.Ext(args.Script)) + ".json"}
+	}
+
+	var failed bool
+	for _, path := range args.Fixtures {
+		if err := runScriptFixture(&args, path); err != nil {
+			failed = true
+			r.Fprintf(color.Error, "FAIL %s\n%v\n", path, err)
+			continue
+		}
+		g.Fprintf(color.Output, "PASS %s\n", path)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runScriptFixture(args *scriptArgs, path string) error {
+	fix, err := scripting.LoadFixture(path)
+	if err != nil {
+		return err
+	}
+
+	cfg := config.NewConfig()
+	if args.Filepaths.ConfigFile != "" {
+		if err := cfg.LoadSettings(args.Filepaths.ConfigFile); err != nil {
+			return fmt.Errorf("failed to load the configuration file: %v", err)
+		}
+	}
+	if args.Options.Verbose {
+		cfg.Log = log.New(color.Error, "", log.Lmicroseconds)
+	}
+
+	findings, err := scripting.RunFixture(args.Script, fix, cfg)
+	if err != nil {
+		return err
+	}
+	if args.Options.Verbose {
+		printScriptFindings(findings)
+	}
+	return findings.Verify(fix.Expected)
+}
+
+func printScriptFindings(f *scripting.Findings) {
+	for _, name := range f.Names {
+		fmt.Fprintf(color.Output, "%s %s\n", blue("name:"), green(name))
+	}
+	for _, addr := range f.Addresses {
+		fmt.Fprintf(color.Output, "%s %s\n", blue("address:"), yellow(addr))
+	}
+	for _, asn := range f.ASNs {
+		fmt.Fprintf(color.Output, "%s %s\n", blue("asn:"), yellow(asn))
+	}
+	for _, domain := range f.Associated {
+		fmt.Fprintf(color.Output, "%s %s\n", blue("associated:"), green(domain))
+	}
+}
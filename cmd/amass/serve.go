@@ -0,0 +1,956 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/datasrcs"
+	"github.com/OWASP/Amass/v3/enum"
+	"github.com/OWASP/Amass/v3/format"
+	"github.com/OWASP/Amass/v3/intel"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/netmap"
+	"github.com/caffix/stringset"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+)
+
+const (
+	serveUsageMsg      = "serve [options]"
+	defaultServeAddr   = "127.0.0.1:4080"
+	defaultEventsLimit = 100
+	defaultJobRetain   = 60
+	// The environment variable providing the API token, which keeps it out of the process list
+	serveTokenEnv = "AMASS_API_TOKEN"
+)
+
+// The job arguments accepted by the server. The others provide access to the filesystem,
+// scripts and resolvers of the server, so they are only set by the server configuration.
+var (
+	enumJobFlags = map[string]struct{}{
+		"d":       {},
+		"brute":   {},
+		"alts":    {},
+		"active":  {},
+		"passive": {},
+		"w":       {},
+		"aw":      {},
+		"timeout": {},
+		"resume":  {},
+	}
+	intelJobFlags = map[string]struct{}{
+		"d":       {},
+		"addr":    {},
+		"asn":     {},
+		"cidr":    {},
+		"org":     {},
+		"whois":   {},
+		"active":  {},
+		"timeout": {},
+	}
+)
+
+type serveArgs struct {
+	Address string
+	Token   string
+	Retain  int
+	Options struct {
+		NoColor bool
+		Silent  bool
+		Verbose bool
+	}
+	Filepaths struct {
+		ConfigFile string
+		Directory  string
+		LogFile    string
+	}
+}
+
+// Job states reported by the API server.
+const (
+	jobRunning  = "running"
+	jobFinished = "finished"
+	jobFailed   = "failed"
+	jobCanceled = "canceled"
+)
+
+type apiJobRequest struct {
+	Type string   `json:"type"`
+	Args []string `json:"args"`
+}
+
+type apiJob struct {
+	sync.Mutex
+	ID       string     `json:"id"`
+	Type     string     `json:"type"`
+	Args     []string   `json:"args"`
+	Domains  []string   `json:"domains,omitempty"`
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Names    int        `json:"names_discovered"`
+	Results  int        `json:"results"`
+	output   []*requests.Output
+	updated  chan struct{}
+	cancel   context.CancelFunc
+}
+
+type apiServer struct {
+	sync.Mutex
+	cfg        *config.Config
+	configFile string
+	sys        *systems.LocalSystem
+	jobs       map[string]*apiJob
+	metrics    http.Handler
+	token      string
+	retain     time.Duration
+	// The domains added to the scope of the shared system by the running jobs
+	scopeLock sync.Mutex
+	scope     map[string]int
+	// The domains provided by the server configuration remain in scope
+	baseScope *stringset.Set
+}
+
+type apiEvent struct {
+	UUID    string    `json:"uuid"`
+	Start   time.Time `json:"start"`
+	Finish  time.Time `json:"finish"`
+	Domains []string  `json:"domains"`
+}
+
+func runServeCommand(clArgs []string) {
+	var args serveArgs
+	var help1, help2 bool
+	serveCommand := flag.NewFlagSet("serve", flag.ContinueOnError)
+
+	serveBuf := new(bytes.Buffer)
+	serveCommand.SetOutput(serveBuf)
+
+	serveCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	serveCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	serveCommand.StringVar(&args.Address, "addr", defaultServeAddr, "Local address and port the HTTP API listens on")
+	serveCommand.StringVar(&args.Token, "token", "", "Token the clients provide as a bearer token (Default: $"+serveTokenEnv+")")
+	serveCommand.IntVar(&args.Retain, "retain", defaultJobRetain, "Minutes that finished jobs and their results are kept")
+	serveCommand.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
+	serveCommand.BoolVar(&args.Options.Silent, "silent", false, "Disable all output during execution")
+	serveCommand.BoolVar(&args.Options.Verbose, "v", false, "Output status / debug / troubleshooting info")
+	serveCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
+	serveCommand.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the output files")
+	serveCommand.StringVar(&args.Filepaths.LogFile, "log", "", "Path to the log file where errors will be written")
+
+	if err := serveCommand.Parse(clArgs); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		commandUsage(serveUsageMsg, serveCommand, serveBuf)
+		return
+	}
+	if args.Options.NoColor {
+		color.NoColor = true
+	}
+	if args.Options.Silent {
+		color.Output = ioutil.Discard
+		color.Error = ioutil.Discard
+	}
+	if args.Token == "" {
+		args.Token = os.Getenv(serveTokenEnv)
+	}
+	// The server runs scans on behalf of its clients, so they must authenticate outside the host
+	if args.Token == "" && !loopbackAddr(args.Address) {
+		r.Fprintf(color.Error, "A token is required when the server listens on a non-loopback address\n")
+		os.Exit(1)
+	}
+
+	cfg := config.NewConfig()
+	// Check if a configuration file was provided, and if so, load the settings
+	if err := config.AcquireConfig(args.Filepaths.Directory, args.Filepaths.ConfigFile, cfg); err != nil && args.Filepaths.ConfigFile != "" {
+		r.Fprintf(color.Error, "Failed to load the configuration file: %v\n", err)
+		os.Exit(1)
+	}
+	if args.Filepaths.Directory != "" {
+		cfg.Dir = args.Filepaths.Directory
+	}
+	createOutputDirectory(cfg)
+
+	rLog, wLog := io.Pipe()
+	// Setup logging so that messages can be written to the file and used by the program
+	cfg.Log = log.New(wLog, "", log.Lmicroseconds)
+	logfile := filepath.Join(config.OutputDirectory(cfg.Dir), "amass.log")
+	if args.Filepaths.LogFile != "" {
+		logfile = args.Filepaths.LogFile
+	}
+	go writeLogsAndMessages(rLog, logfile, args.Options.Verbose)
+	// The queries and timeouts of each resolver are reported with the metrics
+	cfg.ResolverMetrics = true
+	// Create the System shared by all the jobs
+	sys, err := systems.NewLocalSystem(cfg)
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	if err := sys.SetDataSources(datasrcs.GetAllSources(sys)); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	initializeSourceTags(sys.DataSources())
+
+	metrics, err := metricsHandler(sys)
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+
+	s := &apiServer{
+		cfg:        cfg,
+		configFile: args.Filepaths.ConfigFile,
+		sys:        sys,
+		jobs:       make(map[string]*apiJob),
+		metrics:    metrics,
+		token:      args.Token,
+		retain:     time.Duration(args.Retain) * time.Minute,
+		scope:      make(map[string]int),
+		baseScope:  stringset.New(cfg.Domains()...),
+	}
+	srv := &http.Server{
+		Addr:    args.Address,
+		Handler: s.routes(),
+	}
+	// Monitor for cancellation by the user
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(quit)
+
+		<-quit
+		s.cancelAll()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+
+	g.Fprintf(color.Error, "The Amass API server is listening on %s\n", args.Address)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	s.waitForJobs()
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/events/", s.handleEventOutput)
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics)
+	}
+	if s.token != "" {
+		return s.authorize(mux)
+	}
+	return mux
+}
+
+// authorize only passes the requests providing the token of the server along.
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="amass"`)
+			writeJSONError(w, http.StatusUnauthorized, errors.New("a valid API token is required"))
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *apiServer) handleJobs(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		s.Lock()
+		jobs := make([]*apiJob, 0, len(s.jobs))
+		for _, job := range s.jobs {
+			jobs = append(jobs, job.snapshot())
+		}
+		s.Unlock()
+
+		writeJSONResponse(w, http.StatusOK, jobs)
+	case http.MethodPost:
+		var jr apiJobRequest
+		if err := json.NewDecoder(req.Body).Decode(&jr); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("failed to parse the job request: %v", err))
+			return
+		}
+
+		job, err := s.startJob(&jr)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusCreated, job.snapshot())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *apiServer) handleJob(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, "/jobs/"), "/"), "/")
+
+	s.Lock()
+	job, found := s.jobs[parts[0]]
+	s.Unlock()
+	if !found {
+		writeJSONError(w, http.StatusNotFound, errors.New("the job was not found"))
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "results" && req.Method == http.MethodGet {
+		streamJobResults(w, req, job)
+		return
+	} else if len(parts) != 1 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		writeJSONResponse(w, http.StatusOK, job.snapshot())
+	case http.MethodDelete:
+		job.cancel()
+		writeJSONResponse(w, http.StatusOK, job.snapshot())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *apiServer) startJob(jr *apiJobRequest) (*apiJob, error) {
+	var id string
+	var domains []string
+	var run func(ctx context.Context, job *apiJob) error
+
+	switch jr.Type {
+	case "enum":
+		cfg, args, err := s.enumJobConfig(jr.Args)
+		if err != nil {
+			return nil, err
+		}
+		if args.Metrics != "" {
+			return nil, errors.New("the metrics of all the jobs are provided by the server at /metrics")
+		}
+
+		id = cfg.UUID.String()
+		domains = cfg.Domains()
+		run = func(ctx context.Context, job *apiJob) error {
+			return s.runEnumJob(ctx, job, cfg, args)
+		}
+	case "intel":
+		cfg, args, err := s.intelJobConfig(jr.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		id = uuid.New().String()
+		domains = cfg.Domains()
+		run = func(ctx context.Context, job *apiJob) error {
+			return s.runIntelJob(ctx, job, cfg, args)
+		}
+	default:
+		return nil, fmt.Errorf("the job type %q is not supported", jr.Type)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &apiJob{
+		ID:      id,
+		Type:    jr.Type,
+		Args:    jr.Args,
+		Domains: domains,
+		Status:  jobRunning,
+		Started: time.Now(),
+		updated: make(chan struct{}),
+		cancel:  cancel,
+	}
+
+	s.Lock()
+	// A finished job can be replaced, which allows interrupted enumerations to be resumed
+	if prev, found := s.jobs[id]; found && prev.snapshot().Finished == nil {
+		s.Unlock()
+		cancel()
+		return nil, fmt.Errorf("the job %s is still running on the server", id)
+	}
+	s.jobs[id] = job
+	s.Unlock()
+
+	go func() {
+		defer cancel()
+
+		err := run(ctx, job)
+		job.finish(ctx, err)
+		s.evictAfter(job, s.retain)
+	}()
+	return job, nil
+}
+
+// evictAfter removes the finished job and its results from the server once the period has passed.
+func (s *apiServer) evictAfter(job *apiJob, period time.Duration) {
+	time.AfterFunc(period, func() {
+		s.Lock()
+		defer s.Unlock()
+		// The job could have been replaced by a resumed enumeration
+		if s.jobs[job.ID] == job {
+			delete(s.jobs, job.ID)
+		}
+	})
+}
+
+func (s *apiServer) cancelAll() {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, job := range s.jobs {
+		job.cancel()
+	}
+}
+
+func (s *apiServer) waitForJobs() {
+	s.Lock()
+	jobs := make([]*apiJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.Unlock()
+
+	for _, job := range jobs {
+		for {
+			_, done, ch := job.results(0)
+			if done {
+				break
+			}
+			<-ch
+		}
+	}
+}
+
+// The data sources of the shared system check scope against its configuration, so the domains
+// of each job are in scope while it runs, and removed once no running job provides them.
+func (s *apiServer) acquireScope(domains []string) {
+	s.scopeLock.Lock()
+	defer s.scopeLock.Unlock()
+
+	for _, d := range domains {
+		s.scope[d]++
+		s.sys.Config().AddDomain(d)
+	}
+}
+
+func (s *apiServer) releaseScope(domains []string) {
+	s.scopeLock.Lock()
+	defer s.scopeLock.Unlock()
+
+	for _, d := range domains {
+		if s.scope[d]--; s.scope[d] > 0 {
+			continue
+		}
+
+		delete(s.scope, d)
+		if !s.baseScope.Has(d) {
+			s.sys.Config().RemoveDomain(d)
+		}
+	}
+}
+
+func (s *apiServer) runEnumJob(ctx context.Context, job *apiJob, cfg *config.Config, args *enumArgs) error {
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(args.Timeout)*time.Minute)
+		defer cancel()
+	}
+
+	cfg.Log = s.cfg.Log
+	cfg.SourceFilter.Sources = expandCategoryNames(cfg.SourceFilter.Sources, generateCategoryMap(s.sys))
+	// Create the in-memory graph database used to store enumeration findings
+	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer graph.Close()
+
+	e := enum.NewEnumeration(cfg, s.sys, graph)
+	if e == nil {
+		return errors.New("failed to setup the enumeration")
+	}
+
+	events := e.Events()
+	go func() {
+		for ev := range events {
+			if _, ok := ev.(*enum.NameEvent); ok {
+				job.nameDiscovered()
+			}
+		}
+	}()
+
+	ch := make(chan *requests.Output, 10)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+
+		for out := range ch {
+			job.add(out)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go processOutput(ctx, graph, e, []chan *requests.Output{ch}, done, &wg)
+
+	domains := cfg.Domains()
+	s.acquireScope(domains)
+	defer s.releaseScope(domains)
+
+	err := e.Start(ctx)
+	// Let the output goroutines know that the enumeration has finished
+	close(done)
+	wg.Wait()
+	<-collected
+	// Copy the graph of findings into the system graph databases
+	for _, g := range s.sys.GraphDatabases() {
+		if merr := graph.Migrate(context.Background(), g); merr != nil && err == nil {
+			err = fmt.Errorf("the database migration to %s failed: %v", g.String(), merr)
+		}
+	}
+	return err
+}
+
+func (s *apiServer) runIntelJob(ctx context.Context, job *apiJob, cfg *config.Config, args *intelArgs) error {
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(args.Timeout)*time.Minute)
+		defer cancel()
+	}
+
+	cfg.Log = s.cfg.Log
+	domains := cfg.Domains()
+	s.acquireScope(domains)
+	defer s.releaseScope(domains)
+
+	ic := intel.NewCollection(cfg, s.sys)
+	if ic == nil {
+		return errors.New("failed to setup the intelligence collection")
+	}
+	defer ic.Done()
+
+	errs := make(chan error, 1)
+	if args.Options.ReverseWhois {
+		go func() { errs <- ic.ReverseWhois() }()
+	} else {
+		go func() { errs <- ic.HostedDomains(ctx) }()
+	}
+
+	for out := range ic.Output {
+		job.add(out)
+	}
+	return <-errs
+}
+
+func (s *apiServer) enumJobConfig(clArgs []string) (*config.Config, *enumArgs, error) {
+	args := enumArgs{
+		AltWordList:       stringset.New(),
+		AltWordListMask:   stringset.New(),
+		BruteWordList:     stringset.New(),
+		BruteWordListMask: stringset.New(),
+		Blacklist:         stringset.New(),
+		Domains:           stringset.New(),
+		Excluded:          stringset.New(),
+		Included:          stringset.New(),
+		Names:             stringset.New(),
+		Resolvers:         stringset.New(),
+		Trusted:           stringset.New(),
+	}
+	enumCommand := flag.NewFlagSet("enum", flag.ContinueOnError)
+	enumCommand.SetOutput(ioutil.Discard)
+	defineEnumArgumentFlags(enumCommand, &args)
+	defineEnumOptionFlags(enumCommand, &args)
+	defineEnumFilepathFlags(enumCommand, &args)
+
+	if err := enumCommand.Parse(clArgs); err != nil {
+		return nil, nil, err
+	}
+	if err := checkJobFlags(enumCommand, enumJobFlags); err != nil {
+		return nil, nil, err
+	}
+	for _, list := range []format.ParseStrings{args.Filepaths.BruteWordlist, args.Filepaths.AltWordlist} {
+		if err := s.jobWordlists(list); err != nil {
+			return nil, nil, err
+		}
+	}
+	// The jobs use the configuration file and output directory of the server
+	args.Filepaths.ConfigFile, args.Filepaths.Directory = s.configFile, s.cfg.Dir
+
+	cfg, err := enumArgsConfig(&args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, &args, nil
+}
+
+// Build the enumeration configuration from the parsed enum flags without exiting on errors.
+func enumArgsConfig(args *enumArgs) (*config.Config, error) {
+	if args.AltWordListMask.Len() > 0 {
+		args.AltWordList.Union(args.AltWordListMask)
+	}
+	if args.BruteWordListMask.Len() > 0 {
+		args.BruteWordList.Union(args.BruteWordListMask)
+	}
+	if (args.Excluded.Len() > 0 || args.Filepaths.ExcludedSrcs != "") &&
+		(args.Included.Len() > 0 || args.Filepaths.IncludedSrcs != "") {
+		return nil, errors.New("cannot provide both include and exclude arguments")
+	}
+	if err := processEnumInputFiles(args); err != nil {
+		return nil, err
+	}
+
+	cfg := config.NewConfig()
+	if err := config.AcquireConfig(args.Filepaths.Directory, args.Filepaths.ConfigFile, cfg); err == nil {
+		if len(cfg.Resolvers) > 0 && args.Resolvers.Len() == 0 {
+			args.Resolvers = stringset.New(cfg.Resolvers...)
+		}
+	} else if args.Filepaths.ConfigFile != "" {
+		return nil, fmt.Errorf("failed to load the configuration file: %v", err)
+	}
+	if err := cfg.UpdateConfig(args); err != nil {
+		return nil, fmt.Errorf("configuration error: %v", err)
+	}
+	if len(cfg.Domains()) == 0 && !cfg.Resume {
+		return nil, errors.New("configuration error: no root domain names were provided")
+	}
+	return cfg, nil
+}
+
+func (s *apiServer) intelJobConfig(clArgs []string) (*config.Config, *intelArgs, error) {
+	args := intelArgs{
+		Domains:   stringset.New(),
+		Excluded:  stringset.New(),
+		Included:  stringset.New(),
+		Resolvers: stringset.New(),
+	}
+	intelCommand := flag.NewFlagSet("intel", flag.ContinueOnError)
+	intelCommand.SetOutput(ioutil.Discard)
+	defineIntelArgumentFlags(intelCommand, &args)
+	defineIntelOptionFlags(intelCommand, &args)
+	defineIntelFilepathFlags(intelCommand, &args)
+
+	if err := intelCommand.Parse(clArgs); err != nil {
+		return nil, nil, err
+	}
+	if err := checkJobFlags(intelCommand, intelJobFlags); err != nil {
+		return nil, nil, err
+	}
+	// The jobs use the configuration file and output directory of the server
+	args.Filepaths.ConfigFile, args.Filepaths.Directory = s.configFile, s.cfg.Dir
+	if (args.Excluded.Len() > 0 || args.Filepaths.ExcludedSrcs != "") &&
+		(args.Included.Len() > 0 || args.Filepaths.IncludedSrcs != "") {
+		return nil, nil, errors.New("cannot provide both include and exclude arguments")
+	}
+	if err := processIntelInputFiles(&args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := config.NewConfig()
+	if err := config.AcquireConfig(args.Filepaths.Directory, args.Filepaths.ConfigFile, cfg); err == nil {
+		if len(cfg.Resolvers) > 0 && args.Resolvers.Len() == 0 {
+			args.Resolvers = stringset.New(cfg.Resolvers...)
+		}
+	} else if args.Filepaths.ConfigFile != "" {
+		return nil, nil, fmt.Errorf("failed to load the configuration file: %v", err)
+	}
+	if err := cfg.UpdateConfig(args); err != nil {
+		return nil, nil, fmt.Errorf("configuration error: %v", err)
+	}
+	if args.Options.ReverseWhois && len(cfg.Domains()) == 0 {
+		return nil, nil, errors.New("no root domain names were provided")
+	}
+	if !args.Options.ReverseWhois && len(args.Addresses) == 0 && len(args.CIDRs) == 0 && len(args.ASNs) == 0 {
+		return nil, nil, errors.New("the intel job requires addresses, CIDRs, ASNs or the reverse whois option")
+	}
+	return cfg, &args, nil
+}
+
+func checkJobFlags(fs *flag.FlagSet, allowed map[string]struct{}) error {
+	var denied []string
+
+	fs.Visit(func(f *flag.Flag) {
+		if _, found := allowed[f.Name]; !found {
+			denied = append(denied, "-"+f.Name)
+		}
+	})
+	if len(denied) > 0 {
+		return fmt.Errorf("the %s arguments are not permitted in jobs", strings.Join(denied, ", "))
+	}
+	return nil
+}
+
+// The wordlists of jobs are selected by name from the wordlists directory of the server.
+func (s *apiServer) jobWordlists(list format.ParseStrings) error {
+	dir := filepath.Join(config.OutputDirectory(s.cfg.Dir), "wordlists")
+
+	for i, name := range list {
+		if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+			return fmt.Errorf("the wordlist %q is not a file name within %s", name, dir)
+		}
+		list[i] = filepath.Join(dir, name)
+	}
+	return nil
+}
+
+func (j *apiJob) snapshot() *apiJob {
+	j.Lock()
+	defer j.Unlock()
+
+	return &apiJob{
+		ID:       j.ID,
+		Type:     j.Type,
+		Args:     j.Args,
+		Domains:  j.Domains,
+		Status:   j.Status,
+		Error:    j.Error,
+		Started:  j.Started,
+		Finished: j.Finished,
+		Names:    j.Names,
+		Results:  j.Results,
+	}
+}
+
+func (j *apiJob) nameDiscovered() {
+	j.Lock()
+	defer j.Unlock()
+
+	j.Names++
+}
+
+func (j *apiJob) add(out *requests.Output) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.output = append(j.output, out)
+	j.Results = len(j.output)
+	close(j.updated)
+	j.updated = make(chan struct{})
+}
+
+func (j *apiJob) finish(ctx context.Context, err error) {
+	j.Lock()
+	defer j.Unlock()
+
+	now := time.Now()
+	j.Finished = &now
+	switch {
+	case err != nil:
+		j.Status = jobFailed
+		j.Error = err.Error()
+	case ctx.Err() != nil:
+		j.Status = jobCanceled
+	default:
+		j.Status = jobFinished
+	}
+	close(j.updated)
+	j.updated = make(chan struct{})
+}
+
+// results returns the output starting at the provided index, whether the job is done,
+// and the channel closed when the job is next updated.
+func (j *apiJob) results(idx int) ([]*requests.Output, bool, chan struct{}) {
+	j.Lock()
+	defer j.Unlock()
+
+	var output []*requests.Output
+	if idx < len(j.output) {
+		output = j.output[idx:]
+	}
+	return output, j.Finished != nil, j.updated
+}
+
+// Results are streamed as NDJSON, or as Server-Sent Events when requested by the client.
+func streamJobResults(w http.ResponseWriter, req *http.Request, job *apiJob) {
+	flusher, _ := w.(http.Flusher)
+	sse := req.URL.Query().Get("format") == "sse" ||
+		strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	var idx int
+	for {
+		output, done, updated := job.results(idx)
+
+		for _, out := range output {
+			data, err := json.Marshal(out)
+			if err != nil {
+				continue
+			}
+			if sse {
+				fmt.Fprintf(w, "event: output\ndata: %s\n\n", data)
+			} else {
+				fmt.Fprintf(w, "%s\n", data)
+			}
+		}
+		idx += len(output)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			if sse {
+				status, _ := json.Marshal(job.snapshot())
+				fmt.Fprintf(w, "event: done\ndata: %s\n\n", status)
+			}
+			return
+		}
+
+		select {
+		case <-req.Context().Done():
+			return
+		case <-updated:
+		}
+	}
+}
+
+// The events stored in the graph databases are paged using the db command logic.
+func (s *apiServer) handleEvents(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	db, err := s.scopedGraph(req)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer db.Close()
+
+	ctx := req.Context()
+	uuids, earliest, latest := orderedEvents(ctx, db.EventList(ctx), db)
+	offset, limit := pageParams(req)
+	var events []*apiEvent
+	// Present the most recent events first, as the db command does
+	for i := len(uuids) - 1 - offset; i >= 0 && len(events) < limit; i-- {
+		events = append(events, &apiEvent{
+			UUID:    uuids[i],
+			Start:   earliest[i],
+			Finish:  latest[i],
+			Domains: db.EventDomains(ctx, uuids[i]),
+		})
+	}
+	writeJSONResponse(w, http.StatusOK, events)
+}
+
+func (s *apiServer) handleEventOutput(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/events/"), "/")
+	if _, err := uuid.Parse(id); err != nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("the event identifier is not a valid UUID"))
+		return
+	}
+
+	db, err := s.scopedGraph(req)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer db.Close()
+
+	output := getEventOutput(req.Context(), []string{id}, true, db, s.sys.Cache())
+	offset, limit := pageParams(req)
+	if offset > len(output) {
+		offset = len(output)
+	}
+	if end := offset + limit; end < len(output) {
+		output = output[offset:end]
+	} else {
+		output = output[offset:]
+	}
+	writeJSONResponse(w, http.StatusOK, output)
+}
+
+func (s *apiServer) scopedGraph(req *http.Request) (*netmap.Graph, error) {
+	dbs := s.sys.GraphDatabases()
+	if len(dbs) == 0 {
+		return nil, errors.New("no graph database is available to the server")
+	}
+
+	var domains []string
+	for _, d := range req.URL.Query()["domain"] {
+		domains = append(domains, strings.Split(d, ",")...)
+	}
+	return memGraphForScope(req.Context(), domains, dbs[0])
+}
+
+func pageParams(req *http.Request) (int, int) {
+	offset, limit := 0, defaultEventsLimit
+
+	if v, err := strconv.Atoi(req.URL.Query().Get("offset")); err == nil && v > 0 {
+		offset = v
+	}
+	if v, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	return offset, limit
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSONResponse(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,106 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/enum"
+	"github.com/OWASP/Amass/v3/format"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/fatih/color"
+)
+
+const sinkWriteTimeout = 30 * time.Second
+
+// sinkRecords keeps the DNS records obtained by the enumeration for delivery to the output sinks.
+type sinkRecords struct {
+	sync.Mutex
+	records map[string][]requests.DNSAnswer
+}
+
+func newSinkRecords() *sinkRecords {
+	return &sinkRecords{records: make(map[string][]requests.DNSAnswer)}
+}
+
+// Keeps the records reported by the enumeration events.
+func (s *sinkRecords) observe(ev enum.Event) {
+	re, ok := ev.(*enum.ResolvedEvent)
+	if !ok {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+loop:
+	for _, rr := range re.Records {
+		for _, existing := range s.records[re.Name] {
+			if rr.Type == existing.Type && rr.Data == existing.Data {
+				continue loop
+			}
+		}
+		s.records[re.Name] = append(s.records[re.Name], rr)
+	}
+}
+
+func (s *sinkRecords) get(name string) []requests.DNSAnswer {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]requests.DNSAnswer(nil), s.records[name]...)
+}
+
+func openOutputSinks(specs []string) ([]format.OutputSink, error) {
+	var sinks []format.OutputSink
+
+	for _, spec := range specs {
+		sink, err := format.NewOutputSink(spec)
+		if err != nil {
+			for _, s := range sinks {
+				_ = s.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func writeSinkOutput(sinks []format.OutputSink, recs *sinkRecords, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer func() {
+		for _, sink := range sinks {
+			if err := sink.Close(); err != nil {
+				r.Fprintf(color.Error, "Failed to close the %s: %v\n", sink, err)
+			}
+		}
+	}()
+
+	failed := make(map[format.OutputSink]struct{})
+	for out := range output {
+		res := &format.Result{
+			Output:  out,
+			Records: recs.get(out.Name),
+		}
+
+		for _, sink := range sinks {
+			if _, found := failed[sink]; found {
+				continue
+			}
+			// The findings are still delivered after the enumeration has been cancelled
+			ctx, cancel := context.WithTimeout(context.Background(), sinkWriteTimeout)
+			err := sink.Write(ctx, res)
+			cancel()
+
+			if err != nil {
+				failed[sink] = struct{}{}
+				r.Fprintf(color.Error, "The %s failed and will not receive more findings: %v\n", sink, err)
+			}
+		}
+	}
+}
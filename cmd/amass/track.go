@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/OWASP/Amass/v3/config"
@@ -283,7 +284,41 @@ func blueLine() {
 	fmt.Println()
 }
 
+// Kinds of differences identified between the findings of two enumerations.
+const (
+	diffFound   = "found"
+	diffMoved   = "moved"
+	diffRemoved = "removed"
+)
+
+type enumDiff struct {
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Addrs    []string `json:"addresses,omitempty"`
+	Previous []string `json:"previous_addresses,omitempty"`
+}
+
 func diffEnumOutput(older, newer []*requests.Output) []string {
+	var diff []string
+
+	for _, d := range compareEnumOutput(older, newer) {
+		switch d.Kind {
+		case diffFound:
+			diff = append(diff, fmt.Sprintf("%s%s %s", blue("Found: "),
+				green(d.Name), yellow(strings.Join(d.Addrs, ","))))
+		case diffMoved:
+			diff = append(diff, fmt.Sprintf("%s%s\n\t%s\t%s\n\t%s\t%s", blue("Moved: "),
+				green(d.Name), blue(" from "), yellow(strings.Join(d.Previous, ",")),
+				blue(" to "), yellow(strings.Join(d.Addrs, ","))))
+		case diffRemoved:
+			diff = append(diff, fmt.Sprintf("%s%s %s", blue("Removed: "),
+				green(d.Name), yellow(strings.Join(d.Addrs, ","))))
+		}
+	}
+	return diff
+}
+
+func compareEnumOutput(older, newer []*requests.Output) []*enumDiff {
 	oldmap := make(map[string]*requests.Output)
 	newmap := make(map[string]*requests.Output)
 
@@ -294,41 +329,47 @@ func diffEnumOutput(older, newer []*requests.Output) []string {
 		newmap[o.Name] = o
 	}
 
-	var diff []string
+	var diff []*enumDiff
 	for name, o := range newmap {
 		o2, found := oldmap[name]
 		if !found {
-			diff = append(diff, fmt.Sprintf("%s%s %s", blue("Found: "),
-				green(name), yellow(lineOfAddresses(o.Addresses))))
+			diff = append(diff, &enumDiff{
+				Kind:  diffFound,
+				Name:  name,
+				Addrs: addressStrings(o.Addresses),
+			})
 			continue
 		}
 
 		if !compareAddresses(o.Addresses, o2.Addresses) {
-			diff = append(diff, fmt.Sprintf("%s%s\n\t%s\t%s\n\t%s\t%s", blue("Moved: "),
-				green(name), blue(" from "), yellow(lineOfAddresses(o2.Addresses)),
-				blue(" to "), yellow(lineOfAddresses(o.Addresses))))
+			diff = append(diff, &enumDiff{
+				Kind:     diffMoved,
+				Name:     name,
+				Addrs:    addressStrings(o.Addresses),
+				Previous: addressStrings(o2.Addresses),
+			})
 		}
 	}
 
 	for name, o := range oldmap {
 		if _, found := newmap[name]; !found {
-			diff = append(diff, fmt.Sprintf("%s%s %s", blue("Removed: "),
-				green(name), yellow(lineOfAddresses(o.Addresses))))
+			diff = append(diff, &enumDiff{
+				Kind:  diffRemoved,
+				Name:  name,
+				Addrs: addressStrings(o.Addresses),
+			})
 		}
 	}
 	return diff
 }
 
-func lineOfAddresses(addrs []requests.AddressInfo) string {
-	var line string
+func addressStrings(addrs []requests.AddressInfo) []string {
+	var list []string
 
-	for i, addr := range addrs {
-		if i != 0 {
-			line = line + ","
-		}
-		line = line + addr.Address.String()
+	for _, addr := range addrs {
+		list = append(list, addr.Address.String())
 	}
-	return line
+	return list
 }
 
 func compareAddresses(addr1, addr2 []requests.AddressInfo) bool {
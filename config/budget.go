@@ -0,0 +1,95 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExhausted is returned when a data source has used up the requests allowed for the run or domain.
+var ErrBudgetExhausted = errors.New("the request budget of the data source has been used up")
+
+// sourceBudget tracks the requests sent by a data source during a run.
+type sourceBudget struct {
+	sync.Mutex
+	total   int
+	domains map[string]int
+}
+
+// SpendSourceBudget accounts for a request that the data source is about to send for the domain,
+// which can be empty when the request is not associated with a domain. ErrBudgetExhausted is
+// returned when the request would exceed the budgets provided by the configuration, and a message
+// is logged the first time each budget is used up.
+func (c *Config) SpendSourceBudget(source, domain string) error {
+	dsc := c.GetDataSourceConfig(source)
+	if dsc == nil || (dsc.MaxRequests <= 0 && dsc.MaxDomainRequests <= 0) {
+		return nil
+	}
+
+	b := &dsc.budget
+	b.Lock()
+	defer b.Unlock()
+
+	if dsc.MaxRequests > 0 && b.total >= dsc.MaxRequests {
+		if b.total == dsc.MaxRequests {
+			b.total++
+			c.Log.Printf("%s: The budget of %d requests has been used up and the data source was disabled", source, dsc.MaxRequests)
+		}
+		return ErrBudgetExhausted
+	}
+
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain != "" && dsc.MaxDomainRequests > 0 {
+		if b.domains == nil {
+			b.domains = make(map[string]int)
+		}
+		if num := b.domains[domain]; num >= dsc.MaxDomainRequests {
+			if num == dsc.MaxDomainRequests {
+				b.domains[domain]++
+				c.Log.Printf("%s: The budget of %d requests for %s has been used up", source, dsc.MaxDomainRequests, domain)
+			}
+			return ErrBudgetExhausted
+		}
+		b.domains[domain]++
+	}
+
+	b.total++
+	return nil
+}
+
+// SourceBudgetExhausted returns true when the data source cannot send more requests for the domain,
+// which can be empty to only check the budget of the run.
+func (c *Config) SourceBudgetExhausted(source, domain string) bool {
+	dsc := c.GetDataSourceConfig(source)
+	if dsc == nil {
+		return false
+	}
+
+	b := &dsc.budget
+	b.Lock()
+	defer b.Unlock()
+
+	if dsc.MaxRequests > 0 && b.total >= dsc.MaxRequests {
+		return true
+	}
+
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return domain != "" && dsc.MaxDomainRequests > 0 && b.domains[domain] >= dsc.MaxDomainRequests
+}
+
+// ResetSourceBudgets makes the full budgets available to the data sources, as done at the start of a run.
+func (c *Config) ResetSourceBudgets() {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, dsc := range c.datasrcConfigs {
+		dsc.budget.Lock()
+		dsc.budget.total = 0
+		dsc.budget.domains = nil
+		dsc.budget.Unlock()
+	}
+}
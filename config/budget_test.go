@@ -0,0 +1,67 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestSourceBudgets(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConfig()
+	c.Log = log.New(&buf, "", 0)
+
+	cfg, _ := ini.LoadSources(
+		ini.LoadOptions{
+			Insensitive:  true,
+			AllowShadows: true,
+		},
+		[]byte(`
+		[data_sources]
+		[data_sources.SecurityTrails]
+		max_requests = 3
+		max_requests_per_domain = 2
+		`),
+	)
+	if err := c.loadDataSourceSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	for _, domain := range []string{"owasp.org", "OWASP.org"} {
+		if err := c.SpendSourceBudget("SecurityTrails", domain); err != nil {
+			t.Errorf("The request for %s was rejected: %v", domain, err)
+		}
+	}
+	if err := c.SpendSourceBudget("SecurityTrails", "owasp.org"); err != ErrBudgetExhausted {
+		t.Error("The domain budget was exceeded")
+	}
+	if !c.SourceBudgetExhausted("SecurityTrails", "owasp.org") || c.SourceBudgetExhausted("SecurityTrails", "") {
+		t.Error("Only the budget of the domain should be used up")
+	}
+	if err := c.SpendSourceBudget("SecurityTrails", "utica.edu"); err != nil {
+		t.Errorf("The request for another domain was rejected: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.SpendSourceBudget("SecurityTrails", ""); err != ErrBudgetExhausted {
+			t.Error("The budget of the run was exceeded")
+		}
+	}
+	if err := c.SpendSourceBudget("Shodan", "owasp.org"); err != nil {
+		t.Errorf("The data source without a budget was rejected: %v", err)
+	}
+	if n := strings.Count(buf.String(), "has been used up"); n != 2 {
+		t.Errorf("Expected each budget to be reported once, got %d messages: %s", n, buf.String())
+	}
+
+	c.ResetSourceBudgets()
+	if c.SourceBudgetExhausted("SecurityTrails", "owasp.org") {
+		t.Error("The budgets were not reset")
+	}
+}
@@ -50,18 +50,36 @@ type Config struct {
 	// Logger for error messages
 	Log *log.Logger
 
+	// The hooks that receive the errors reported by the data sources
+	errHooks sourceErrorHooks
+
+	// The Lua modules loaded once for the ADS scripts
+	modules scriptModules
+
 	// The directory that stores the bolt db and other files created
 	Dir string `ini:"output_directory"`
 
 	// Alternative directory for scripts provided by the user
 	ScriptsDirectory string `ini:"scripts_directory"`
 
+	// The limits applied to each callback executed by the ADS scripts
+	ScriptLimits ScriptLimits
+
 	// The graph databases used by the system / enumerations
 	GraphDBs []*Database
 
+	// The output sinks, in the form type:target, that receive the enumeration findings
+	OutputSinks []string
+
 	// The maximum number of concurrent DNS queries
 	MaxDNSQueries int `ini:"maximum_dns_queries"`
 
+	// The number of minutes between saved checkpoints of the enumeration state
+	CheckpointInterval int `ini:"checkpoint_interval"`
+
+	// Will the enumeration identified by UUID continue from the last saved checkpoint?
+	Resume bool
+
 	// Names provided to seed the enumeration
 	ProvidedNames []string
 
@@ -124,11 +142,25 @@ type Config struct {
 	// Type of DNS records to query for
 	RecordTypes []string
 
+	// Determines if the DNSSEC chain of trust is validated for the zones of resolved names
+	DNSSEC bool
+
+	// Determines if the names are validated against the authoritative nameservers of their zones
+	Authoritative bool
+	// The QPS for each authoritative nameserver
+	AuthoritativeQPS int
+
 	// Resolver settings
 	Resolvers        []string
 	ResolversQPS     int
 	TrustedResolvers []string
 	TrustedQPS       int
+	// The QPS for each DNS-over-HTTPS and DNS-over-TLS resolver
+	DoHQPS int
+	DoTQPS int
+	// Determines if the plain DNS resolvers are reached through local servers that count the
+	// queries and timeouts of each resolver, as done for the encrypted resolvers
+	ResolverMetrics bool
 
 	// Option for verbose logging and output
 	Verbose bool
@@ -151,16 +183,26 @@ func NewConfig() *Config {
 		Ports:           []int{80, 443},
 		MinForRecursive: 1,
 		// The following is enum-only, but intel will just ignore them anyway
-		FlipWords:      true,
-		FlipNumbers:    true,
-		AddWords:       true,
-		AddNumbers:     true,
-		MinForWordFlip: 2,
-		EditDistance:   1,
-		Recursive:      true,
-		MinimumTTL:     1440,
-		ResolversQPS:   DefaultQueriesPerPublicResolver,
-		TrustedQPS:     DefaultQueriesPerBaselineResolver,
+		FlipWords:          true,
+		FlipNumbers:        true,
+		AddWords:           true,
+		AddNumbers:         true,
+		MinForWordFlip:     2,
+		EditDistance:       1,
+		Recursive:          true,
+		MinimumTTL:         1440,
+		CheckpointInterval: 5,
+		ResolversQPS:       DefaultQueriesPerPublicResolver,
+		TrustedQPS:         DefaultQueriesPerBaselineResolver,
+		DoHQPS:             DefaultQueriesPerEncryptedResolver,
+		DoTQPS:             DefaultQueriesPerEncryptedResolver,
+		AuthoritativeQPS:   DefaultQueriesPerAuthoritativeServer,
+		RecordTypes:        append([]string(nil), DefaultRecordTypes...),
+		ScriptLimits: ScriptLimits{
+			Timeout:      DefaultScriptTimeout,
+			Instructions: DefaultScriptInstructions,
+			Registry:     DefaultScriptRegistry,
+		},
 	}
 }
 
@@ -177,12 +219,7 @@ func (c *Config) CheckSettings() error {
 		if c.Passive {
 			return errors.New("brute forcing cannot be performed without DNS resolution")
 		} else if len(c.Wordlist) == 0 {
-			f, err := resources.GetResourceFile("namelist.txt")
-			if err != nil {
-				return err
-			}
-
-			c.Wordlist, err = getWordList(f)
+			c.Wordlist, err = DefaultWordlist()
 			if err != nil {
 				return err
 			}
@@ -243,11 +280,14 @@ func (c *Config) LoadSettings(path string) error {
 
 	loads := []func(cfg *ini.File) error{
 		c.loadResolverSettings,
+		c.loadDNSSettings,
 		c.loadScopeSettings,
 		c.loadAlterationSettings,
 		c.loadBruteForceSettings,
 		c.loadDatabaseSettings,
 		c.loadDataSourceSettings,
+		c.loadOutputSettings,
+		c.loadScriptSettings,
 	}
 	for _, load := range loads {
 		if err := load(cfg); err != nil {
@@ -335,6 +375,15 @@ func GetListFromFile(path string) ([]string, error) {
 	return s, err
 }
 
+// DefaultWordlist returns the embedded wordlist used for brute forcing when another has not been provided.
+func DefaultWordlist() ([]string, error) {
+	f, err := resources.GetResourceFile("namelist.txt")
+	if err != nil {
+		return nil, err
+	}
+	return getWordList(f)
+}
+
 func getWordList(reader io.Reader) ([]string, error) {
 	var words []string
 
@@ -6,9 +6,9 @@ package config
 
 import (
 	"fmt"
-	"math/rand"
 	"strings"
 
+	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/caffix/stringset"
 	"github.com/go-ini/ini"
 )
@@ -18,6 +18,26 @@ type DataSourceConfig struct {
 	Name  string
 	TTL   int `ini:"ttl"`
 	creds map[string]*Credentials
+
+	// The maximum number of requests the data source can send during a run
+	MaxRequests int `ini:"max_requests"`
+
+	// The maximum number of requests the data source can send for each domain during a run
+	MaxDomainRequests int `ini:"max_requests_per_domain"`
+	budget            sourceBudget
+
+	// The number of callbacks the scripted data source can execute concurrently
+	Concurrency int `ini:"concurrency"`
+
+	// The OAuth2 token endpoint used to obtain bearer tokens with the client credentials grant
+	TokenURL string `ini:"token_url"`
+
+	// The scopes requested for the OAuth2 bearer tokens
+	Scopes []string `ini:"scopes" delim:","`
+
+	// The policy used to rotate the credentials sets: round_robin (default) or quota
+	Rotation string `ini:"rotation"`
+	rotation credentialRotation
 }
 
 // Credentials contains values required for authenticating with web APIs.
@@ -27,6 +47,8 @@ type Credentials struct {
 	Password string `ini:"password"`
 	Key      string `ini:"apikey"`
 	Secret   string `ini:"secret"`
+	// The maximum number of requests that can be sent using the credentials during a run
+	Quota int `ini:"quota"`
 }
 
 // GetDataSourceConfig returns the DataSourceConfig associated with the data source name argument.
@@ -53,24 +75,83 @@ func (dsc *DataSourceConfig) AddCredentials(cred *Credentials) error {
 		return fmt.Errorf("AddCredentials: The Credentials argument is invalid")
 	}
 
+	dsc.rotation.Lock()
+	defer dsc.rotation.Unlock()
+
 	if dsc.creds == nil {
 		dsc.creds = make(map[string]*Credentials)
 	}
+	if _, found := dsc.creds[cred.Name]; !found {
+		dsc.rotation.order = append(dsc.rotation.order, cred.Name)
+	}
 
 	dsc.creds[cred.Name] = cred
 	return nil
 }
 
-// GetCredentials returns randomly selected Credentials associated with the receiver configuration.
+// GetCredentials returns the Credentials associated with the receiver configuration that should be
+// used next. The credentials sets are rotated according to the configured policy, and nil is returned
+// when all the credentials have been marked as invalid or exhausted.
 func (dsc *DataSourceConfig) GetCredentials() *Credentials {
-	if num := len(dsc.creds); num > 0 {
-		var creds []*Credentials
-		for _, c := range dsc.creds {
-			creds = append(creds, c)
+	return dsc.selectCredentials(true)
+}
+
+// PeekCredentials returns the Credentials that GetCredentials would return next, without advancing
+// the rotation. This allows the credentials to be checked without consuming a selection.
+func (dsc *DataSourceConfig) PeekCredentials() *Credentials {
+	return dsc.selectCredentials(false)
+}
+
+// ClientCredentials returns the OAuth2 client credentials for the data source, using the apikey
+// and secret of the provided Credentials as the client ID and secret. Nil is returned when the
+// token endpoint has not been configured or the credentials are missing.
+func (dsc *DataSourceConfig) ClientCredentials(creds *Credentials) *http.ClientCredentials {
+	if dsc.TokenURL == "" {
+		return nil
+	}
+	if creds == nil || creds.Key == "" || creds.Secret == "" {
+		return nil
+	}
+
+	var scopes []string
+	for _, scope := range dsc.Scopes {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
 		}
-		return creds[rand.Intn(num)]
 	}
-	return nil
+
+	return &http.ClientCredentials{
+		TokenURL:     dsc.TokenURL,
+		ClientID:     creds.Key,
+		ClientSecret: creds.Secret,
+		Scopes:       scopes,
+	}
+}
+
+// CredentialSecrets returns the passwords, API keys and secrets of all the credentials in the configuration.
+func (c *Config) CredentialSecrets() []string {
+	c.Lock()
+	dscs := make([]*DataSourceConfig, 0, len(c.datasrcConfigs))
+	for _, dsc := range c.datasrcConfigs {
+		dscs = append(dscs, dsc)
+	}
+	c.Unlock()
+
+	secrets := stringset.New()
+	defer secrets.Close()
+
+	for _, dsc := range dscs {
+		dsc.rotation.Lock()
+		for _, cred := range dsc.creds {
+			for _, s := range []string{cred.Password, cred.Key, cred.Secret} {
+				if s != "" {
+					secrets.Insert(s)
+				}
+			}
+		}
+		dsc.rotation.Unlock()
+	}
+	return secrets.Slice()
 }
 
 func (c *Config) loadDataSourceSettings(cfg *ini.File) error {
@@ -4,6 +4,9 @@
 package config
 
 import (
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/go-ini/ini"
@@ -54,6 +57,18 @@ func TestGetCredentials(t *testing.T) {
 	}
 }
 
+func TestCredentialSecrets(t *testing.T) {
+	c := NewConfig()
+	_ = c.GetDataSourceConfig("first").AddCredentials(&Credentials{Name: "account1", Username: "user", Password: "pass", Key: "key1"})
+	_ = c.GetDataSourceConfig("second").AddCredentials(&Credentials{Name: "account1", Key: "key2", Secret: "secret"})
+
+	secrets := c.CredentialSecrets()
+	sort.Strings(secrets)
+	if expected := []string{"key1", "key2", "pass", "secret"}; !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("CredentialSecrets returned %v, expected %v", secrets, expected)
+	}
+}
+
 func TestLoadDataSourceSettings(t *testing.T) {
 	c := NewConfig()
 
@@ -92,6 +107,13 @@ func TestLoadDataSourceSettings(t *testing.T) {
 		[data_sources.BinaryEdge]
 		[data_sources.BinaryEdge.Credentials]
 		apikey = fake2
+
+		[data_sources.Twitter]
+		token_url = https://api.twitter.com/oauth2/token
+		scopes = read, write
+		[data_sources.Twitter.Credentials]
+		apikey = client
+		secret = secret
 		`),
 	)
 
@@ -109,4 +131,14 @@ func TestLoadDataSourceSettings(t *testing.T) {
 	if creds := dsc.GetCredentials(); creds == nil || creds.Key != "fake" {
 		t.Errorf("Failed to load data source credentials")
 	}
+
+	if cc := c.GetDataSourceConfig("BinaryEdge").ClientCredentials(&Credentials{Key: "client", Secret: "secret"}); cc != nil {
+		t.Errorf("Returned client credentials without a token endpoint")
+	}
+	twitter := c.GetDataSourceConfig("Twitter")
+	if cc := twitter.ClientCredentials(twitter.GetCredentials()); cc == nil ||
+		cc.TokenURL != "https://api.twitter.com/oauth2/token" || cc.ClientID != "client" ||
+		cc.ClientSecret != "secret" || strings.Join(cc.Scopes, " ") != "read write" {
+		t.Errorf("Failed to load the OAuth2 client credentials: %+v", cc)
+	}
 }
@@ -0,0 +1,70 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caffix/stringset"
+	"github.com/go-ini/ini"
+)
+
+// DefaultRecordTypes are the DNS record types queried for each discovered name by default.
+var DefaultRecordTypes = []string{"CNAME", "A", "AAAA"}
+
+// The DNS record types that can be queried for each discovered name.
+var supportedRecordTypes = stringset.New("CNAME", "A", "AAAA", "TXT", "MX",
+	"NS", "CAA", "HTTPS", "SVCB", "SRV", "SOA", "SPF")
+
+// SetRecordTypes selects the DNS record types queried for each discovered name, in addition to the
+// default record types. The CNAME record type is always queried first, since the aliases need to be
+// followed to the addresses, and the A and AAAA records are always queried to resolve the names.
+func (c *Config) SetRecordTypes(types ...string) error {
+	rtypes := append([]string(nil), DefaultRecordTypes...)
+	seen := stringset.New(DefaultRecordTypes...)
+	defer seen.Close()
+
+	for _, t := range types {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "" || seen.Has(t) {
+			continue
+		}
+		if !supportedRecordTypes.Has(t) {
+			return fmt.Errorf("the DNS record type %s is not supported", t)
+		}
+
+		seen.Insert(t)
+		rtypes = append(rtypes, t)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.RecordTypes = rtypes
+	return nil
+}
+
+func (c *Config) loadDNSSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("dns")
+	if err != nil {
+		return nil
+	}
+
+	c.DNSSEC = sec.Key("dnssec").MustBool(false)
+	c.Authoritative = sec.Key("authoritative").MustBool(false)
+	if qps := sec.Key("authoritative_qps").MustInt(0); qps > 0 {
+		c.AuthoritativeQPS = qps
+	}
+
+	var types []string
+	for _, value := range sec.Key("record_type").ValueWithShadows() {
+		types = append(types, strings.Split(value, ",")...)
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	return c.SetRecordTypes(types...)
+}
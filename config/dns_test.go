@@ -0,0 +1,63 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestRecordTypes(t *testing.T) {
+	c := NewConfig()
+
+	if got := strings.Join(c.RecordTypes, ","); got != "CNAME,A,AAAA" {
+		t.Errorf("Unexpected default record types: %s", got)
+	}
+	if err := c.SetRecordTypes("a", "TXT", "A", "cname", "CAA"); err != nil {
+		t.Errorf("The record types were not accepted: %v", err)
+	}
+	if got := strings.Join(c.RecordTypes, ","); got != "CNAME,A,AAAA,TXT,CAA" {
+		t.Errorf("The record types were not normalized: %s", got)
+	}
+	if err := c.SetRecordTypes("TXT"); err != nil {
+		t.Errorf("The record types were not accepted: %v", err)
+	}
+	if got := strings.Join(c.RecordTypes, ","); got != "CNAME,A,AAAA,TXT" {
+		t.Errorf("The address record types were not kept: %s", got)
+	}
+	if err := c.SetRecordTypes("A", "AXFR"); err == nil {
+		t.Errorf("An unsupported record type was accepted")
+	}
+
+	cfg, _ := ini.LoadSources(
+		ini.LoadOptions{
+			Insensitive:  true,
+			AllowShadows: true,
+		},
+		[]byte(`
+		[dns]
+		record_type = A, AAAA
+		record_type = MX
+		record_type = HTTPS
+		dnssec = true
+		authoritative = true
+		authoritative_qps = 25
+		`),
+	)
+	if err := c.loadDNSSettings(cfg); err != nil {
+		t.Errorf("Failed to load the DNS settings: %v", err)
+	}
+	if got := strings.Join(c.RecordTypes, ","); got != "CNAME,A,AAAA,MX,HTTPS" {
+		t.Errorf("The record types were not loaded: %s", got)
+	}
+	if !c.DNSSEC {
+		t.Errorf("The dnssec setting was not loaded")
+	}
+	if !c.Authoritative || c.AuthoritativeQPS != 25 {
+		t.Errorf("The authoritative settings were not loaded")
+	}
+}
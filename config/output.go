@@ -0,0 +1,50 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// AddOutputSinks appends the output sink specifications, in the form type:target, to the configuration.
+func (c *Config) AddOutputSinks(sinks ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	// The targets can be case-sensitive file paths and URLs
+loop:
+	for _, sink := range sinks {
+		s := strings.TrimSpace(sink)
+		if s == "" {
+			continue
+		}
+
+		for _, existing := range c.OutputSinks {
+			if s == existing {
+				continue loop
+			}
+		}
+		c.OutputSinks = append(c.OutputSinks, s)
+	}
+}
+
+func (c *Config) loadOutputSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("output")
+	if err != nil {
+		return nil
+	}
+
+	for _, sink := range sec.Key("sink").ValueWithShadows() {
+		if parts := strings.SplitN(sink, ":", 2); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("the output sink %q is not of the form type:target", sink)
+		}
+	}
+
+	c.AddOutputSinks(sec.Key("sink").ValueWithShadows()...)
+	return nil
+}
@@ -0,0 +1,39 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadOutputSettings(t *testing.T) {
+	c := NewConfig()
+
+	cfg, _ := ini.LoadSources(
+		ini.LoadOptions{
+			Insensitive:  true,
+			AllowShadows: true,
+		},
+		[]byte(`
+		[output]
+		sink = ndjson:/tmp/amass.ndjson
+		sink = webhook:https://inventory.example.com/Amass
+		sink = ndjson:/tmp/amass.ndjson
+		`),
+	)
+	if err := c.loadOutputSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(c.OutputSinks) != 2 || c.OutputSinks[1] != "webhook:https://inventory.example.com/Amass" {
+		t.Errorf("Unexpected output sinks: %v", c.OutputSinks)
+	}
+
+	bad, _ := ini.LoadSources(ini.LoadOptions{AllowShadows: true}, []byte("[output]\nsink = ndjson\n"))
+	if err := NewConfig().loadOutputSettings(bad); err == nil {
+		t.Error("The output sink without a target was accepted")
+	}
+}
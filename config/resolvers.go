@@ -24,6 +24,12 @@ const DefaultQueriesPerPublicResolver = 5
 // DefaultQueriesPerBaselineResolver is the number of queries sent to each trusted DNS resolver per second.
 const DefaultQueriesPerBaselineResolver = 10
 
+// DefaultQueriesPerEncryptedResolver is the number of queries sent to each DNS-over-HTTPS and DNS-over-TLS resolver per second.
+const DefaultQueriesPerEncryptedResolver = 10
+
+// DefaultQueriesPerAuthoritativeServer is the number of queries sent to each authoritative nameserver per second.
+const DefaultQueriesPerAuthoritativeServer = 10
+
 const minResolverReliability = 0.85
 
 // DefaultBaselineResolvers is a list of trusted public DNS resolvers.
@@ -125,8 +131,8 @@ func (c *Config) AddResolver(resolver string) {
 
 // SetTrustedResolvers assigns the trusted resolver names provided in the parameter to the list in the configuration.
 func (c *Config) SetTrustedResolvers(resolvers ...string) {
-	c.Resolvers = []string{}
-	c.AddResolvers(resolvers...)
+	c.TrustedResolvers = []string{}
+	c.AddTrustedResolvers(resolvers...)
 }
 
 // AddTrustedResolvers appends the trusted resolver names provided in the parameter to the list in the configuration.
@@ -162,6 +168,13 @@ func (c *Config) loadResolverSettings(cfg *ini.File) error {
 		return nil
 	}
 
+	if qps := sec.Key("doh_qps").MustInt(0); qps > 0 {
+		c.DoHQPS = qps
+	}
+	if qps := sec.Key("dot_qps").MustInt(0); qps > 0 {
+		c.DoTQPS = qps
+	}
+
 	c.Resolvers = stringset.Deduplicate(sec.Key("resolver").ValueWithShadows())
 	if len(c.Resolvers) == 0 {
 		return errors.New("no resolver keys were found in the resolvers section")
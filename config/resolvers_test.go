@@ -42,3 +42,13 @@ func TestConfigSetResolvers(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigSetTrustedResolvers(t *testing.T) {
+	c := NewConfig()
+	c.SetResolvers("8.8.8.8")
+	c.SetTrustedResolvers("https://dns.google/dns-query", "tls://1.1.1.1")
+
+	if len(c.Resolvers) != 1 || len(c.TrustedResolvers) != 2 {
+		t.Errorf("The trusted resolvers replaced the untrusted resolvers: %v, %v", c.Resolvers, c.TrustedResolvers)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	amasshttp "github.com/OWASP/Amass/v3/net/http"
+)
+
+// The status of the credentials sets used by the data sources.
+const (
+	CredentialsActive    = "active"
+	CredentialsExhausted = "exhausted"
+	CredentialsInvalid   = "invalid"
+)
+
+// The rotation policies that select the credentials used by a data source.
+const (
+	RotateRoundRobin = "round_robin"
+	RotateQuota      = "quota"
+)
+
+// The time exhausted credentials are not used when the API did not request a delay.
+const credentialsCooldown = 5 * time.Minute
+
+// CredentialsUsage contains the requests sent using a set of credentials and its status.
+type CredentialsUsage struct {
+	Name     string
+	Requests int
+	Status   string
+}
+
+// credentialRotation tracks the use of the credentials sets by a data source during a run.
+type credentialRotation struct {
+	sync.Mutex
+	order []string
+	next  int
+	state map[string]*credentialState
+}
+
+type credentialState struct {
+	requests int
+	status   string
+	until    time.Time
+}
+
+func (r *credentialRotation) get(name string) *credentialState {
+	if r.state == nil {
+		r.state = make(map[string]*credentialState)
+	}
+	if _, found := r.state[name]; !found {
+		r.state[name] = &credentialState{status: CredentialsActive}
+	}
+	return r.state[name]
+}
+
+// Returns true when the credentials can be used. Exhausted credentials become
+// available again once the delay requested by the API has passed.
+func (r *credentialRotation) available(cred *Credentials, now time.Time) bool {
+	st := r.get(cred.Name)
+
+	if st.status == CredentialsExhausted && !st.until.IsZero() && now.After(st.until) {
+		st.status = CredentialsActive
+		st.until = time.Time{}
+	}
+	if cred.Quota > 0 && st.requests >= cred.Quota {
+		st.status = CredentialsExhausted
+		st.until = time.Time{}
+	}
+	return st.status == CredentialsActive
+}
+
+// Returns the remaining requests that can be sent using the credentials.
+func (r *credentialRotation) remaining(cred *Credentials) int {
+	if cred.Quota <= 0 {
+		return math.MaxInt
+	}
+	return cred.Quota - r.get(cred.Name).requests
+}
+
+// Selects the next credentials to be used by the data source, according to the rotation policy.
+// The rotation only moves on to the following credentials when advance is true.
+func (dsc *DataSourceConfig) selectCredentials(advance bool) *Credentials {
+	r := &dsc.rotation
+	r.Lock()
+	defer r.Unlock()
+
+	num := len(r.order)
+	if num == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var best *Credentials
+	var bestIdx int
+	for i := 0; i < num; i++ {
+		idx := (r.next + i) % num
+		cred := dsc.creds[r.order[idx]]
+		if !r.available(cred, now) {
+			continue
+		}
+		if strings.ToLower(dsc.Rotation) != RotateQuota {
+			best, bestIdx = cred, idx
+			break
+		}
+		if best == nil || r.remaining(cred) > r.remaining(best) {
+			best, bestIdx = cred, idx
+		}
+	}
+
+	if best != nil && advance {
+		r.next = (bestIdx + 1) % num
+	}
+	return best
+}
+
+// ReportCredentials accounts for a request the data source sent using the credentials, and
+// marks the credentials as invalid or exhausted when the response status indicates that the API
+// rejected them. The following selections rotate to the other credentials of the data source.
+func (c *Config) ReportCredentials(source string, cred *Credentials, resp *amasshttp.Response) {
+	dsc := c.GetDataSourceConfig(source)
+	if dsc == nil || cred == nil {
+		return
+	}
+
+	r := &dsc.rotation
+	r.Lock()
+	defer r.Unlock()
+
+	st := r.get(cred.Name)
+	st.requests++
+	if cred.Quota > 0 && st.requests == cred.Quota {
+		c.Log.Printf("%s: The quota of %d requests for the %s credentials has been used up", source, cred.Quota, cred.Name)
+	}
+	if resp == nil || st.status == CredentialsInvalid {
+		return
+	}
+
+	switch resp.Status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		st.status = CredentialsInvalid
+	case http.StatusTooManyRequests:
+		delay, found := amasshttp.RetryAfter(resp)
+		if !found || delay <= 0 {
+			delay = credentialsCooldown
+		}
+		st.status = CredentialsExhausted
+		st.until = time.Now().Add(delay)
+	default:
+		return
+	}
+	c.Log.Printf("%s: The %s credentials were marked %s after receiving status %d, rotating to the next credentials",
+		source, cred.Name, st.status, resp.Status)
+}
+
+// CredentialsUsage returns the requests sent using each set of credentials and its status.
+func (dsc *DataSourceConfig) CredentialsUsage() []*CredentialsUsage {
+	r := &dsc.rotation
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	var usage []*CredentialsUsage
+	for _, name := range r.order {
+		cred := dsc.creds[name]
+
+		r.available(cred, now)
+		st := r.get(name)
+		usage = append(usage, &CredentialsUsage{
+			Name:     name,
+			Requests: st.requests,
+			Status:   st.status,
+		})
+	}
+	return usage
+}
+
+// ResetCredentialsRotation makes all the credentials available to the data sources, as done at the start of a run.
+func (c *Config) ResetCredentialsRotation() {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, dsc := range c.datasrcConfigs {
+		dsc.rotation.Lock()
+		dsc.rotation.next = 0
+		dsc.rotation.state = nil
+		dsc.rotation.Unlock()
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	amasshttp "github.com/OWASP/Amass/v3/net/http"
+)
+
+func TestCredentialsRotation(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConfig()
+	c.Log = log.New(&buf, "", 0)
+
+	dsc := c.GetDataSourceConfig("Shodan")
+	for _, name := range []string{"first", "second", "third"} {
+		_ = dsc.AddCredentials(&Credentials{Name: name, Key: name + "-key"})
+	}
+
+	if cred := dsc.PeekCredentials(); cred == nil || cred.Name != "first" {
+		t.Errorf("The credentials to be used next were not returned")
+	}
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		names = append(names, dsc.GetCredentials().Name)
+	}
+	if got := strings.Join(names, ","); got != "first,second,third,first" {
+		t.Errorf("The credentials were not rotated round-robin: %s", got)
+	}
+
+	c.ReportCredentials("Shodan", dsc.creds["second"], &amasshttp.Response{Status: http.StatusUnauthorized})
+	header := make(http.Header)
+	header.Set("Retry-After", "3600")
+	c.ReportCredentials("Shodan", dsc.creds["third"], &amasshttp.Response{Status: http.StatusTooManyRequests, Header: header})
+	for i := 0; i < 2; i++ {
+		if cred := dsc.GetCredentials(); cred == nil || cred.Name != "first" {
+			t.Errorf("The rejected credentials were not rotated out")
+		}
+	}
+	if n := strings.Count(buf.String(), "rotating to the next credentials"); n != 2 {
+		t.Errorf("Expected the rotations to be logged, got: %s", buf.String())
+	}
+
+	// The exhausted credentials are available again after the delay
+	dsc.rotation.state["third"].until = time.Now().Add(-time.Second)
+	usage := dsc.CredentialsUsage()
+	if len(usage) != 3 || usage[0].Status != CredentialsActive || usage[1].Status != CredentialsInvalid ||
+		usage[1].Requests != 1 || usage[2].Status != CredentialsActive {
+		t.Errorf("The credentials usage was not reported correctly")
+	}
+
+	c.ReportCredentials("Shodan", dsc.creds["first"], &amasshttp.Response{Status: http.StatusForbidden})
+	c.ReportCredentials("Shodan", dsc.creds["third"], &amasshttp.Response{Status: http.StatusForbidden})
+	if cred := dsc.GetCredentials(); cred != nil {
+		t.Errorf("Credentials were returned after all of them were rejected")
+	}
+
+	c.ResetCredentialsRotation()
+	if cred := dsc.GetCredentials(); cred == nil || cred.Name != "first" {
+		t.Errorf("The credentials were not made available at the start of the run")
+	}
+}
+
+func TestCredentialsQuota(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConfig()
+	c.Log = log.New(&buf, "", 0)
+
+	dsc := c.GetDataSourceConfig("Censys")
+	dsc.Rotation = RotateQuota
+	_ = dsc.AddCredentials(&Credentials{Name: "small", Key: "small-key", Quota: 2})
+	_ = dsc.AddCredentials(&Credentials{Name: "large", Key: "large-key", Quota: 3})
+
+	var names []string
+	for i := 0; i < 6; i++ {
+		cred := dsc.GetCredentials()
+		if cred == nil {
+			names = append(names, "none")
+			continue
+		}
+
+		names = append(names, cred.Name)
+		c.ReportCredentials("Censys", cred, &amasshttp.Response{Status: http.StatusOK})
+	}
+	if got := strings.Join(names, ","); got != "large,small,large,small,large,none" {
+		t.Errorf("The credentials were not selected by the remaining quota: %s", got)
+	}
+	if n := strings.Count(buf.String(), "has been used up"); n != 2 {
+		t.Errorf("Expected each quota to be reported once, got: %s", buf.String())
+	}
+}
@@ -72,6 +72,26 @@ func (c *Config) AddDomain(domain string) {
 	c.domains = stringset.Deduplicate(c.domains)
 }
 
+// RemoveDomain removes the domain name provided in the parameter from the list in the configuration.
+func (c *Config) RemoveDomain(domain string) {
+	c.Lock()
+	defer c.Unlock()
+
+	d := strings.TrimSpace(domain)
+	if _, found := c.regexps[d]; !found {
+		return
+	}
+
+	delete(c.regexps, d)
+	domains := make([]string, 0, len(c.domains))
+	for _, name := range c.domains {
+		if name != d {
+			domains = append(domains, name)
+		}
+	}
+	c.domains = domains
+}
+
 // Domains returns the list of domain names currently in the configuration.
 func (c *Config) Domains() []string {
 	c.Lock()
@@ -61,6 +61,22 @@ func TestConfigAddDomains(t *testing.T) {
 	}
 }
 
+func TestConfigRemoveDomain(t *testing.T) {
+	c := new(Config)
+	c.AddDomains("owasp.org", "utica.edu")
+	c.RemoveDomain("owasp.org")
+
+	if c.IsDomainInScope("www.owasp.org") {
+		t.Errorf("Config.RemoveDomain() error = the removed domain is still in scope")
+	}
+	if !c.IsDomainInScope("www.utica.edu") || c.DomainRegex("utica.edu") == nil {
+		t.Errorf("Config.RemoveDomain() error = the other domain was removed")
+	}
+	if c.DomainRegex("owasp.org") != nil {
+		t.Errorf("Config.RemoveDomain() error = the regular expression was not removed")
+	}
+}
+
 func TestConfigParseIPsParseRange(t *testing.T) {
 	type args struct {
 		s string
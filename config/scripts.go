@@ -5,13 +5,36 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/OWASP/Amass/v3/resources"
+	"github.com/go-ini/ini"
 )
 
+// The default limits applied to each callback executed by the ADS scripts.
+const (
+	DefaultScriptTimeout      = 600
+	DefaultScriptInstructions = 100000000
+	DefaultScriptRegistry     = 1024 * 1024
+)
+
+// ScriptLimits constrains the resources used by each callback executed by an ADS script.
+// A value of zero disables the limit.
+type ScriptLimits struct {
+	// The number of seconds that a callback can execute
+	Timeout int
+	// The number of Lua VM instructions that a callback can execute
+	Instructions int
+	// The number of slots that the registry of each Lua state can grow to. The registry only
+	// holds the stack values, so the memory used by the strings and tables is not bounded
+	Registry int
+}
+
 // AcquireScripts returns all the default and user provided scripts for data sources.
 func (c *Config) AcquireScripts() ([]string, error) {
 	scripts, err := resources.GetDefaultScripts()
@@ -19,21 +42,31 @@ func (c *Config) AcquireScripts() ([]string, error) {
 		return scripts, err
 	}
 
-	dir := OutputDirectory(c.Dir)
-	if dir == "" {
-		return scripts, nil
+	user, err := c.AcquireUserScripts()
+	if err != nil {
+		return scripts, err
 	}
 
-	finfo, err := os.Stat(dir)
-	if os.IsNotExist(err) || !finfo.IsDir() {
-		return scripts, errors.New("the output directory does not exist or is not a directory")
+	paths := make([]string, 0, len(user))
+	for path := range user {
+		paths = append(paths, path)
 	}
+	sort.Strings(paths)
 
-	paths := []string{filepath.Join(dir, "scripts")}
-	if c.ScriptsDirectory != "" {
-		paths = append(paths, c.ScriptsDirectory)
+	for _, path := range paths {
+		scripts = append(scripts, user[path])
+	}
+	return scripts, nil
+}
+
+// AcquireUserScripts returns the user provided scripts for data sources, keyed by file path.
+func (c *Config) AcquireUserScripts() (map[string]string, error) {
+	paths, err := c.scriptPaths()
+	if err != nil {
+		return nil, err
 	}
 
+	scripts := make(map[string]string)
 	for _, path := range paths {
 		_ = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
@@ -49,10 +82,115 @@ func (c *Config) AcquireScripts() ([]string, error) {
 				return err
 			}
 
-			scripts = append(scripts, string(data))
+			scripts[path] = string(data)
 			return nil
 		})
 	}
 
 	return scripts, nil
 }
+
+type scriptModules struct {
+	sync.Once
+	modules map[string]string
+	err     error
+}
+
+// ScriptModules returns the Lua modules for the ADS scripts, which are acquired the first time
+// it is called. The modules are loaded once for the System using the configuration, instead of
+// once for each script.
+func (c *Config) ScriptModules() (map[string]string, error) {
+	c.modules.Do(func() {
+		c.modules.modules, c.modules.err = c.AcquireScriptModules()
+	})
+	return c.modules.modules, c.modules.err
+}
+
+// AcquireScriptModules returns the Lua modules that can be required by the ADS scripts, keyed
+// by module name. The user provided modules take precedence over the embedded modules.
+func (c *Config) AcquireScriptModules() (map[string]string, error) {
+	modules, err := resources.GetScriptModules()
+	if err != nil {
+		return modules, err
+	}
+
+	// Without the output directory, only the embedded modules are available
+	paths, err := c.scriptPaths()
+	if err != nil {
+		return modules, nil
+	}
+
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Is this file not a Lua module?
+			if info.IsDir() || filepath.Ext(info.Name()) != resources.ScriptModuleExt {
+				return nil
+			}
+			// Get the module content
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+
+			modules[resources.ScriptModuleName(rel)] = string(data)
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return modules, fmt.Errorf("failed to load the Lua modules from %s: %v", root, err)
+		}
+	}
+
+	return modules, nil
+}
+
+// Returns the directories that contain the user provided scripts and Lua modules.
+func (c *Config) scriptPaths() ([]string, error) {
+	dir := OutputDirectory(c.Dir)
+	if dir == "" {
+		return nil, nil
+	}
+
+	finfo, err := os.Stat(dir)
+	if os.IsNotExist(err) || !finfo.IsDir() {
+		return nil, errors.New("the output directory does not exist or is not a directory")
+	}
+
+	paths := []string{filepath.Join(dir, "scripts")}
+	if c.ScriptsDirectory != "" {
+		paths = append(paths, c.ScriptsDirectory)
+	}
+	return paths, nil
+}
+
+func (c *Config) loadScriptSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("scripts")
+	if err != nil {
+		return nil
+	}
+
+	limits := map[string]*int{
+		"callback_timeout": &c.ScriptLimits.Timeout,
+		"max_instructions": &c.ScriptLimits.Instructions,
+		"max_registry":     &c.ScriptLimits.Registry,
+	}
+	for key, limit := range limits {
+		if !sec.HasKey(key) {
+			continue
+		}
+
+		val, err := sec.Key(key).Int()
+		if err != nil || val < 0 {
+			return fmt.Errorf("the scripts %s setting must be a number greater than or equal to zero", key)
+		}
+		*limit = val
+	}
+	return nil
+}
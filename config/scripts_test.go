@@ -0,0 +1,73 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadScriptSettings(t *testing.T) {
+	c := NewConfig()
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[scripts]
+		callback_timeout = 30
+		max_instructions = 0
+		`),
+	)
+	if err := c.loadScriptSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	expected := ScriptLimits{Timeout: 30, Instructions: 0, Registry: DefaultScriptRegistry}
+	if c.ScriptLimits != expected {
+		t.Errorf("Unexpected script limits: %+v", c.ScriptLimits)
+	}
+
+	bad, _ := ini.LoadSources(ini.LoadOptions{}, []byte("[scripts]\nmax_registry = -1\n"))
+	if err := NewConfig().loadScriptSettings(bad); err == nil {
+		t.Error("The negative registry limit was accepted")
+	}
+}
+
+func TestScriptModules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "scripts", "private"), 0700); err != nil {
+		t.Fatalf("Failed to create the scripts directory: %v", err)
+	}
+
+	path := filepath.Join(dir, "scripts", "private", "helpers.lua")
+	if err := os.WriteFile(path, []byte("return {}"), 0600); err != nil {
+		t.Fatalf("Failed to write the module: %v", err)
+	}
+
+	c := NewConfig()
+	c.Dir = dir
+	modules, err := c.ScriptModules()
+	if err != nil {
+		t.Fatalf("ScriptModules returned the error: %v", err)
+	}
+	if modules["private.helpers"] != "return {}" || modules["utils"] == "" {
+		t.Errorf("ScriptModules did not return the embedded and user modules: %v", modules)
+	}
+	// The modules are only loaded once
+	if err := os.WriteFile(path, []byte("return nil"), 0600); err != nil {
+		t.Fatalf("Failed to write the module: %v", err)
+	}
+	if modules, _ := c.ScriptModules(); modules["private.helpers"] != "return {}" {
+		t.Errorf("ScriptModules loaded the modules again")
+	}
+
+	c = NewConfig()
+	c.Dir = dir
+	c.ScriptsDirectory = filepath.Join(dir, "missing")
+	if _, err := c.ScriptModules(); err != nil {
+		t.Errorf("ScriptModules failed due to the missing scripts directory: %v", err)
+	}
+}
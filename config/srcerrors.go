@@ -0,0 +1,81 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"sync"
+)
+
+// SourceErrorHook receives the errors reported by the data sources, along with the domain
+// of the request that failed. The domain is empty when the request was not about a domain.
+type SourceErrorHook func(source, domain string, err error)
+
+// The context key used to store the domain name of the request being served by a data source.
+type sourceDomainKey struct{}
+
+// WithSourceDomain returns a copy of the context carrying the domain name of the request
+// being served by a data source.
+func WithSourceDomain(ctx context.Context, domain string) context.Context {
+	return context.WithValue(ctx, sourceDomainKey{}, domain)
+}
+
+// SourceDomain returns the domain name of the request being served by a data source using the context.
+func SourceDomain(ctx context.Context) string {
+	if domain, ok := ctx.Value(sourceDomainKey{}).(string); ok {
+		return domain
+	}
+	return ""
+}
+
+type sourceErrorHooks struct {
+	sync.Mutex
+	next  int
+	hooks map[int]SourceErrorHook
+}
+
+// AddSourceErrorHook registers the hook with the configuration and returns the function that removes it.
+func (c *Config) AddSourceErrorHook(hook SourceErrorHook) func() {
+	c.errHooks.Lock()
+	defer c.errHooks.Unlock()
+
+	if c.errHooks.hooks == nil {
+		c.errHooks.hooks = make(map[int]SourceErrorHook)
+	}
+
+	id := c.errHooks.next
+	c.errHooks.next++
+	c.errHooks.hooks[id] = hook
+
+	return func() {
+		c.errHooks.Lock()
+		defer c.errHooks.Unlock()
+
+		delete(c.errHooks.hooks, id)
+	}
+}
+
+// SourceError writes the error reported by the data source to the log and passes it to the
+// registered hooks, with the domain of the request served using the context.
+func (c *Config) SourceError(ctx context.Context, source string, err error) {
+	if err == nil {
+		return
+	}
+	if c.Log != nil {
+		c.Log.Printf("%s: %v", source, err)
+	}
+
+	c.errHooks.Lock()
+	hooks := make([]SourceErrorHook, 0, len(c.errHooks.hooks))
+	for _, hook := range c.errHooks.hooks {
+		hooks = append(hooks, hook)
+	}
+	c.errHooks.Unlock()
+
+	domain := SourceDomain(ctx)
+	for _, hook := range hooks {
+		hook(source, domain, err)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"testing"
+)
+
+func TestSourceErrorHooks(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConfig()
+	c.Log = log.New(&buf, "", 0)
+
+	var first, second int
+	remove := c.AddSourceErrorHook(func(source, domain string, err error) {
+		if source == "AlienVault" && domain == "owasp.org" && err.Error() == "403 Forbidden" {
+			first++
+		}
+	})
+	defer c.AddSourceErrorHook(func(source, domain string, err error) {
+		if domain == "" {
+			second++
+		}
+	})()
+
+	c.SourceError(WithSourceDomain(context.Background(), "owasp.org"), "AlienVault", errors.New("403 Forbidden"))
+	remove()
+	c.SourceError(context.Background(), "AlienVault", errors.New("403 Forbidden"))
+	c.SourceError(context.Background(), "AlienVault", nil)
+
+	if first != 1 || second != 1 {
+		t.Errorf("The hooks received the wrong number of errors: %d and %d", first, second)
+	}
+	if buf.String() != "AlienVault: 403 Forbidden\nAlienVault: 403 Forbidden\n" {
+		t.Errorf("The errors were not written to the log: %q", buf.String())
+	}
+}
@@ -15,7 +15,6 @@ import (
 	"strings"
 
 	"github.com/OWASP/Amass/v3/config"
-	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/service"
@@ -28,7 +27,6 @@ type AlienVault struct {
 
 	SourceType string
 	sys        systems.System
-	creds      *config.Credentials
 }
 
 // NewAlienVault returns he object initialized, but not yet started.
@@ -50,9 +48,7 @@ func (a *AlienVault) Description() string {
 
 // OnStart implements the Service interface.
 func (a *AlienVault) OnStart() error {
-	a.creds = a.sys.Config().GetDataSourceConfig(a.String()).GetCredentials()
-
-	if a.creds == nil {
+	if !hasAPICredentials(a.sys, a) {
 		a.sys.Config().Log.Printf("%s: API key data was not provided", a.String())
 	}
 
@@ -66,13 +62,15 @@ func (a *AlienVault) requests() {
 		case <-a.Done():
 			return
 		case in := <-a.Input():
+			// The errors are reported with the domain of the request
+			ctx := config.WithSourceDomain(context.TODO(), requests.RequestDomain(in))
 			switch req := in.(type) {
 			case *requests.DNSRequest:
 				a.CheckRateLimit()
-				a.dnsRequest(context.TODO(), req)
+				a.dnsRequest(ctx, req)
 			case *requests.WhoisRequest:
 				a.CheckRateLimit()
-				a.whoisRequest(context.TODO(), req)
+				a.whoisRequest(ctx, req)
 			}
 		}
 	}
@@ -104,9 +102,9 @@ func (a *AlienVault) executeDNSQuery(ctx context.Context, req *requests.DNSReque
 	}
 
 	u := a.getURL(req.Domain) + "passive_dns"
-	page, err := http.RequestWebPage(ctx, u, nil, a.getHeaders(), nil)
+	page, err := a.requestPage(ctx, req.Domain, u)
 	if err != nil {
-		a.sys.Config().Log.Printf("%s: %s: %v", a.String(), u, err)
+		a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	}
 	// Extract the subdomain names and IP addresses from the passive DNS information
@@ -117,7 +115,7 @@ func (a *AlienVault) executeDNSQuery(ctx context.Context, req *requests.DNSReque
 		} `json:"passive_dns"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		a.sys.Config().Log.Printf("%s: %s: %v", a.String(), u, err)
+		a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	} else if len(m.Subdomains) == 0 {
 		a.sys.Config().Log.Printf("%s: %s: The query returned zero results", a.String(), u)
@@ -171,11 +169,10 @@ func (a *AlienVault) executeURLQuery(ctx context.Context, req *requests.DNSReque
 		return
 	}
 
-	headers := a.getHeaders()
 	u := a.getURL(req.Domain) + "url_list"
-	page, err := http.RequestWebPage(ctx, u, nil, headers, nil)
+	page, err := a.requestPage(ctx, req.Domain, u)
 	if err != nil {
-		a.sys.Config().Log.Printf("%s: %s: %v", a.String(), u, err)
+		a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	}
 	// Extract the subdomain names and IP addresses from the URL information
@@ -187,7 +184,7 @@ func (a *AlienVault) executeURLQuery(ctx context.Context, req *requests.DNSReque
 		URLs     []avURL `json:"url_list"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		a.sys.Config().Log.Printf("%s: %s: %v", a.String(), u, err)
+		a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	} else if len(m.URLs) == 0 {
 		a.sys.Config().Log.Printf("%s: %s: The query returned zero results", a.String(), u)
@@ -208,14 +205,14 @@ func (a *AlienVault) executeURLQuery(ctx context.Context, req *requests.DNSReque
 		for cur := m.PageNum + 1; cur <= pages; cur++ {
 			a.CheckRateLimit()
 			pageURL := u + "?page=" + strconv.Itoa(cur)
-			page, err = http.RequestWebPage(ctx, pageURL, nil, headers, nil)
+			page, err = a.requestPage(ctx, req.Domain, pageURL)
 			if err != nil {
-				a.sys.Config().Log.Printf("%s: %s: %v", a.String(), pageURL, err)
+				a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", pageURL, err))
 				break
 			}
 
 			if err := json.Unmarshal([]byte(page), &m); err != nil {
-				a.sys.Config().Log.Printf("%s: %s: %v", a.String(), pageURL, err)
+				a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", pageURL, err))
 				break
 			} else if len(m.URLs) == 0 {
 				a.sys.Config().Log.Printf("%s: %s: The query returned zero results", a.String(), pageURL)
@@ -260,12 +257,11 @@ func (a *AlienVault) executeWhoisQuery(ctx context.Context, req *requests.WhoisR
 	newDomains := stringset.New()
 	defer newDomains.Close()
 
-	headers := a.getHeaders()
 	for _, email := range emails {
 		pageURL := a.getReverseWhoisURL(email)
-		page, err := http.RequestWebPage(ctx, pageURL, nil, headers, nil)
+		page, err := a.requestPage(ctx, req.Domain, pageURL)
 		if err != nil {
-			a.sys.Config().Log.Printf("%s: %s: %v", a.String(), pageURL, err)
+			a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", pageURL, err))
 			continue
 		}
 
@@ -274,7 +270,7 @@ func (a *AlienVault) executeWhoisQuery(ctx context.Context, req *requests.WhoisR
 		}
 		var domains []record
 		if err := json.Unmarshal([]byte(page), &domains); err != nil {
-			a.sys.Config().Log.Printf("%s: %s: %v", a.String(), pageURL, err)
+			a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", pageURL, err))
 			continue
 		}
 		for _, d := range domains {
@@ -303,9 +299,9 @@ func (a *AlienVault) queryWhoisForEmails(ctx context.Context, req *requests.Whoi
 	defer emails.Close()
 
 	u := a.getWhoisURL(req.Domain)
-	page, err := http.RequestWebPage(ctx, u, nil, a.getHeaders(), nil)
+	page, err := a.requestPage(ctx, req.Domain, u)
 	if err != nil {
-		a.sys.Config().Log.Printf("%s: %s: %v", a.String(), u, err)
+		a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", u, err))
 		return emails.Slice()
 	}
 
@@ -318,7 +314,7 @@ func (a *AlienVault) queryWhoisForEmails(ctx context.Context, req *requests.Whoi
 		} `json:"data"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		a.sys.Config().Log.Printf("%s: %s: %v", a.String(), u, err)
+		a.sys.Config().SourceError(ctx, a.String(), fmt.Errorf("%s: %v", u, err))
 		return emails.Slice()
 	} else if m.Count == 0 {
 		a.sys.Config().Log.Printf("%s: %s: The query returned zero results", a.String(), u)
@@ -344,13 +340,15 @@ func (a *AlienVault) queryWhoisForEmails(ctx context.Context, req *requests.Whoi
 	return emails.Slice()
 }
 
-func (a *AlienVault) getHeaders() map[string]string {
+// Sends the request using the next credentials of the data source, since the API key is optional.
+func (a *AlienVault) requestPage(ctx context.Context, domain, u string) (string, error) {
 	headers := map[string]string{"Content-Type": "application/json"}
 
-	if a.creds != nil && a.creds.Key != "" {
-		headers["X-OTX-API-KEY"] = a.creds.Key
+	cred := apiCredentials(a.sys, a)
+	if cred != nil {
+		headers["X-OTX-API-KEY"] = cred.Key
 	}
-	return headers
+	return requestWebPage(ctx, a.sys, a, domain, u, nil, headers, cred)
 }
 
 func (a *AlienVault) getWhoisURL(domain string) string {
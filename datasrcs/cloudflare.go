@@ -6,8 +6,10 @@ package datasrcs
 
 import (
 	"context"
+	"errors"
 
 	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/service"
@@ -20,7 +22,6 @@ type Cloudflare struct {
 
 	SourceType string
 	sys        systems.System
-	creds      *config.Credentials
 }
 
 // NewCloudflare returns he object initialized, but not yet started.
@@ -42,9 +43,7 @@ func (c *Cloudflare) Description() string {
 
 // OnStart implements the Service interface.
 func (c *Cloudflare) OnStart() error {
-	c.creds = c.sys.Config().GetDataSourceConfig(c.String()).GetCredentials()
-
-	if c.creds == nil || c.creds.Key == "" {
+	if !hasAPICredentials(c.sys, c) {
 		c.sys.Config().Log.Printf("%s: API key data was not provided", c.String())
 	}
 
@@ -58,40 +57,49 @@ func (c *Cloudflare) requests() {
 		case <-c.Done():
 			return
 		case in := <-c.Input():
+			// The errors are reported with the domain of the request
+			ctx := config.WithSourceDomain(context.TODO(), requests.RequestDomain(in))
 			switch req := in.(type) {
 			case *requests.DNSRequest:
 				c.CheckRateLimit()
-				c.dnsRequest(context.TODO(), req)
+				c.dnsRequest(ctx, req)
 			}
 		}
 	}
 }
 
 func (c *Cloudflare) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
-	if c.creds == nil || c.creds.Key == "" {
+	if !c.sys.Config().IsDomainInScope(req.Domain) {
+		return
+	}
+	if c.sys.Config().SpendSourceBudget(c.String(), req.Domain) != nil {
 		return
 	}
 
-	if !c.sys.Config().IsDomainInScope(req.Domain) {
+	cred := apiCredentials(c.sys, c)
+	if cred == nil {
 		return
 	}
 
 	c.sys.Config().Log.Printf("Querying %s for %s subdomains", c.String(), req.Domain)
 
-	api, err := cloudflare.NewWithAPIToken(c.creds.Key)
+	api, err := cloudflare.NewWithAPIToken(cred.Key)
 	if err != nil {
-		c.sys.Config().Log.Printf("%s: %v", c.String(), err)
+		c.sys.Config().SourceError(ctx, c.String(), err)
+		return
 	}
 
 	zones, err := api.ListZones(ctx, req.Domain)
+	c.reportCredentials(cred, err)
 	if err != nil {
-		c.sys.Config().Log.Printf("%s: %v", c.String(), err)
+		c.sys.Config().SourceError(ctx, c.String(), err)
 	}
 
 	for _, zone := range zones {
 		records, err := api.DNSRecords(ctx, zone.ID, cloudflare.DNSRecord{})
+		c.reportCredentials(cred, err)
 		if err != nil {
-			c.sys.Config().Log.Printf("%s: %v", c.String(), err)
+			c.sys.Config().SourceError(ctx, c.String(), err)
 		}
 
 		for _, record := range records {
@@ -116,3 +124,14 @@ func (c *Cloudflare) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 		}
 	}
 }
+
+// Reports the outcome of the API request sent using the credentials, so the credentials rejected by the API are rotated out.
+func (c *Cloudflare) reportCredentials(cred *config.Credentials, err error) {
+	resp := &http.Response{Status: 200}
+
+	var cerr *cloudflare.Error
+	if errors.As(err, &cerr) {
+		resp.Status = cerr.StatusCode
+	}
+	c.sys.Config().ReportCredentials(c.String(), cred, resp)
+}
@@ -13,7 +13,6 @@ import (
 	"strings"
 
 	"github.com/OWASP/Amass/v3/config"
-	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/service"
@@ -26,7 +25,6 @@ type DNSDB struct {
 
 	SourceType string
 	sys        systems.System
-	creds      *config.Credentials
 }
 
 // NewDNSDB returns he object initialized, but not yet started.
@@ -48,9 +46,7 @@ func (d *DNSDB) Description() string {
 
 // OnStart implements the Service interface.
 func (d *DNSDB) OnStart() error {
-	d.creds = d.sys.Config().GetDataSourceConfig(d.String()).GetCredentials()
-
-	if d.creds == nil || d.creds.Key == "" {
+	if !hasAPICredentials(d.sys, d) {
 		d.sys.Config().Log.Printf("%s: API key data was not provided", d.String())
 	}
 
@@ -59,9 +55,7 @@ func (d *DNSDB) OnStart() error {
 }
 
 func (d *DNSDB) checkConfig() error {
-	creds := d.sys.Config().GetDataSourceConfig(d.String()).GetCredentials()
-
-	if creds == nil || creds.Key == "" {
+	if !hasAPICredentials(d.sys, d) {
 		estr := fmt.Sprintf("%s: check callback failed for the configuration", d.String())
 		d.sys.Config().Log.Print(estr)
 		return errors.New(estr)
@@ -76,10 +70,12 @@ func (d *DNSDB) requests() {
 		case <-d.Done():
 			return
 		case in := <-d.Input():
+			// The errors are reported with the domain of the request
+			ctx := config.WithSourceDomain(context.TODO(), requests.RequestDomain(in))
 			switch req := in.(type) {
 			case *requests.DNSRequest:
 				d.CheckRateLimit()
-				d.dnsRequest(context.TODO(), req)
+				d.dnsRequest(ctx, req)
 			}
 		}
 	}
@@ -90,7 +86,8 @@ func (d *DNSDB) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 		return
 	}
 
-	if d.creds == nil || d.creds.Key == "" {
+	cred := apiCredentials(d.sys, d)
+	if cred == nil {
 		return
 	}
 
@@ -98,15 +95,15 @@ func (d *DNSDB) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 	d.sys.Config().Log.Printf("Querying %s for %s subdomains", d.String(), req.Domain)
 
 	headers := map[string]string{
-		"X-API-Key":    d.creds.Key,
+		"X-API-Key":    cred.Key,
 		"Accept":       "application/json",
 		"Content-Type": "application/json",
 	}
 
 	url := d.getURL(req.Domain)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := requestWebPage(ctx, d.sys, d, req.Domain, url, nil, headers, cred)
 	if err != nil {
-		d.sys.Config().Log.Printf("%s: %s: %v", d.String(), url, err)
+		d.sys.Config().SourceError(ctx, d.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	}
 
@@ -7,6 +7,9 @@ package datasrcs
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"regexp"
@@ -16,7 +19,6 @@ import (
 	"github.com/OWASP/Amass/v3/config"
 	amassnet "github.com/OWASP/Amass/v3/net"
 	"github.com/OWASP/Amass/v3/net/dns"
-	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/service"
@@ -46,7 +48,6 @@ type NetworksDB struct {
 
 	SourceType string
 	sys        systems.System
-	creds      *config.Credentials
 	hasAPIKey  bool
 }
 
@@ -70,9 +71,7 @@ func (n *NetworksDB) Description() string {
 
 // OnStart implements the Service interface.
 func (n *NetworksDB) OnStart() error {
-	n.creds = n.sys.Config().GetDataSourceConfig(n.String()).GetCredentials()
-
-	if n.creds == nil || n.creds.Key == "" {
+	if !hasAPICredentials(n.sys, n) {
 		n.sys.Config().Log.Printf("%s: API key data was not provided", n.String())
 		n.SourceType = requests.SCRAPE
 		n.hasAPIKey = false
@@ -88,13 +87,15 @@ func (n *NetworksDB) requests() {
 		case <-n.Done():
 			return
 		case in := <-n.Input():
+			// The errors are reported with the domain of the request
+			ctx := config.WithSourceDomain(context.TODO(), requests.RequestDomain(in))
 			switch req := in.(type) {
 			case *requests.ASNRequest:
 				n.CheckRateLimit()
-				n.asnRequest(context.TODO(), req)
+				n.asnRequest(ctx, req)
 			case *requests.WhoisRequest:
 				n.CheckRateLimit()
-				n.whoisRequest(context.TODO(), req)
+				n.whoisRequest(ctx, req)
 			}
 		}
 	}
@@ -127,23 +128,23 @@ func (n *NetworksDB) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 
 func (n *NetworksDB) executeASNAddrQuery(ctx context.Context, addr string) {
 	u := n.getIPURL(addr)
-	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	page, err := requestWebPage(ctx, n.sys, n, "", u, nil, nil, nil)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	}
 
 	matches := networksdbASNLinkRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
-		n.sys.Config().Log.Printf("%s: %s: Failed to extract the autonomous system href", n.String(), u)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to extract the autonomous system href", u))
 		return
 	}
 
 	numRateLimitChecks(n, 3)
 	u = networksdbBaseURL + matches[1]
-	page, err = http.RequestWebPage(ctx, u, nil, nil, nil)
+	page, err = requestWebPage(ctx, n.sys, n, "", u, nil, nil, nil)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	}
 
@@ -158,13 +159,13 @@ func (n *NetworksDB) executeASNAddrQuery(ctx context.Context, addr string) {
 
 	matches = networksdbASNRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
-		n.sys.Config().Log.Printf("%s: %s: The regular expression failed to extract the ASN", n.String(), u)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: The regular expression failed to extract the ASN", u))
 		return
 	}
 
 	asn, err := strconv.Atoi(strings.TrimSpace(matches[1]))
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: Failed to extract a valid ASN", n.String(), u)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to extract a valid ASN", u))
 		return
 	}
 
@@ -178,22 +179,22 @@ func (n *NetworksDB) getIPURL(addr string) string {
 func (n *NetworksDB) executeASNQuery(ctx context.Context, asn int, addr string, netblocks *stringset.Set) {
 	numRateLimitChecks(n, 3)
 	u := n.getASNURL(asn)
-	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	page, err := requestWebPage(ctx, n.sys, n, "", u, nil, nil, nil)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	}
 
 	matches := networksdbASNameRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
-		n.sys.Config().Log.Printf("%s: The regular expression failed to extract the AS name", n.String())
+		n.sys.Config().SourceError(ctx, n.String(), errors.New("the regular expression failed to extract the AS name"))
 		return
 	}
 	name := strings.TrimSpace(matches[1])
 
 	matches = networksdbCCRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
-		n.sys.Config().Log.Printf("%s: The regular expression failed to extract the country code", n.String())
+		n.sys.Config().SourceError(ctx, n.String(), errors.New("the regular expression failed to extract the country code"))
 		return
 	}
 	cc := strings.TrimSpace(matches[1])
@@ -238,14 +239,14 @@ func (n *NetworksDB) getASNURL(asn int) string {
 func (n *NetworksDB) executeAPIASNAddrQuery(ctx context.Context, addr string) {
 	_, id := n.apiIPQuery(ctx, addr)
 	if id == "" {
-		n.sys.Config().Log.Printf("%s: %s: Failed to obtain IP address information", n.String(), addr)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to obtain IP address information", addr))
 		return
 	}
 
 	numRateLimitChecks(n, 3)
 	asns := n.apiOrgInfoQuery(ctx, id)
 	if len(asns) == 0 {
-		n.sys.Config().Log.Printf("%s: %s: Failed to obtain ASNs associated with the organization", n.String(), id)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to obtain ASNs associated with the organization", id))
 		return
 	}
 
@@ -260,7 +261,7 @@ loop:
 		defer cidrs.Close()
 
 		if cidrs.Len() == 0 {
-			n.sys.Config().Log.Printf("%s: %d: Failed to obtain netblocks associated with the ASN", n.String(), a)
+			n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%d: Failed to obtain netblocks associated with the ASN", a))
 		}
 
 		for _, cidr := range cidrs.Slice() {
@@ -274,7 +275,7 @@ loop:
 	}
 
 	if asn == 0 {
-		n.sys.Config().Log.Printf("%s: %s: Failed to obtain the ASN associated with the IP address", n.String(), addr)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to obtain the ASN associated with the IP address", addr))
 		return
 	}
 	n.executeAPIASNQuery(ctx, asn, addr, cidrs)
@@ -292,7 +293,7 @@ func (n *NetworksDB) executeAPIASNQuery(ctx context.Context, asn int, addr strin
 
 		netblocks.Union(set)
 		if netblocks.Len() == 0 {
-			n.sys.Config().Log.Printf("%s: %d: Failed to obtain netblocks associated with the ASN", n.String(), asn)
+			n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%d: Failed to obtain netblocks associated with the ASN", asn))
 			return
 		}
 	}
@@ -314,7 +315,7 @@ func (n *NetworksDB) executeAPIASNQuery(ctx context.Context, asn int, addr strin
 	numRateLimitChecks(n, 3)
 	req := n.apiASNInfoQuery(ctx, asn)
 	if req == nil {
-		n.sys.Config().Log.Printf("%s: %d: Failed to obtain ASN information", n.String(), asn)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%d: Failed to obtain ASN information", asn))
 		return
 	}
 
@@ -331,9 +332,9 @@ func (n *NetworksDB) apiIPQuery(ctx context.Context, addr string) (string, strin
 	u := n.getAPIIPURL()
 	params := url.Values{"ip": {addr}}
 	body := strings.NewReader(params.Encode())
-	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
+	page, err := n.apiRequest(ctx, u, body)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return "", ""
 	}
 
@@ -350,10 +351,10 @@ func (n *NetworksDB) apiIPQuery(ctx context.Context, addr string) (string, strin
 		} `json:"results"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return "", ""
 	} else if m.Error != "" {
-		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %s", u, m.Error))
 		return "", ""
 	} else if m.Total == 0 || len(m.Results) == 0 {
 		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
@@ -372,9 +373,9 @@ func (n *NetworksDB) apiOrgInfoQuery(ctx context.Context, id string) []int {
 	u := n.getAPIOrgInfoURL()
 	params := url.Values{"id": {id}}
 	body := strings.NewReader(params.Encode())
-	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
+	page, err := n.apiRequest(ctx, u, body)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return []int{}
 	}
 
@@ -386,10 +387,10 @@ func (n *NetworksDB) apiOrgInfoQuery(ctx context.Context, id string) []int {
 		} `json:"results"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return []int{}
 	} else if m.Error != "" {
-		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %s", u, m.Error))
 		return []int{}
 	} else if m.Total == 0 || len(m.Results[0].ASNs) == 0 {
 		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
@@ -408,9 +409,9 @@ func (n *NetworksDB) apiASNInfoQuery(ctx context.Context, asn int) *requests.ASN
 	u := n.getAPIASNInfoURL()
 	params := url.Values{"asn": {strconv.Itoa(asn)}}
 	body := strings.NewReader(params.Encode())
-	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
+	page, err := n.apiRequest(ctx, u, body)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return nil
 	}
 
@@ -426,10 +427,10 @@ func (n *NetworksDB) apiASNInfoQuery(ctx context.Context, asn int) *requests.ASN
 		} `json:"results"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return nil
 	} else if m.Error != "" {
-		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %s", u, m.Error))
 		return nil
 	} else if m.Total == 0 || len(m.Results) == 0 {
 		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
@@ -456,9 +457,9 @@ func (n *NetworksDB) apiNetblocksQuery(ctx context.Context, asn int) *stringset.
 	u := n.getAPINetblocksURL()
 	params := url.Values{"asn": {strconv.Itoa(asn)}}
 	body := strings.NewReader(params.Encode())
-	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
+	page, err := n.apiRequest(ctx, u, body)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return netblocks
 	}
 
@@ -470,10 +471,10 @@ func (n *NetworksDB) apiNetblocksQuery(ctx context.Context, asn int) *stringset.
 		} `json:"results"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return netblocks
 	} else if m.Error != "" {
-		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %s", u, m.Error))
 		return netblocks
 	} else if m.Total == 0 || len(m.Results) == 0 {
 		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
@@ -490,15 +491,18 @@ func (n *NetworksDB) getAPINetblocksURL() string {
 	return networksdbBaseURL + networksdbAPIPATH + "/as/networks"
 }
 
-func (n *NetworksDB) getHeaders() map[string]string {
-	if !n.hasAPIKey {
-		return nil
+// Sends the API request authorized by the next credentials of the data source.
+func (n *NetworksDB) apiRequest(ctx context.Context, u string, body io.Reader) (string, error) {
+	cred := apiCredentials(n.sys, n)
+	if cred == nil {
+		return "", errors.New("the API key is not available")
 	}
 
-	return map[string]string{
-		"X-Api-Key":    n.creds.Key,
+	headers := map[string]string{
+		"X-Api-Key":    cred.Key,
 		"Content-Type": "application/x-www-form-urlencoded",
 	}
+	return requestWebPage(ctx, n.sys, n, "", u, body, headers, cred)
 }
 
 func (n *NetworksDB) whoisRequest(ctx context.Context, req *requests.WhoisRequest) {
@@ -508,15 +512,15 @@ func (n *NetworksDB) whoisRequest(ctx context.Context, req *requests.WhoisReques
 
 	numRateLimitChecks(n, 2)
 	u := n.getDomainToIPURL(req.Domain)
-	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	page, err := requestWebPage(ctx, n.sys, n, req.Domain, u, nil, nil, nil)
 	if err != nil {
-		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 		return
 	}
 
 	matches := networksdbIPLinkRE.FindAllStringSubmatch(page, -1)
 	if matches == nil {
-		n.sys.Config().Log.Printf("%s: %s: Failed to extract the IP page href", n.String(), u)
+		n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to extract the IP page href", u))
 		return
 	}
 
@@ -531,15 +535,15 @@ func (n *NetworksDB) whoisRequest(ctx context.Context, req *requests.WhoisReques
 
 		numRateLimitChecks(n, 3)
 		u = networksdbBaseURL + match[1]
-		page, err = http.RequestWebPage(ctx, u, nil, nil, nil)
+		page, err = requestWebPage(ctx, n.sys, n, req.Domain, u, nil, nil, nil)
 		if err != nil {
-			n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+			n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 			continue
 		}
 
 		cidrMatch := networksdbIPPageCIDRRE.FindStringSubmatch(page)
 		if cidrMatch == nil || len(cidrMatch) < 2 {
-			n.sys.Config().Log.Printf("%s: %s: Failed to extract the CIDR", n.String(), u)
+			n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to extract the CIDR", u))
 			continue
 		}
 
@@ -552,16 +556,16 @@ func (n *NetworksDB) whoisRequest(ctx context.Context, req *requests.WhoisReques
 		first, last := amassnet.FirstLast(cidr)
 		u := n.getDomainsInNetworkURL(first.String(), last.String())
 
-		page, err = http.RequestWebPage(ctx, u, nil, nil, nil)
+		page, err = requestWebPage(ctx, n.sys, n, req.Domain, u, nil, nil, nil)
 		if err != nil {
-			n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+			n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: %v", u, err))
 			continue
 		}
 
 		domainsPos := networksdbDomainsRE.FindStringIndex(page)
 		tablePos := networksdbTableRE.FindStringIndex(page)
 		if domainsPos == nil || tablePos == nil || len(domainsPos) < 2 || len(tablePos) < 2 {
-			n.sys.Config().Log.Printf("%s: %s: Failed to extract the domain section of the page", n.String(), u)
+			n.sys.Config().SourceError(ctx, n.String(), fmt.Errorf("%s: Failed to extract the domain section of the page", u))
 			continue
 		}
 
@@ -13,8 +13,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OWASP/Amass/v3/config"
 	amassnet "github.com/OWASP/Amass/v3/net"
-	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/resolve"
@@ -93,10 +93,12 @@ func (r *RADb) requests() {
 		case <-r.Done():
 			return
 		case in := <-r.Input():
+			// The errors are reported with the domain of the request
+			ctx := config.WithSourceDomain(context.TODO(), requests.RequestDomain(in))
 			switch req := in.(type) {
 			case *requests.ASNRequest:
 				r.CheckRateLimit()
-				r.asnRequest(context.TODO(), req)
+				r.asnRequest(ctx, req)
 			}
 		}
 	}
@@ -118,9 +120,9 @@ func (r *RADb) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 func (r *RADb) executeASNAddrQuery(ctx context.Context, addr string) {
 	url := r.getIPURL("arin", addr)
 	headers := map[string]string{"Content-Type": "application/json"}
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := requestWebPage(ctx, r.sys, r, "", url, nil, headers, nil)
 	if err != nil {
-		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	}
 
@@ -134,7 +136,7 @@ func (r *RADb) executeASNAddrQuery(ctx context.Context, addr string) {
 		} `json:"cidr0_cidrs"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	} else if m.ClassName != "ip network" || len(m.CIDRs) == 0 {
 		r.sys.Config().Log.Printf("%s: %s: The request returned zero results", r.String(), url)
@@ -172,9 +174,9 @@ func (r *RADb) executeASNQuery(ctx context.Context, asn int, addr, prefix string
 	numRateLimitChecks(r, 2)
 	url := r.getASNURL("arin", strconv.Itoa(asn))
 	headers := map[string]string{"Content-Type": "application/json"}
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := requestWebPage(ctx, r.sys, r, "", url, nil, headers, nil)
 	if err != nil {
-		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	}
 
@@ -187,10 +189,10 @@ func (r *RADb) executeASNQuery(ctx context.Context, asn int, addr, prefix string
 		}
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	} else if m.ClassName != "autnum" {
-		r.sys.Config().Log.Printf("%s: %s: The query returned incorrect results", r.String(), url)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: The query returned incorrect results", url))
 		return
 	}
 
@@ -251,9 +253,9 @@ func (r *RADb) netblocks(ctx context.Context, asn int) *stringset.Set {
 	numRateLimitChecks(r, 2)
 	url := r.getNetblocksURL(strconv.Itoa(asn))
 	headers := map[string]string{"Content-Type": "application/json"}
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := requestWebPage(ctx, r.sys, r, "", url, nil, headers, nil)
 	if err != nil {
-		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: %v", url, err))
 		return netblocks
 	}
 
@@ -269,7 +271,7 @@ func (r *RADb) netblocks(ctx context.Context, asn int) *stringset.Set {
 		} `json:"arin_originas0_networkSearchResults"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
-		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: %v", url, err))
 		return netblocks
 	}
 
@@ -297,7 +299,7 @@ func (r *RADb) netblocks(ctx context.Context, asn int) *stringset.Set {
 	}
 
 	if netblocks.Len() == 0 {
-		r.sys.Config().Log.Printf("%s: Failed to acquire netblocks for ASN %d", r.String(), asn)
+		r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("failed to acquire netblocks for ASN %d", asn))
 	}
 	return netblocks
 }
@@ -314,7 +316,7 @@ func (r *RADb) ipToASN(ctx context.Context, cidr string) int {
 		msg := resolve.QueryMsg(radbWhoisURL, dns.TypeA)
 		resp, err := r.sys.TrustedResolvers().QueryBlocking(ctx, msg)
 		if err != nil {
-			r.sys.Config().Log.Printf("%s: %s: %v", r.String(), radbWhoisURL, err)
+			r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("%s: %v", radbWhoisURL, err))
 			return 0
 		}
 
@@ -325,7 +327,7 @@ func (r *RADb) ipToASN(ctx context.Context, cidr string) int {
 
 		ip := ans[0].Data
 		if ip == "" {
-			r.sys.Config().Log.Printf("%s: Failed to resolve %s", r.String(), radbWhoisURL)
+			r.sys.Config().SourceError(ctx, r.String(), fmt.Errorf("failed to resolve %s", radbWhoisURL))
 			return 0
 		}
 		r.addr = ip
@@ -336,7 +338,7 @@ func (r *RADb) ipToASN(ctx context.Context, cidr string) int {
 
 	conn, err := amassnet.DialContext(ctx, "tcp", r.addr+":43")
 	if err != nil {
-		r.sys.Config().Log.Printf("%s: %v", r.String(), err)
+		r.sys.Config().SourceError(ctx, r.String(), err)
 		return 0
 	}
 	defer conn.Close()
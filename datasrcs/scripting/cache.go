@@ -6,9 +6,11 @@ package scripting
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/OWASP/Amass/v3/net/http"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -36,6 +38,48 @@ func (s *Script) setCachedResponse(ctx context.Context, url, resp string) error
 	return nil
 }
 
+// cachedResponse is the HTTP response stored in the cache for a request sent by the script,
+// so the status and headers are available to the script along with the body.
+type cachedResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header"`
+	Body   string              `json:"body"`
+}
+
+// Returns the key of the cached response, which identifies the request by the method, URL and body.
+func requestCacheKey(req *http.Request) string {
+	return req.Method + " " + req.URL + " " + req.Body
+}
+
+func (s *Script) getCachedRequest(ctx context.Context, req *http.Request, ttl int) (*http.Response, error) {
+	data, err := s.getCachedResponse(ctx, requestCacheKey(req), ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal([]byte(data), &cached); err != nil {
+		return nil, fmt.Errorf("failed to decode the cached response for %s: %v", req.URL, err)
+	}
+	return &http.Response{
+		Status: cached.Status,
+		Header: cached.Header,
+		Body:   cached.Body,
+	}, nil
+}
+
+func (s *Script) setCachedRequest(ctx context.Context, req *http.Request, resp *http.Response) error {
+	data, err := json.Marshal(&cachedResponse{
+		Status: resp.Status,
+		Header: resp.Header,
+		Body:   resp.Body,
+	})
+	if err != nil {
+		return err
+	}
+	return s.setCachedResponse(ctx, requestCacheKey(req), string(data))
+}
+
 // Wrapper so that scripts can obtain cached data source responses.
 func (s *Script) obtainResponse(L *lua.LState) int {
 	ctx, err := extractContext(L.CheckUserData(1))
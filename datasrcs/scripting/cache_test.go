@@ -5,9 +5,13 @@
 package scripting
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/OWASP/Amass/v3/config"
 	"github.com/OWASP/Amass/v3/requests"
 )
 
@@ -19,10 +23,10 @@ func TestCachedResponse(t *testing.T) {
 		function vertical(ctx, domain)
 			cache_response(ctx, "https://www.owasp.org", "success.owasp.org")
 
-			local resp, err = request(ctx, {['url']="https://www.owasp.org"})
-			if (err == nil and resp ~= "") then
+			local resp = obtain_response(ctx, "https://www.owasp.org", 1440)
+			if (resp ~= nil and resp ~= "") then
 				new_name(ctx, resp)
-    		end
+			end
 		end
 	`)
 	if script == nil || sys == nil {
@@ -49,3 +53,55 @@ func TestCachedResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestCachedRequest(t *testing.T) {
+	var lock sync.Mutex
+	counts := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		counts[r.Method]++
+		lock.Unlock()
+
+		w.Header().Set("X-Method", r.Method)
+		_, _ = w.Write([]byte(r.Method))
+	}))
+	defer ts.Close()
+
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	cfg.GetDataSourceConfig("cached").TTL = 1440
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s, err := newScript(`
+		name="cached"
+		type="testing"
+
+		function vertical(ctx, domain)
+			for _, method in pairs({"GET", "POST", "GET", "POST"}) do
+				local body, err, resp = request(ctx, {['url']="`+ts.URL+`", ['method']=method})
+				if (err ~= nil and err ~= "") then return end
+				if (resp.headers["x-method"] ~= body) then return end
+			end
+			new_name(ctx, "www." .. domain)
+		end
+	`, "cached.ads", sys)
+	if err != nil {
+		t.Fatalf("Failed to load the script: %v", err)
+	}
+
+	if err := s.dispatch(s.main, &requests.DNSRequest{Domain: "owasp.org"}); err != nil {
+		t.Fatalf("The callback failed: %v", err)
+	}
+	select {
+	case <-s.Output():
+	case <-time.After(5 * time.Second):
+		t.Fatal("The cached responses did not match the requests")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if counts["GET"] != 1 || counts["POST"] != 1 {
+		t.Errorf("The responses were not cached for each method: %v", counts)
+	}
+}
@@ -116,6 +116,8 @@ func (s *Script) dataSourceConfig(L *lua.LState) int {
 	}
 
 	if creds := cfg.GetCredentials(); creds != nil {
+		selectCredentials(L, creds)
+
 		c := L.NewTable()
 
 		c.RawSetString("name", lua.LString(creds.Name))
@@ -189,7 +191,7 @@ func (s *Script) altWordlist(L *lua.LState) int {
 
 // Wrapper so scripts can set the data source rate limit.
 func (s *Script) setRateLimit(L *lua.LState) int {
-	s.seconds = L.CheckInt(1)
+	s.setRateLimitSeconds(L.CheckInt(1))
 	return 0
 }
 
@@ -201,7 +203,7 @@ func numRateLimitChecks(srv service.Service, num int) {
 
 // Wrapper so scripts can block until past the data source rate limit.
 func (s *Script) checkRateLimit(L *lua.LState) int {
-	numRateLimitChecks(s, s.seconds)
+	numRateLimitChecks(s, s.rateLimitSeconds())
 	return 0
 }
 
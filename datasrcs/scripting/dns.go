@@ -7,14 +7,23 @@ package scripting
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/OWASP/Amass/v3/requests"
 	"github.com/caffix/resolve"
 	"github.com/miekg/dns"
 	lua "github.com/yuin/gopher-lua"
 	"golang.org/x/net/publicsuffix"
 )
 
+// The resolver pools that can be selected by scripts when making DNS queries.
+const (
+	resolversDefault   = ""
+	resolversTrusted   = "trusted"
+	resolversUntrusted = "untrusted"
+)
+
 // Wrapper so that scripts can make DNS queries.
 func (s *Script) resolve(L *lua.LState) int {
 	ctx, err := extractContext(L.CheckUserData(1))
@@ -26,18 +35,43 @@ func (s *Script) resolve(L *lua.LState) int {
 		return 2
 	}
 
-	resp, err := s.fwdQuery(ctx, name, qtype)
+	detection := true
+	pool := resolversDefault
+	if L.GetTop() == 4 {
+		switch opt := L.Get(4).(type) {
+		case lua.LBool:
+			detection = bool(opt)
+		case *lua.LTable:
+			if lv := opt.RawGetString("detection"); lv.Type() == lua.LTBool {
+				detection = lua.LVAsBool(lv)
+			}
+			if lv, ok := opt.RawGetString("resolvers").(lua.LString); ok {
+				pool = strings.ToLower(string(lv))
+			}
+		default:
+			L.ArgError(4, "boolean or table expected")
+		}
+	}
+
+	var resp *dns.Msg
+	switch pool {
+	case resolversDefault:
+		resp, err = s.fwdQuery(ctx, name, qtype)
+	case resolversTrusted:
+		resp, err = s.poolQuery(ctx, name, qtype, s.sys.TrustedResolvers())
+	case resolversUntrusted:
+		resp, err = s.poolQuery(ctx, name, qtype, s.sys.Resolvers())
+	default:
+		L.Push(lua.LNil)
+		L.Push(lua.LString("The resolvers option must be trusted or untrusted"))
+		return 2
+	}
 	if err != nil || resp.Rcode != dns.RcodeSuccess || len(resp.Answer) == 0 {
 		L.Push(lua.LNil)
 		L.Push(lua.LString("The query was unsuccessful for " + name))
 		return 2
 	}
 
-	detection := true
-	if L.GetTop() == 4 {
-		detection = L.CheckBool(4)
-	}
-
 	if detection {
 		domain, err := publicsuffix.EffectiveTLDPlusOne(name)
 
@@ -49,15 +83,9 @@ func (s *Script) resolve(L *lua.LState) int {
 	}
 
 	tb := L.NewTable()
-	if ans := resolve.ExtractAnswers(resp); len(ans) > 0 {
-		if records := resolve.AnswersByType(ans, qtype); len(records) > 0 {
-			for _, rr := range records {
-				entry := L.NewTable()
-				entry.RawSetString("rrname", lua.LString(rr.Name))
-				entry.RawSetString("rrtype", lua.LNumber(rr.Type))
-				entry.RawSetString("rrdata", lua.LString(rr.Data))
-				tb.Append(entry)
-			}
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype == qtype {
+			tb.Append(recordToTable(L, rr))
 		}
 	}
 	L.Push(tb)
@@ -75,7 +103,11 @@ func (s *Script) fwdQuery(ctx context.Context, name string, qtype uint16) (*dns.
 		return nil, errors.New("query failed")
 	}
 
-	resp, err = s.dnsQuery(ctx, msg, s.sys.TrustedResolvers(), 50)
+	return s.poolQuery(ctx, name, qtype, s.sys.TrustedResolvers())
+}
+
+func (s *Script) poolQuery(ctx context.Context, name string, qtype uint16, r *resolve.Resolvers) (*dns.Msg, error) {
+	resp, err := s.dnsQuery(ctx, resolve.QueryMsg(name, qtype), r, 50)
 	if resp == nil && err == nil {
 		err = errors.New("query failed")
 	}
@@ -129,6 +161,136 @@ func convertType(qtype string) uint16 {
 		t = dns.TypeSOA
 	case "srv":
 		t = dns.TypeSRV
+	case "caa":
+		t = dns.TypeCAA
+	case "ds":
+		t = dns.TypeDS
+	case "dnskey":
+		t = dns.TypeDNSKEY
+	case "https":
+		t = dns.TypeHTTPS
+	case "svcb":
+		t = dns.TypeSVCB
+	case "naptr":
+		t = dns.TypeNAPTR
+	case "tlsa":
+		t = dns.TypeTLSA
+	case "uri":
+		t = dns.TypeURI
+	default:
+		// Accept any other resource record type known to the DNS library
+		t = dns.StringToType[strings.ToUpper(qtype)]
 	}
 	return t
 }
+
+// Converts the resource record to a Lua table. The rrname, rrtype and rrdata fields are
+// always provided, and the remaining fields depend on the type of the resource record.
+func recordToTable(L *lua.LState, rr dns.RR) *lua.LTable {
+	hdr := rr.Header()
+	tb := L.NewTable()
+
+	data := strings.TrimSpace(strings.TrimPrefix(rr.String(), hdr.String()))
+	// Keep the data format used by the enumeration for the common types
+	if ans := resolve.ExtractAnswers(&dns.Msg{Answer: []dns.RR{rr}}); len(ans) > 0 {
+		data = ans[0].Data
+	}
+
+	tb.RawSetString("rrname", lua.LString(strings.ToLower(resolve.RemoveLastDot(hdr.Name))))
+	tb.RawSetString("rrtype", lua.LNumber(hdr.Rrtype))
+	tb.RawSetString("rrdata", lua.LString(data))
+	tb.RawSetString("ttl", lua.LNumber(hdr.Ttl))
+
+	switch v := rr.(type) {
+	case *dns.MX:
+		tb.RawSetString("preference", lua.LNumber(v.Preference))
+		tb.RawSetString("mx", lua.LString(resolve.RemoveLastDot(v.Mx)))
+	case *dns.SRV:
+		tb.RawSetString("priority", lua.LNumber(v.Priority))
+		tb.RawSetString("weight", lua.LNumber(v.Weight))
+		tb.RawSetString("port", lua.LNumber(v.Port))
+		tb.RawSetString("target", lua.LString(resolve.RemoveLastDot(v.Target)))
+	case *dns.SOA:
+		tb.RawSetString("ns", lua.LString(resolve.RemoveLastDot(v.Ns)))
+		tb.RawSetString("mbox", lua.LString(resolve.RemoveLastDot(v.Mbox)))
+		tb.RawSetString("serial", lua.LNumber(v.Serial))
+		tb.RawSetString("refresh", lua.LNumber(v.Refresh))
+		tb.RawSetString("retry", lua.LNumber(v.Retry))
+		tb.RawSetString("expire", lua.LNumber(v.Expire))
+		tb.RawSetString("minttl", lua.LNumber(v.Minttl))
+	case *dns.TXT:
+		txt := L.NewTable()
+		for _, str := range v.Txt {
+			txt.Append(lua.LString(str))
+		}
+		tb.RawSetString("txt", txt)
+	case *dns.CAA:
+		tb.RawSetString("flag", lua.LNumber(v.Flag))
+		tb.RawSetString("tag", lua.LString(v.Tag))
+		tb.RawSetString("value", lua.LString(v.Value))
+	case *dns.DS:
+		tb.RawSetString("key_tag", lua.LNumber(v.KeyTag))
+		tb.RawSetString("algorithm", lua.LNumber(v.Algorithm))
+		tb.RawSetString("digest_type", lua.LNumber(v.DigestType))
+		tb.RawSetString("digest", lua.LString(v.Digest))
+	case *dns.DNSKEY:
+		tb.RawSetString("flags", lua.LNumber(v.Flags))
+		tb.RawSetString("protocol", lua.LNumber(v.Protocol))
+		tb.RawSetString("algorithm", lua.LNumber(v.Algorithm))
+		tb.RawSetString("public_key", lua.LString(v.PublicKey))
+		tb.RawSetString("key_tag", lua.LNumber(v.KeyTag()))
+	case *dns.HTTPS:
+		svcbFields(L, tb, &v.SVCB)
+	case *dns.SVCB:
+		svcbFields(L, tb, v)
+	case *dns.NAPTR:
+		tb.RawSetString("order", lua.LNumber(v.Order))
+		tb.RawSetString("preference", lua.LNumber(v.Preference))
+		tb.RawSetString("flags", lua.LString(v.Flags))
+		tb.RawSetString("service", lua.LString(v.Service))
+		tb.RawSetString("regexp", lua.LString(v.Regexp))
+		tb.RawSetString("replacement", lua.LString(resolve.RemoveLastDot(v.Replacement)))
+	case *dns.TLSA:
+		tb.RawSetString("usage", lua.LNumber(v.Usage))
+		tb.RawSetString("selector", lua.LNumber(v.Selector))
+		tb.RawSetString("matching_type", lua.LNumber(v.MatchingType))
+		tb.RawSetString("certificate", lua.LString(v.Certificate))
+	case *dns.URI:
+		tb.RawSetString("priority", lua.LNumber(v.Priority))
+		tb.RawSetString("weight", lua.LNumber(v.Weight))
+		tb.RawSetString("target", lua.LString(v.Target))
+	}
+	return tb
+}
+
+func svcbFields(L *lua.LState, tb *lua.LTable, rr *dns.SVCB) {
+	tb.RawSetString("priority", lua.LNumber(rr.Priority))
+	tb.RawSetString("target", lua.LString(resolve.RemoveLastDot(rr.Target)))
+
+	params := L.NewTable()
+	for _, kv := range rr.Value {
+		params.RawSetString(kv.Key().String(), lua.LString(kv.String()))
+	}
+	tb.RawSetString("params", params)
+}
+
+// Builds a Lua table for a DNS answer provided by the enumeration. The answer is
+// parsed, so the table also includes the fields specific to the record type.
+func answerToTable(L *lua.LState, ans requests.DNSAnswer) *lua.LTable {
+	if t, found := dns.TypeToString[uint16(ans.Type)]; found {
+		if rr, err := dns.NewRR(fmt.Sprintf("%s 0 IN %s %s", dns.Fqdn(ans.Name), t, ans.Data)); err == nil && rr != nil {
+			tb := recordToTable(L, rr)
+			// The answer data is provided to the script unaltered
+			tb.RawSetString("rrname", lua.LString(ans.Name))
+			tb.RawSetString("rrdata", lua.LString(ans.Data))
+			tb.RawSetString("ttl", lua.LNil)
+			return tb
+		}
+	}
+
+	tb := L.NewTable()
+	tb.RawSetString("rrname", lua.LString(ans.Name))
+	tb.RawSetString("rrtype", lua.LNumber(ans.Type))
+	tb.RawSetString("rrdata", lua.LString(ans.Data))
+	return tb
+}
@@ -5,6 +5,8 @@
 package scripting
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -62,3 +64,91 @@ loop:
 		}
 	}
 }
+
+const recordTypesScript = `
+name="RecordTypes"
+type="dns"
+
+function vertical(ctx, domain)
+	local opts = {detection=false, resolvers="trusted"}
+
+	local records, err = resolve(ctx, domain, "CAA", opts)
+	if (err == nil and #records > 0) then
+		new_name(ctx, records[1].tag .. "." .. records[1].value)
+	end
+
+	records, err = resolve(ctx, domain, "HTTPS", opts)
+	if (err == nil and #records > 0 and records[1].params.alpn == "h2") then
+		new_name(ctx, records[1].target)
+	end
+
+	records, err = resolve(ctx, "_443._tcp.www." .. domain, "TLSA", opts)
+	if (err == nil and #records > 0 and records[1].usage == 3) then
+		new_name(ctx, "tlsa" .. records[1].selector .. "." .. domain)
+	end
+
+	records, err = resolve(ctx, "_sip._udp." .. domain, "URI", {detection=false, resolvers="untrusted"})
+	if (err == nil and #records > 0) then
+		new_name(ctx, records[1].target)
+	end
+
+	records, err = resolve(ctx, domain, "DNSKEY", false)
+	if (err == nil and #records > 0 and records[1].key_tag > 0) then
+		new_name(ctx, "dnskey." .. domain)
+	end
+
+	records, err = resolve(ctx, domain, "NAPTR", opts)
+	if (err == nil and #records > 0) then
+		new_name(ctx, records[1].replacement)
+	end
+end
+
+function resolved(ctx, name, domain, records)
+	for _, rec in ipairs(records) do
+		if (rec.rrtype == 33) then
+			new_name(ctx, rec.target)
+		end
+	end
+end
+`
+
+func TestResolveRecordTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "types.ads")
+	if err := ioutil.WriteFile(path, []byte(recordTypesScript), 0600); err != nil {
+		t.Fatalf("Failed to write the script: %v", err)
+	}
+
+	f, err := RunFixture(path, &Fixture{
+		Domains: []string{"owasp.org"},
+		DNS: []*FixtureRecord{
+			{Name: "owasp.org", Type: "CAA", Data: `0 issue "ca.owasp.org"`},
+			{Name: "owasp.org", Type: "HTTPS", Data: `1 svc.owasp.org. alpn="h2"`},
+			{Name: "owasp.org", Type: "DNSKEY", Data: "257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ=="},
+			{Name: "owasp.org", Type: "NAPTR", Data: `100 10 "S" "SIP+D2U" "" _sip._udp.owasp.org.`},
+			{Name: "_443._tcp.www.owasp.org", Type: "TLSA", Data: "3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6"},
+			{Name: "_sip._udp.owasp.org", Type: "URI", Data: `10 1 "sip.owasp.org"`},
+		},
+		Calls: []*FixtureCall{
+			{Callback: "vertical"},
+			{Callback: "resolved", Name: "owasp.org", Records: []*FixtureRecord{
+				{Name: "_sip._tcp.owasp.org", Type: "SRV", Data: "10 5 5060 sipserver.owasp.org."},
+			}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to run the fixture: %v", err)
+	}
+
+	err = f.Verify(&Findings{Names: []string{
+		"_sip._udp.owasp.org",
+		"dnskey.owasp.org",
+		"issue.ca.owasp.org",
+		"sip.owasp.org",
+		"sipserver.owasp.org",
+		"svc.owasp.org",
+		"tlsa1.owasp.org",
+	}})
+	if err != nil {
+		t.Errorf("The record fields were not provided to the script:\n%v", err)
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/netmap"
+	"github.com/caffix/resolve"
+	"github.com/caffix/stringset"
+	"github.com/miekg/dns"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	fixtureResolverQPS = 100
+	fixtureOutputWait  = 100 * time.Millisecond
+)
+
+// Fixture provides the canned data and the callback calls used to test a single ADS script.
+type Fixture struct {
+	Domains     []string             `json:"domains"`
+	Credentials *config.Credentials  `json:"credentials,omitempty"`
+	HTTP        []*http.ArchiveEntry `json:"http,omitempty"`
+	Archive     string               `json:"archive,omitempty"`
+	DNS         []*FixtureRecord     `json:"dns,omitempty"`
+	Calls       []*FixtureCall       `json:"calls"`
+	Expected    *Findings            `json:"expected,omitempty"`
+}
+
+// FixtureRecord is a DNS resource record provided to the script.
+type FixtureRecord struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// FixtureCall describes a callback executed by the test and the parameters passed to the script.
+type FixtureCall struct {
+	Callback string           `json:"callback"`
+	Domain   string           `json:"domain,omitempty"`
+	Name     string           `json:"name,omitempty"`
+	Address  string           `json:"addr,omitempty"`
+	ASN      int              `json:"asn,omitempty"`
+	Records  []*FixtureRecord `json:"records,omitempty"`
+}
+
+// Findings contains the names, addresses, ASNs and associated domains emitted by a script,
+// along with the Lua errors raised by the callbacks.
+type Findings struct {
+	Names      []string `json:"names,omitempty"`
+	Addresses  []string `json:"addresses,omitempty"`
+	ASNs       []int    `json:"asns,omitempty"`
+	Associated []string `json:"associated,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// LoadFixture reads the JSON fixture file at the provided path. The archive
+// file path is relative to the directory containing the fixture file.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fix Fixture
+	if err := json.Unmarshal(data, &fix); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if fix.Archive != "" && !filepath.IsAbs(fix.Archive) {
+		fix.Archive = filepath.Join(filepath.Dir(path), fix.Archive)
+	}
+	return &fix, nil
+}
+
+// RunFixture loads the script at the provided path and executes the fixture calls against it.
+// The HTTP requests are served from the fixture and the DNS queries are answered using the
+// fixture records, so the network is never used. The cfg parameter can be nil.
+func RunFixture(path string, fix *Fixture, cfg *config.Config) (*Findings, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = config.NewConfig()
+	}
+	cfg.AddDomains(fix.Domains...)
+
+	entries := fix.HTTP
+	if fix.Archive != "" {
+		recorded, err := http.ReadArchive(fix.Archive)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, recorded...)
+	}
+	fr, err := newFixtureResolver(fix.DNS)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = fr.server.Shutdown() }()
+
+	sys := &systems.SimpleSystem{
+		Cfg:      cfg,
+		Pool:     resolve.NewResolvers(),
+		Trusted:  resolve.NewResolvers(),
+		Graph:    netmap.NewGraph(netmap.NewCayleyGraphMemory()),
+		ASNCache: requests.NewASNCache(),
+	}
+	defer sys.Trusted.Stop()
+	for _, r := range []*resolve.Resolvers{sys.Pool, sys.Trusted} {
+		r.SetLogger(cfg.Log)
+		if err := r.AddResolvers(fixtureResolverQPS, fr.addr); err != nil {
+			_ = sys.Shutdown()
+			return nil, err
+		}
+	}
+
+	s, err := newScript(string(data), path, sys)
+	if err != nil {
+		_ = sys.Shutdown()
+		return nil, err
+	}
+	defer func() { _ = sys.Shutdown() }()
+	// Only the requests of the script are served from the fixture, without changing the package client
+	s.transport = http.NewReplayTransport(entries)
+
+	if creds := fix.Credentials; creds != nil {
+		if creds.Name == "" {
+			creds.Name = "fixture"
+		}
+		if err := cfg.GetDataSourceConfig(s.String()).AddCredentials(creds); err != nil {
+			return nil, err
+		}
+	}
+
+	f := new(Findings)
+	if err := sys.AddAndStart(s); err != nil {
+		f.Errors = append(f.Errors, err.Error())
+		return f, nil
+	}
+	// The fixture data is served without delay
+	s.setRateLimitSeconds(0)
+	s.SetRateLimit(0)
+
+	names := stringset.New()
+	defer names.Close()
+	addrs := stringset.New()
+	defer addrs.Close()
+	assoc := stringset.New()
+	defer assoc.Close()
+
+	for i, call := range fix.Calls {
+		req, err := s.fixtureRequest(call)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %v", i+1, err)
+		}
+		st, err := s.acquireState()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.dispatch(st, req); err != nil {
+			f.Errors = append(f.Errors, err.Error())
+		}
+		s.releaseState(st)
+		s.collectFindings(names, addrs, assoc)
+	}
+
+	f.Names = names.Slice()
+	f.Addresses = addrs.Slice()
+	f.Associated = assoc.Slice()
+	for _, as := range sys.Cache().DescriptionSearch("") {
+		f.ASNs = append(f.ASNs, as.ASN)
+	}
+	sort.Strings(f.Names)
+	sort.Strings(f.Addresses)
+	sort.Strings(f.Associated)
+	sort.Ints(f.ASNs)
+	return f, nil
+}
+
+// Builds the request that causes the dispatcher to execute the callback of the fixture call.
+func (s *Script) fixtureRequest(call *FixtureCall) (interface{}, error) {
+	cfg := s.sys.Config()
+
+	domain := call.Domain
+	if domain == "" && call.Name != "" {
+		domain = cfg.WhichDomain(call.Name)
+	}
+	if domain == "" && len(cfg.Domains()) > 0 {
+		domain = cfg.Domains()[0]
+	}
+
+	var cb lua.LValue
+	var req interface{}
+	switch strings.ToLower(call.Callback) {
+	case "vertical":
+		cb = s.main.cbs.Vertical
+		req = &requests.DNSRequest{Domain: domain}
+	case "horizontal":
+		cb = s.main.cbs.Horizontal
+		req = &requests.WhoisRequest{Domain: domain}
+	case "subdomain":
+		cb = s.main.cbs.Subdomain
+		req = &requests.SubdomainRequest{Name: call.Name, Domain: domain, Times: 1}
+	case "resolved":
+		var records []requests.DNSAnswer
+		for _, rec := range call.Records {
+			records = append(records, requests.DNSAnswer{
+				Name: rec.Name,
+				Type: int(convertType(rec.Type)),
+				Data: rec.Data,
+			})
+		}
+
+		cb = s.main.cbs.Resolved
+		req = &requests.ResolvedRequest{Name: call.Name, Domain: domain, Records: records}
+	case "address":
+		cb = s.main.cbs.Address
+		req = &requests.AddrRequest{Address: call.Address, Domain: domain}
+	case "asn":
+		cb = s.main.cbs.Asn
+		req = &requests.ASNRequest{Address: call.Address, ASN: call.ASN}
+	default:
+		return nil, fmt.Errorf("the %q callback is not supported", call.Callback)
+	}
+
+	if cb.Type() == lua.LTNil {
+		return nil, fmt.Errorf("the script does not implement the %s callback", call.Callback)
+	}
+	return req, nil
+}
+
+// Reads the script output until the findings stop arriving.
+func (s *Script) collectFindings(names, addrs, assoc *stringset.Set) {
+	t := time.NewTimer(fixtureOutputWait)
+	defer t.Stop()
+
+	for {
+		select {
+		case out := <-s.Output():
+			switch v := out.(type) {
+			case *requests.DNSRequest:
+				names.Insert(v.Name)
+			case *requests.AddrRequest:
+				addrs.Insert(v.Address)
+			case *requests.WhoisRequest:
+				assoc.InsertMany(v.NewDomains...)
+			}
+		case <-t.C:
+			if s.queue.Len() == 0 {
+				return
+			}
+		}
+		t.Reset(fixtureOutputWait)
+	}
+}
+
+// Verify compares the findings with the expected findings. Only the fields set in
+// the expected findings are compared, and the Lua errors always cause a failure.
+func (f *Findings) Verify(expected *Findings) error {
+	var msgs []string
+
+	msgs = append(msgs, f.Errors...)
+	if expected != nil {
+		if expected.Names != nil {
+			msgs = append(msgs, compareFindings("name", f.Names, expected.Names)...)
+		}
+		if expected.Addresses != nil {
+			msgs = append(msgs, compareFindings("address", f.Addresses, expected.Addresses)...)
+		}
+		if expected.Associated != nil {
+			msgs = append(msgs, compareFindings("associated domain", f.Associated, expected.Associated)...)
+		}
+		if expected.ASNs != nil {
+			msgs = append(msgs, compareFindings("ASN", intStrings(f.ASNs), intStrings(expected.ASNs))...)
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	return nil
+}
+
+func compareFindings(kind string, found, expected []string) []string {
+	var msgs []string
+
+	got := stringset.New(found...)
+	defer got.Close()
+	want := stringset.New(expected...)
+	defer want.Close()
+
+	for _, v := range expected {
+		if !got.Has(v) {
+			msgs = append(msgs, fmt.Sprintf("the expected %s %s was not found", kind, v))
+		}
+	}
+	for _, v := range found {
+		if !want.Has(v) {
+			msgs = append(msgs, fmt.Sprintf("the %s %s was not expected", kind, v))
+		}
+	}
+	return msgs
+}
+
+func intStrings(nums []int) []string {
+	var strs []string
+
+	for _, n := range nums {
+		strs = append(strs, fmt.Sprint(n))
+	}
+	return strs
+}
+
+// fixtureResolver answers the DNS queries sent by the script using the fixture records.
+type fixtureResolver struct {
+	addr    string
+	server  *dns.Server
+	records map[string][]dns.RR
+}
+
+func newFixtureResolver(records []*FixtureRecord) (*fixtureResolver, error) {
+	fr := &fixtureResolver{records: make(map[string][]dns.RR)}
+
+	for _, rec := range records {
+		rr, err := dns.NewRR(fmt.Sprintf("%s 300 IN %s %s",
+			dns.Fqdn(rec.Name), strings.ToUpper(rec.Type), rec.Data))
+		if err != nil || rr == nil {
+			return nil, fmt.Errorf("the DNS record %s %s %s is invalid: %v", rec.Name, rec.Type, rec.Data, err)
+		}
+
+		name := strings.ToLower(rr.Header().Name)
+		fr.records[name] = append(fr.records[name], rr)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	started := make(chan struct{})
+	fr.addr = pc.LocalAddr().String()
+	fr.server = &dns.Server{
+		PacketConn:        pc,
+		Handler:           fr,
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go func() { _ = fr.server.ActivateAndServe() }()
+	<-started
+	return fr, nil
+}
+
+// ServeDNS implements the dns.Handler interface.
+func (fr *fixtureResolver) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+
+	if len(req.Question) > 0 {
+		q := req.Question[0]
+
+		rrs, found := fr.records[strings.ToLower(q.Name)]
+		if !found {
+			m.Rcode = dns.RcodeNameError
+		}
+		for _, rr := range rrs {
+			if t := rr.Header().Rrtype; t == q.Qtype || t == dns.TypeCNAME {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+	}
+	_ = w.WriteMsg(m)
+}
@@ -0,0 +1,115 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/net/http"
+)
+
+const harnessScript = `
+name="Harness"
+type="api"
+
+function check()
+	local c
+	local cfg = datasrc_config()
+	if cfg ~= nil then
+		c = cfg.credentials
+	end
+	return (c ~= nil and c.key ~= nil and c.key ~= "")
+end
+
+function vertical(ctx, domain)
+	local resp, err = request(ctx, {url="https://api.example.com/" .. domain})
+	if (err ~= nil and err ~= "") then
+		return
+	end
+	send_names(ctx, resp)
+
+	local records, err = resolve(ctx, "mail." .. domain, "A", false)
+	if (err == nil and #records > 0) then
+		new_addr(ctx, records[1].rrdata, "mail." .. domain)
+	end
+end
+
+function asn(ctx, addr, asn)
+	new_asn(ctx, {
+		addr=addr,
+		asn=13335,
+		prefix="104.16.0.0/12",
+		desc="CLOUDFLARENET",
+	})
+end
+
+function horizontal(ctx, domain)
+	local missing = nil
+	missing.field = 1
+end
+`
+
+func TestRunFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harness.ads")
+	if err := ioutil.WriteFile(path, []byte(harnessScript), 0600); err != nil {
+		t.Fatalf("Failed to write the script: %v", err)
+	}
+
+	fix := &Fixture{
+		Domains: []string{"owasp.org"},
+		HTTP: []*http.ArchiveEntry{{
+			URL:  "https://api.example.com/owasp.org",
+			Data: `{"subdomains": ["www.owasp.org", "api.owasp.org"]}`,
+		}},
+		DNS: []*FixtureRecord{{Name: "mail.owasp.org", Type: "A", Data: "104.22.27.77"}},
+		Calls: []*FixtureCall{
+			{Callback: "vertical"},
+			{Callback: "asn", Address: "104.22.27.77"},
+		},
+		Expected: &Findings{
+			Names:     []string{"api.owasp.org", "www.owasp.org"},
+			Addresses: []string{"104.22.27.77"},
+			ASNs:      []int{13335},
+		},
+	}
+
+	if f, err := RunFixture(path, fix, nil); err != nil {
+		t.Fatalf("Failed to run the fixture: %v", err)
+	} else if len(f.Errors) == 0 || !strings.Contains(f.Errors[0], "check callback failed") {
+		t.Errorf("The check callback did not fail without credentials: %v", f.Errors)
+	}
+
+	fix.Credentials = &config.Credentials{Key: "secret"}
+	// The fixture responses are only served to the script, so the package client is left alone
+	transport := http.DefaultClient.Transport
+	f, err := RunFixture(path, fix, nil)
+	if err != nil {
+		t.Fatalf("Failed to run the fixture: %v", err)
+	}
+	if err := f.Verify(fix.Expected); err != nil {
+		t.Errorf("The findings did not match the fixture:\n%v", err)
+	}
+	if http.DefaultClient.Transport != transport || http.Archiving() {
+		t.Errorf("The fixture changed the transport of the package client")
+	}
+
+	fix.Calls = append(fix.Calls, &FixtureCall{Callback: "horizontal"})
+	f, err = RunFixture(path, fix, nil)
+	if err != nil {
+		t.Fatalf("Failed to run the fixture: %v", err)
+	}
+	if len(f.Errors) != 1 || !strings.Contains(f.Errors[0], path+":38:") {
+		t.Errorf("The Lua error did not provide the line number: %v", f.Errors)
+	}
+
+	fix.Calls = []*FixtureCall{{Callback: "address", Address: "104.22.27.77"}}
+	if _, err := RunFixture(path, fix, nil); err == nil {
+		t.Errorf("The fixture called a callback missing from the script")
+	}
+}
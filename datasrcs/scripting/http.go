@@ -6,13 +6,19 @@ package scripting
 
 import (
 	"context"
-	"io"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/OWASP/Amass/v3/config"
 	"github.com/OWASP/Amass/v3/net/http"
 	lua "github.com/yuin/gopher-lua"
 )
 
+// The largest number of times a request can be sent again by the retry policy.
+const maxRequestRetries = 10
+
 // Wrapper that allows scripts to make HTTP client requests.
 func (s *Script) request(L *lua.LState) int {
 	ctx, err := extractContext(L.CheckUserData(1))
@@ -29,42 +35,30 @@ func (s *Script) request(L *lua.LState) int {
 		return 2
 	}
 
-	var data string
-	if method, ok := getStringField(L, opt, "method"); ok && strings.ToLower(method) == "post" {
-		if d, ok := getStringField(L, opt, "data"); ok {
-			data = d
-		}
-	}
-
-	url, found := getStringField(L, opt, "url")
-	if !found {
+	req, retries, cred, err := s.requestOptions(L, opt)
+	if err != nil {
 		L.Push(lua.LNil)
-		L.Push(lua.LString("No URL found in the parameters"))
+		L.Push(lua.LString(err.Error()))
 		return 2
 	}
 
-	headers := make(map[string]string)
-	lv := L.GetField(opt, "headers")
-	if tbl, ok := lv.(*lua.LTable); ok {
-		tbl.ForEach(func(k, v lua.LValue) {
-			headers[k.String()] = v.String()
-		})
+	resp, err := s.req(ctx, req, retries, cred)
+	if resp != nil {
+		L.Push(lua.LString(resp.Body))
+	} else {
+		L.Push(lua.LString(""))
 	}
-
-	id, _ := getStringField(L, opt, "id")
-	pass, _ := getStringField(L, opt, "pass")
-	page, err := s.req(ctx, url, data, headers, &http.BasicAuth{
-		Username: id,
-		Password: pass,
-	})
-
-	L.Push(lua.LString(page))
 	if err != nil {
 		L.Push(lua.LString(err.Error()))
 	} else {
 		L.Push(lua.LNil)
 	}
-	return 2
+	if resp != nil {
+		L.Push(responseToTable(L, resp))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 3
 }
 
 // Wrapper so that scripts can scrape the contents of a GET request for subdomain names in scope.
@@ -81,69 +75,155 @@ func (s *Script) scrape(L *lua.LState) int {
 		return 1
 	}
 
-	var data string
-	if method, ok := getStringField(L, opt, "method"); ok && strings.ToLower(method) == "post" {
-		if d, ok := getStringField(L, opt, "data"); ok {
-			data = d
+	req, retries, cred, err := s.requestOptions(L, opt)
+	if err != nil {
+		s.sys.Config().Log.Print(s.String() + ": scrape: " + err.Error())
+		L.Push(lua.LFalse)
+		return 1
+	}
+
+	sucess := lua.LFalse
+	if resp, err := s.req(ctx, req, retries, cred); err == nil {
+		if num := s.internalSendNames(ctx, resp.Body); num > 0 {
+			sucess = lua.LTrue
 		}
+	} else {
+		s.sys.Config().Log.Print(s.String() + ": scrape: " + err.Error())
 	}
 
+	L.Push(sucess)
+	return 1
+}
+
+// Builds the HTTP client request from the options table provided by the script, and
+// returns the number of times the request can be sent again by the retry policy. The
+// credentials used by the request are also returned, which are those selected for the
+// OAuth2 client credentials, or else those obtained by the callback from datasrc_config.
+func (s *Script) requestOptions(L *lua.LState, opt *lua.LTable) (*http.Request, int, *config.Credentials, error) {
 	url, found := getStringField(L, opt, "url")
 	if !found {
-		L.Push(lua.LFalse)
-		return 1
+		return nil, 0, nil, errors.New("no URL found in the parameters")
+	}
+
+	req := &http.Request{
+		Method: "GET",
+		URL:    url,
+		Header: make(map[string]string),
+	}
+	if method, ok := getStringField(L, opt, "method"); ok && method != "" {
+		req.Method = strings.ToUpper(method)
+	}
+	if req.Method != "GET" && req.Method != "HEAD" {
+		if d, ok := getStringField(L, opt, "data"); ok {
+			req.Body = d
+		}
 	}
 
-	headers := make(map[string]string)
 	lv := L.GetField(opt, "headers")
 	if tbl, ok := lv.(*lua.LTable); ok {
 		tbl.ForEach(func(k, v lua.LValue) {
-			headers[k.String()] = v.String()
+			req.Header[k.String()] = v.String()
 		})
 	}
 
 	id, _ := getStringField(L, opt, "id")
 	pass, _ := getStringField(L, opt, "pass")
-
-	sucess := lua.LFalse
-	if resp, err := s.req(ctx, url, data, headers, &http.BasicAuth{
+	req.Auth = &http.BasicAuth{
 		Username: id,
 		Password: pass,
-	}); err == nil {
-		if num := s.internalSendNames(ctx, resp); num > 0 {
-			sucess = lua.LTrue
+	}
+
+	cred := selectedCredentials(L)
+	// Authorize the request using the OAuth2 bearer tokens obtained for the data source
+	if lv := L.GetField(opt, "oauth2"); lua.LVAsBool(lv) {
+		var cc *http.ClientCredentials
+		if dsc := s.sys.Config().GetDataSourceConfig(s.String()); dsc != nil {
+			cred = dsc.GetCredentials()
+			cc = dsc.ClientCredentials(cred)
 		}
-	} else {
-		s.sys.Config().Log.Print(s.String() + ": scrape: " + err.Error())
+		if cc == nil {
+			return nil, 0, nil, errors.New("the OAuth2 client credentials were not provided in the configuration")
+		}
+		req.OAuth2 = cc
 	}
 
-	L.Push(sucess)
-	return 1
+	var retries int
+	if n, ok := getNumberField(L, opt, "retries"); ok && n > 0 {
+		retries = int(n)
+		if retries > maxRequestRetries {
+			retries = maxRequestRetries
+		}
+	}
+	return req, retries, cred, nil
+}
+
+// Converts the HTTP response into a table with the status code, headers and body. The header
+// names are provided in lower case and multiple values of a header are joined using commas.
+func responseToTable(L *lua.LState, resp *http.Response) *lua.LTable {
+	headers := L.NewTable()
+	for name, values := range resp.Header {
+		headers.RawSetString(strings.ToLower(name), lua.LString(strings.Join(values, ", ")))
+	}
+
+	tb := L.NewTable()
+	tb.RawSetString("status", lua.LNumber(resp.Status))
+	tb.RawSetString("headers", headers)
+	tb.RawSetString("body", lua.LString(resp.Body))
+	return tb
 }
 
-func (s *Script) req(ctx context.Context, url, data string, headers map[string]string, auth *http.BasicAuth) (string, error) {
+// Sends the HTTP client request, and sends it again up to the number of retries while the
+// status code of the response indicates a transient failure. Each attempt is subject to the
+// request budget and the rate limit of the script, and is accounted for by the credentials.
+func (s *Script) req(ctx context.Context, req *http.Request, retries int, cred *config.Credentials) (*http.Response, error) {
 	cfg := s.sys.Config()
+	// Only the GET and POST responses are cached, since the other methods can change state, and
+	// the archive or the transport provided to the script need to see every request
+	cacheable := (req.Method == "GET" || req.Method == "POST") && s.transport == nil && !http.Archiving()
 	// Check for cached responses first
 	dsc := cfg.GetDataSourceConfig(s.String())
-	if dsc != nil && dsc.TTL > 0 {
-		if r, err := s.getCachedResponse(ctx, url+data, dsc.TTL); err == nil {
-			return r, err
+	if cacheable && dsc != nil && dsc.TTL > 0 {
+		if resp, err := s.getCachedRequest(ctx, req, dsc.TTL); err == nil {
+			return resp, nil
 		}
 	}
 
-	var body io.Reader
-	if data != "" {
-		body = strings.NewReader(data)
-	}
+	var err error
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		if err = cfg.SpendSourceBudget(s.String(), config.SourceDomain(ctx)); err != nil {
+			return nil, err
+		}
 
-	numRateLimitChecks(s, s.seconds)
-	resp, err := http.RequestWebPage(ctx, url, body, headers, auth)
-	if err != nil {
+		numRateLimitChecks(s, s.rateLimitSeconds())
+		req.Transport = s.transport
+		resp, err = http.SendRequest(ctx, req)
+		cfg.ReportCredentials(s.String(), cred, resp)
+		if err == nil || attempt >= retries {
+			break
+		}
+
+		delay, retry := http.RetryDelay(resp, attempt)
+		if !retry {
+			break
+		}
 		if cfg.Verbose {
-			cfg.Log.Printf("%s: %s: %v", s.String(), url, err)
+			cfg.Log.Printf("%s: %s: %v: retrying in %v", s.String(), req.URL, err, delay)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return resp, err
+		case <-t.C:
 		}
-	} else if dsc != nil && dsc.TTL > 0 {
-		_ = s.setCachedResponse(ctx, url+data, resp)
+	}
+
+	if err != nil {
+		cfg.SourceError(ctx, s.String(), fmt.Errorf("%s: %v", req.URL, err))
+	} else if cacheable && dsc != nil && dsc.TTL > 0 {
+		_ = s.setCachedRequest(ctx, req, resp)
 	}
 	return resp, err
 }
@@ -161,7 +241,12 @@ func (s *Script) crawl(L *lua.LState) int {
 		var names []string
 		max := L.CheckInt(3)
 
-		names, err = http.Crawl(ctx, u, cfg.Domains(), max)
+		// The crawler cannot use the transport provided to the script
+		if s.transport != nil {
+			err = fmt.Errorf("%s: %w", u, http.ErrNotArchived)
+		} else {
+			names, err = http.Crawl(ctx, u, cfg.Domains(), max)
+		}
 		if err == nil {
 			for _, name := range names {
 				genNewName(ctx, s.sys, s, http.CleanName(name))
@@ -169,8 +254,8 @@ func (s *Script) crawl(L *lua.LState) int {
 		}
 	}
 
-	if err != nil && cfg.Verbose {
-		cfg.Log.Printf("%s: %s: %v", s.String(), u, err)
+	if err != nil {
+		cfg.SourceError(ctx, s.String(), fmt.Errorf("%s: %v", u, err))
 	}
 	return 0
 }
@@ -0,0 +1,221 @@
+// Copyright © by Jeff Foley 2021-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+func TestRequestBudget(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		_, _ = w.Write([]byte("www.owasp.org"))
+	}))
+	defer ts.Close()
+
+	script, sys := setupMockScriptEnv(`
+		name="budget"
+		type="testing"
+
+		function vertical(ctx, domain)
+			for i=1,3 do
+				local _, err = request(ctx, {url="` + ts.URL + `"})
+				if (err ~= nil and err ~= "") then
+					new_name(ctx, "exhausted." .. domain)
+					return
+				end
+			end
+		end
+	`)
+	if script == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	sys.Config().GetDataSourceConfig("budget").MaxRequests = 2
+	sys.Config().AddDomain("owasp.org")
+	script.Input() <- &requests.DNSRequest{Domain: "owasp.org"}
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		t.Fatal("the test timed out")
+	case msg := <-script.Output():
+		if ans, ok := msg.(*requests.DNSRequest); !ok || ans.Name != "exhausted.owasp.org" {
+			t.Error("The script did not receive an error once the budget was used up")
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Expected two requests to be sent, got %d", n)
+	}
+}
+
+func TestRequestResponse(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) <= 2 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Link", `<https://api.owasp.org/?cursor=2>; rel="next"`)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	script, sys := setupMockScriptEnv(`
+		name="response"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local _, err, resp = request(ctx, {url="` + ts.URL + `"})
+			if (err == nil or resp == nil or resp.status ~= 429) then
+				return
+			end
+
+			local page, err, resp = request(ctx, {
+				method="put",
+				data="body",
+				url="` + ts.URL + `",
+				retries=2,
+			})
+			if (err ~= nil and err ~= "") or page ~= "PUT body" or resp.body ~= page or
+				resp.status ~= 201 or resp.headers["link"] == nil then
+				return
+			end
+			new_name(ctx, "www." .. domain)
+		end
+	`)
+	if script == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	sys.Config().AddDomain("owasp.org")
+	script.Input() <- &requests.DNSRequest{Domain: "owasp.org"}
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		t.Fatal("the test timed out")
+	case msg := <-script.Output():
+		if ans, ok := msg.(*requests.DNSRequest); !ok || ans.Name != "www.owasp.org" {
+			t.Error("The script did not receive the expected responses")
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("Expected three requests to be sent, got %d", n)
+	}
+}
+
+func TestRequestOAuth2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"secret-token","token_type":"bearer","expires_in":3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("www.owasp.org"))
+	}))
+	defer ts.Close()
+
+	script, sys := setupMockScriptEnv(`
+		name="oauth2"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local page, err = request(ctx, {url="` + ts.URL + `/api", oauth2=true})
+			if (err == nil or err == "") then
+				new_name(ctx, page)
+			end
+		end
+	`)
+	if script == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	dsc := sys.Config().GetDataSourceConfig("oauth2")
+	dsc.TokenURL = ts.URL + "/token"
+	_ = dsc.AddCredentials(&config.Credentials{Name: "account", Key: "client", Secret: "secret"})
+	sys.Config().AddDomain("owasp.org")
+	script.Input() <- &requests.DNSRequest{Domain: "owasp.org"}
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		t.Fatal("the test timed out")
+	case msg := <-script.Output():
+		if ans, ok := msg.(*requests.DNSRequest); !ok || ans.Name != "www.owasp.org" {
+			t.Error("The request was not authorized using the bearer token")
+		}
+	}
+}
+
+func TestRequestCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	dsc := cfg.GetDataSourceConfig("creds")
+	_ = dsc.AddCredentials(&config.Credentials{Name: "first", Key: "first-key"})
+	_ = dsc.AddCredentials(&config.Credentials{Name: "second", Key: "second-key"})
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	// The key is transformed, so the credentials cannot be found in the request
+	s, err := newScript(`
+		name="creds"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local c = datasrc_config()
+			request(ctx, {
+				url="`+ts.URL+`",
+				headers={["X-Token"]=string.reverse(c.credentials.key)},
+			})
+		end
+	`, "creds.ads", sys)
+	if err != nil {
+		t.Fatalf("Failed to load the script: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.dispatch(s.main, &requests.DNSRequest{Domain: "owasp.org"}); err != nil {
+			t.Fatalf("The callback failed: %v", err)
+		}
+	}
+	for _, usage := range dsc.CredentialsUsage() {
+		if usage.Requests != 1 || usage.Status != config.CredentialsInvalid {
+			t.Errorf("The %s credentials were not accounted for the request: %d requests, %s",
+				usage.Name, usage.Requests, usage.Status)
+		}
+	}
+}
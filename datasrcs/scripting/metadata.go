@@ -0,0 +1,205 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/caffix/stringset"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Metadata contains the information declared by a script using the 'meta' global table.
+type Metadata struct {
+	// The credentials fields that must be provided in the configuration
+	Credentials []string
+	// The callbacks implemented by the script to handle requests
+	Requests []string
+	// The API endpoints contacted by the script
+	Endpoints []string
+	// The number of seconds between the requests sent by the script
+	RateLimit int
+	// Indicates that the script does not interact with the target infrastructure,
+	// or is nil when the script did not declare it
+	Passive *bool
+}
+
+// The credentials fields that can be declared by scripts, mapped to the configuration values.
+var credentialFields = map[string]func(*config.Credentials) string{
+	"username": func(c *config.Credentials) string { return c.Username },
+	"password": func(c *config.Credentials) string { return c.Password },
+	"key":      func(c *config.Credentials) string { return c.Key },
+	"apikey":   func(c *config.Credentials) string { return c.Key },
+	"secret":   func(c *config.Credentials) string { return c.Secret },
+}
+
+// Returns the names of the request callbacks implemented by the script.
+func (cbs *callbacks) requests() []string {
+	var names []string
+
+	for _, cb := range []struct {
+		name string
+		fn   lua.LValue
+	}{
+		{"vertical", cbs.Vertical},
+		{"horizontal", cbs.Horizontal},
+		{"address", cbs.Address},
+		{"asn", cbs.Asn},
+		{"resolved", cbs.Resolved},
+		{"subdomain", cbs.Subdomain},
+	} {
+		if cb.fn.Type() != lua.LTNil {
+			names = append(names, cb.name)
+		}
+	}
+	return names
+}
+
+// Reads and validates the 'meta' global table of the script. The metadata is optional,
+// and the scripts that do not declare it can be used in passive mode.
+func scriptMetadata(st *scriptState) (*Metadata, error) {
+	implemented := st.cbs.requests()
+	meta := &Metadata{Requests: implemented}
+
+	lv := st.L.GetGlobal("meta")
+	if lv.Type() == lua.LTNil {
+		return meta, nil
+	}
+	tb, ok := lv.(*lua.LTable)
+	if !ok {
+		return nil, errors.New("the script global 'meta' is not a table")
+	}
+
+	var err error
+	if meta.Credentials, err = metaStrings(tb, "credentials"); err != nil {
+		return nil, err
+	}
+	for _, field := range meta.Credentials {
+		if _, found := credentialFields[field]; !found {
+			return nil, fmt.Errorf("the credentials field '%s' is not valid", field)
+		}
+	}
+
+	if lv := tb.RawGetString("requests"); lv.Type() != lua.LTNil {
+		declared, err := metaStrings(tb, "requests")
+		if err != nil {
+			return nil, err
+		}
+
+		impl := stringset.New(implemented...)
+		defer impl.Close()
+		decl := stringset.New(declared...)
+		defer decl.Close()
+
+		for _, name := range declared {
+			if !impl.Has(name) {
+				return nil, fmt.Errorf("the declared request type '%s' has no callback", name)
+			}
+		}
+		for _, name := range implemented {
+			if !decl.Has(name) {
+				return nil, fmt.Errorf("the %s callback is not declared in the request types", name)
+			}
+		}
+	}
+
+	if meta.Endpoints, err = metaStrings(tb, "endpoints"); err != nil {
+		return nil, err
+	}
+
+	switch v := tb.RawGetString("rate_limit").(type) {
+	case *lua.LNilType:
+	case lua.LNumber:
+		if v < 0 {
+			return nil, errors.New("the 'rate_limit' field must not be negative")
+		}
+		meta.RateLimit = int(v)
+	default:
+		return nil, errors.New("the 'rate_limit' field is not a number")
+	}
+
+	switch v := tb.RawGetString("passive").(type) {
+	case *lua.LNilType:
+	case lua.LBool:
+		passive := bool(v)
+		meta.Passive = &passive
+	default:
+		return nil, errors.New("the 'passive' field is not a boolean")
+	}
+	return meta, nil
+}
+
+// Returns the strings in the array held by the field of the metadata table.
+func metaStrings(tb *lua.LTable, field string) ([]string, error) {
+	lv := tb.RawGetString(field)
+	if lv.Type() == lua.LTNil {
+		return nil, nil
+	}
+
+	arr, ok := lv.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("the '%s' field is not a table", field)
+	}
+
+	var strs []string
+	var err error
+	arr.ForEach(func(_, v lua.LValue) {
+		if str, ok := v.(lua.LString); ok {
+			strs = append(strs, strings.ToLower(string(str)))
+		} else if err == nil {
+			err = fmt.Errorf("the '%s' field must only contain strings", field)
+		}
+	})
+	return strs, err
+}
+
+// Metadata returns the information declared by the script.
+func (s *Script) Metadata() *Metadata {
+	return s.meta
+}
+
+// Unavailable returns the reason the script cannot be used during the enumeration,
+// or an empty string when the script is available.
+func (s *Script) Unavailable() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.unavailable
+}
+
+// Checks that the configuration can satisfy the requirements declared by the script.
+func (s *Script) checkMetadata() error {
+	cfg := s.sys.Config()
+
+	if cfg.Passive && s.meta.Passive != nil && !*s.meta.Passive {
+		return errors.New("the script is not safe to use in passive mode")
+	}
+	if len(s.meta.Credentials) == 0 {
+		return nil
+	}
+
+	var creds *config.Credentials
+	if dsc := cfg.GetDataSourceConfig(s.String()); dsc != nil {
+		creds = dsc.PeekCredentials()
+	}
+	if creds == nil {
+		return fmt.Errorf("the %s credentials were not provided in the configuration",
+			strings.Join(s.meta.Credentials, ", "))
+	}
+
+	var missing []string
+	for _, field := range s.meta.Credentials {
+		if credentialFields[field](creds) == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the %s credentials are missing from the configuration", strings.Join(missing, ", "))
+	}
+	return nil
+}
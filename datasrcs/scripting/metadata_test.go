@@ -0,0 +1,105 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+)
+
+const metadataScript = `
+name="Metadata"
+type="api"
+
+meta = {
+	credentials={"username", "apikey"},
+	requests={"vertical", "asn"},
+	endpoints={"api.example.com"},
+	rate_limit=2,
+	passive=false,
+}
+
+function vertical(ctx, domain) end
+
+function asn(ctx, addr, asn) end
+`
+
+func TestScriptMetadata(t *testing.T) {
+	cfg := config.NewConfig()
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s, err := newScript(metadataScript, "metadata.ads", sys)
+	if err != nil {
+		t.Fatalf("Failed to load the script: %v", err)
+	}
+
+	meta := s.Metadata()
+	if strings.Join(meta.Credentials, ",") != "username,apikey" ||
+		strings.Join(meta.Requests, ",") != "vertical,asn" ||
+		strings.Join(meta.Endpoints, ",") != "api.example.com" ||
+		meta.RateLimit != 2 || meta.Passive == nil || *meta.Passive {
+		t.Errorf("The metadata was not read correctly: %+v", meta)
+	}
+
+	if err := s.Start(); err == nil || !strings.Contains(s.Unavailable(), "credentials were not provided") {
+		t.Errorf("The script started without the credentials: %v", s.Unavailable())
+	}
+
+	dsc := cfg.GetDataSourceConfig("metadata")
+	_ = dsc.AddCredentials(&config.Credentials{Name: "account", Key: "secret"})
+	if err := s.checkConfig(); err == nil || err.Error() != "the username credentials are missing from the configuration" {
+		t.Errorf("The missing credentials field was not reported: %v", err)
+	}
+
+	_ = dsc.AddCredentials(&config.Credentials{Name: "account", Username: "user", Key: "secret"})
+	_ = dsc.AddCredentials(&config.Credentials{Name: "backup", Username: "user", Key: "backup"})
+	if err := s.checkConfig(); err != nil {
+		t.Errorf("The credentials were not accepted: %v", err)
+	}
+	// Checking the configuration does not rotate the credentials
+	if cred := dsc.GetCredentials(); cred == nil || cred.Name != "account" {
+		t.Errorf("Checking the configuration advanced the credentials rotation")
+	}
+
+	cfg.Passive = true
+	if err := s.checkConfig(); err == nil {
+		t.Errorf("The script was used in passive mode")
+	}
+
+	undeclared, err := newScript(`
+		name="Undeclared"
+		type="api"
+
+		function vertical(ctx, domain) end
+	`, "undeclared.ads", sys)
+	if err != nil {
+		t.Fatalf("Failed to load the script: %v", err)
+	}
+	if undeclared.Metadata().Passive != nil {
+		t.Errorf("The script without metadata was considered passive")
+	}
+	if err := undeclared.checkConfig(); err != nil {
+		t.Errorf("The script without metadata was not used in passive mode: %v", err)
+	}
+
+	invalid := []string{
+		`meta = "invalid"`,
+		`meta = {credentials={"token"}}`,
+		`meta = {requests={"vertical", "horizontal"}}`,
+		`meta = {requests={}}`,
+		`meta = {rate_limit=-1}`,
+		`meta = {passive="yes"}`,
+	}
+	for _, decl := range invalid {
+		script := strings.Replace(metadataScript, "meta = {", decl+"\nlocal unused = {", 1)
+
+		if _, err := newScript(script, "invalid.ads", sys); err == nil {
+			t.Errorf("The invalid metadata was accepted: %s", decl)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"fmt"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// scriptState is a Lua state loaded from the script source. Each state executes a single
+// callback at a time, so the script serves concurrent requests using a pool of states.
+type scriptState struct {
+	L   *lua.LState
+	cbs *callbacks
+}
+
+// Loads the script source into a new Lua state within the limits of the sandbox.
+func (s *Script) newScriptState() (*scriptState, error) {
+	L := s.newLuaState(s.sys.Config())
+
+	fn, err := L.Load(strings.NewReader(s.source), s.chunk)
+	if err == nil {
+		ctx, cancel := s.callbackContext(s.ctx)
+		sc := newSandboxContext(ctx, s.sys.Config().ScriptLimits)
+
+		L.SetContext(sc)
+		L.Push(fn)
+		err = L.PCall(0, lua.MultRet, nil)
+		L.RemoveContext()
+		cancel()
+
+		if limit := sc.exceeded(err); limit != nil {
+			err = limit
+		}
+	}
+	if err != nil {
+		L.Close()
+		return nil, err
+	}
+	return &scriptState{L: L, cbs: assignCallbacks(L)}, nil
+}
+
+// Creates the additional Lua states required to serve the configured number of concurrent
+// callbacks. The start callback is executed by each new state, so the script initialization
+// is performed for all the states in the pool.
+func (s *Script) fillStatePool() {
+	for len(s.all) < s.concurrency {
+		st, err := s.newScriptState()
+		if err != nil {
+			s.sys.Config().SourceError(s.ctx, s.String(), fmt.Errorf("failed to load the script: %v", err))
+			return
+		}
+
+		s.startCallback(st)
+		s.all = append(s.all, st)
+		s.states <- st
+	}
+}
+
+// Executes the start callback of the script using the provided Lua state.
+func (s *Script) startCallback(st *scriptState) {
+	if st.cbs.Start.Type() == lua.LTNil {
+		return
+	}
+
+	ctx, cancel := s.callbackContext(s.ctx)
+	defer cancel()
+
+	if err := s.call(ctx, st, st.cbs.Start, 0); err != nil {
+		s.sys.Config().SourceError(ctx, s.String(), fmt.Errorf("start callback: %v", err))
+	}
+}
+
+// Blocks until a Lua state is available in the pool or the script has been stopped.
+func (s *Script) acquireState() (*scriptState, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, fmt.Errorf("%s: the script has been stopped", s.String())
+	case st := <-s.states:
+		return st, nil
+	}
+}
+
+// Returns the Lua state to the pool after the callback has finished.
+func (s *Script) releaseState(st *scriptState) {
+	s.states <- st
+}
+
+// Waits for the executing callbacks to return all the Lua states to the pool.
+func (s *Script) drainStatePool() {
+	for range s.all {
+		<-s.states
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/stringset"
+)
+
+func TestConcurrentCallbacks(t *testing.T) {
+	const concurrency = 3
+
+	var lock sync.Mutex
+	var active, max int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		active++
+		if active > max {
+			max = active
+		}
+		lock.Unlock()
+		// Hold the request so the callbacks overlap
+		time.Sleep(500 * time.Millisecond)
+		lock.Lock()
+		active--
+		lock.Unlock()
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	cfg := config.NewConfig()
+	cfg.GetDataSourceConfig("pool").Concurrency = concurrency
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s, err := newScript(`
+		name="pool"
+		type="testing"
+
+		function start()
+			set_rate_limit(0)
+			calls = 0
+		end
+
+		function vertical(ctx, domain)
+			calls = calls + 1
+			local _, err = request(ctx, {url="`+ts.URL+`"})
+			if (err == nil or err == "") then
+				new_name(ctx, "www" .. calls .. "." .. domain)
+			end
+		end
+	`, "pool.ads", sys)
+	if err != nil {
+		t.Fatalf("Failed to load the script: %v", err)
+	}
+	if err := sys.AddAndStart(s); err != nil {
+		t.Fatalf("Failed to start the script: %v", err)
+	}
+	if n := len(s.all); n != concurrency {
+		t.Errorf("Expected %d Lua states in the pool, got %d", concurrency, n)
+	}
+
+	domains := []string{"owasp.org", "example.com", "example.org"}
+	cfg.AddDomains(domains...)
+	for _, d := range domains {
+		s.Input() <- &requests.DNSRequest{Domain: d}
+	}
+
+	names := stringset.New()
+	defer names.Close()
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	for names.Len() < len(domains) {
+		select {
+		case <-timer.C:
+			t.Fatalf("The test timed out with the names %v", names.Slice())
+		case out := <-s.Output():
+			if req, ok := out.(*requests.DNSRequest); ok {
+				names.Insert(req.Name)
+			}
+		}
+	}
+	// Each callback executed using a separate Lua state
+	for _, d := range domains {
+		if !names.Has("www1." + d) {
+			t.Errorf("The name www1.%s was not provided by a separate Lua state", d)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if max != concurrency {
+		t.Errorf("Expected %d concurrent callbacks, got %d", concurrency, max)
+	}
+}
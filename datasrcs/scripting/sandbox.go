@@ -0,0 +1,157 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// The errors reported when a callback exceeds the limits of the script sandbox.
+var (
+	ErrCallbackTimeout   = errors.New("the callback timeout was exceeded")
+	ErrInstructionBudget = errors.New("the instruction budget was exceeded")
+	ErrRegistryLimit     = errors.New("the registry limit was exceeded")
+)
+
+// The message raised by the Lua VM when the registry cannot grow any further.
+const registryOverflow = "registry overflow"
+
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+}
+
+// sandboxContext is set on the Lua state while a callback executes. The VM checks the
+// context before executing each instruction, which allows the instructions to be counted
+// without modifying the interpreter.
+type sandboxContext struct {
+	context.Context
+	count        int64
+	instructions int64
+	violation    atomic.Value
+	// The credentials the callback obtained from the data source configuration
+	creds atomic.Value
+}
+
+func newSandboxContext(ctx context.Context, limits config.ScriptLimits) *sandboxContext {
+	return &sandboxContext{
+		Context:      ctx,
+		instructions: int64(limits.Instructions),
+	}
+}
+
+// Done implements the context.Context interface.
+func (sc *sandboxContext) Done() <-chan struct{} {
+	if sc.violated() != nil {
+		return closedChan
+	}
+
+	if count := atomic.AddInt64(&sc.count, 1); sc.instructions > 0 && count > sc.instructions {
+		sc.violation.Store(ErrInstructionBudget)
+		return closedChan
+	}
+	return sc.Context.Done()
+}
+
+// Err implements the context.Context interface.
+func (sc *sandboxContext) Err() error {
+	if err := sc.violated(); err != nil {
+		return err
+	}
+
+	err := sc.Context.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrCallbackTimeout
+	}
+	return err
+}
+
+func (sc *sandboxContext) violated() error {
+	if err, ok := sc.violation.Load().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Returns the credentials selected by the callback executing on the Lua state, or nil when
+// the callback has not obtained credentials from the data source configuration.
+func selectedCredentials(L *lua.LState) *config.Credentials {
+	if sc, ok := L.Context().(*sandboxContext); ok {
+		if cred, ok := sc.creds.Load().(*config.Credentials); ok {
+			return cred
+		}
+	}
+	return nil
+}
+
+// Records the credentials selected by the callback executing on the Lua state, so the
+// requests sent by the callback are accounted for by these credentials.
+func selectCredentials(L *lua.LState, cred *config.Credentials) {
+	if sc, ok := L.Context().(*sandboxContext); ok && cred != nil {
+		sc.creds.Store(cred)
+	}
+}
+
+// Returns the limit exceeded by the callback, or nil when the limits were respected.
+// The error returned by the Lua VM reveals when the registry of the state overflowed.
+func (sc *sandboxContext) exceeded(err error) error {
+	if v := sc.violated(); v != nil {
+		return v
+	}
+	if errors.Is(sc.Context.Err(), context.DeadlineExceeded) {
+		return ErrCallbackTimeout
+	}
+	if err != nil && strings.Contains(err.Error(), registryOverflow) {
+		return ErrRegistryLimit
+	}
+	return nil
+}
+
+// Returns a context for the callback that expires when the callback timeout is exceeded.
+func (s *Script) callbackContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t := s.sys.Config().ScriptLimits.Timeout; t > 0 {
+		return context.WithTimeout(ctx, time.Duration(t)*time.Second)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Executes the Lua function within the limits of the sandbox. The script is disabled when
+// the function exceeds the limits, so it cannot continue to consume the resources.
+func (s *Script) call(ctx context.Context, st *scriptState, fn lua.LValue, nret int, args ...lua.LValue) error {
+	L := st.L
+	sc := newSandboxContext(ctx, s.sys.Config().ScriptLimits)
+
+	L.SetContext(sc)
+	err := L.CallByParam(lua.P{
+		Fn:      fn,
+		NRet:    nret,
+		Protect: true,
+	}, args...)
+	L.RemoveContext()
+
+	if limit := sc.exceeded(err); limit != nil {
+		s.disable(limit)
+		err = limit
+	}
+	return err
+}
+
+// Stops the script from receiving requests and reports the failure.
+func (s *Script) disable(err error) {
+	s.lock.Lock()
+	s.disabled = true
+	s.lock.Unlock()
+
+	s.sys.Config().SourceError(s.ctx, s.String(), fmt.Errorf("the script was disabled: %v", err))
+}
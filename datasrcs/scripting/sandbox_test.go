@@ -0,0 +1,87 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+func TestSandboxLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   config.ScriptLimits
+		body     string
+		expected error
+	}{
+		{
+			name:     "instructions",
+			limits:   config.ScriptLimits{Instructions: 100000},
+			body:     "while true do end",
+			expected: ErrInstructionBudget,
+		},
+		{
+			name:     "timeout",
+			limits:   config.ScriptLimits{Timeout: 1},
+			body:     "while true do end",
+			expected: ErrCallbackTimeout,
+		},
+		{
+			name:   "registry",
+			limits: config.ScriptLimits{Registry: 10000},
+			body: `local t = {}
+				for i = 1, 100000 do
+					t[i] = i
+				end
+				local copy = {unpack(t)}`,
+			expected: ErrRegistryLimit,
+		},
+	}
+
+	for _, test := range tests {
+		cfg := config.NewConfig()
+		cfg.ScriptLimits = test.limits
+
+		sys := newMockSystem(cfg)
+		s, err := newScript(`
+			name="sandbox"
+			type="testing"
+
+			function vertical(ctx, domain)
+				`+test.body+`
+			end
+		`, "sandbox.ads", sys)
+		if err != nil {
+			t.Fatalf("%s: Failed to load the script: %v", test.name, err)
+		}
+
+		start := time.Now()
+		err = s.dispatch(s.main, &requests.DNSRequest{Domain: "owasp.org"})
+		if err == nil || !strings.Contains(err.Error(), test.expected.Error()) {
+			t.Errorf("%s: The callback returned the wrong error: %v", test.name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 30*time.Second {
+			t.Errorf("%s: The callback ran for %v", test.name, elapsed)
+		}
+		if !s.isDisabled() {
+			t.Errorf("%s: The script was not disabled", test.name)
+		}
+		if err := s.dispatch(s.main, &requests.DNSRequest{Domain: "owasp.org"}); err != nil {
+			t.Errorf("%s: The disabled script executed the callback: %v", test.name, err)
+		}
+		_ = sys.Shutdown()
+	}
+
+	cfg := config.NewConfig()
+	cfg.ScriptLimits = config.ScriptLimits{Instructions: 100000}
+	if _, err := newScript("while true do end", "loop.ads", newMockSystem(cfg)); err == nil ||
+		!strings.Contains(err.Error(), ErrInstructionBudget.Error()) {
+		t.Errorf("The script body was not limited: %v", err)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"regexp"
 	"sync"
 	"time"
@@ -39,70 +41,142 @@ type callbacks struct {
 // Script is the Service that handles access to the Script data source.
 type Script struct {
 	service.BaseService
-	SourceType string
-	sys        systems.System
-	luaState   *lua.LState
-	cbs        *callbacks
-	subre      *regexp.Regexp
-	seconds    int
-	active     sync.Mutex
-	ctx        context.Context
-	cancel     context.CancelFunc
-	queue      queue.Queue
+	SourceType  string
+	sys         systems.System
+	source      string
+	chunk       string
+	modules     map[string]string
+	main        *scriptState
+	all         []*scriptState
+	states      chan *scriptState
+	concurrency int
+	meta        *Metadata
+	unavailable string
+	subre       *regexp.Regexp
+	lock        sync.Mutex
+	seconds     int
+	disabled    bool
+	ctx         context.Context
+	cancel      context.CancelFunc
+	queue       queue.Queue
+	shared      sharedStore
+	// Sends the HTTP requests of the script instead of the package client, such as for the fixtures
+	transport http.RoundTripper
 }
 
 // NewScript returns he object initialized, but not yet started.
 func NewScript(script string, sys systems.System) *Script {
-	re, err := regexp.Compile(dns.AnySubdomainRegexString())
+	s, err := newScript(script, "<string>", sys)
 	if err != nil {
+		sys.Config().Log.Printf("Script: %v", err)
 		return nil
 	}
+	return s
+}
+
+// Loads the script using the chunk name provided for the Lua error messages.
+func newScript(script, chunk string, sys systems.System) (*Script, error) {
+	re, err := regexp.Compile(dns.AnySubdomainRegexString())
+	if err != nil {
+		return nil, err
+	}
 
 	s := &Script{
-		sys:   sys,
-		subre: re,
-		queue: queue.NewQueue(),
+		sys:    sys,
+		source: script,
+		chunk:  chunk,
+		subre:  re,
+		queue:  queue.NewQueue(),
+	}
+	// The embedded and user provided Lua modules are available to the script
+	s.modules, err = sys.Config().ScriptModules()
+	if err != nil {
+		return nil, err
 	}
 	s.ctx, s.cancel = context.WithCancel(context.Background())
 
-	L := s.newLuaState(sys.Config())
-	s.luaState = L
-	//L.SetContext(s.ctx)
-	// Load the script
-	if err := L.DoString(script); err != nil {
-		sys.Config().Log.Printf("Script: Failed to load the %s script: %v", script, err)
-		return nil
+	s.main, err = s.newScriptState()
+	if err != nil {
+		s.cancel()
+		return nil, fmt.Errorf("failed to load the %s script: %v", chunk, err)
 	}
 	// Pull the script type from the script
-	s.SourceType, err = s.scriptType()
+	s.SourceType, err = scriptType(s.main.L)
 	if err != nil {
-		sys.Config().Log.Printf("Script: Failed to obtain the %s script type: %v", script, err)
-		return nil
+		s.cancel()
+		s.main.L.Close()
+		return nil, fmt.Errorf("failed to obtain the %s script type: %v", chunk, err)
 	}
 	// Pull the script name from the script
-	name, err := s.scriptName()
+	name, err := scriptName(s.main.L)
 	if err != nil {
-		sys.Config().Log.Printf("Script: Failed to obtain the %s script name: %v", script, err)
-		return nil
+		s.cancel()
+		s.main.L.Close()
+		return nil, fmt.Errorf("failed to obtain the %s script name: %v", chunk, err)
+	}
+	// Validate the metadata declared by the script
+	s.meta, err = scriptMetadata(s.main)
+	if err != nil {
+		s.cancel()
+		s.main.L.Close()
+		return nil, fmt.Errorf("failed to obtain the %s script metadata: %v", chunk, err)
 	}
 	s.BaseService = *service.NewBaseService(s, name)
-	// Save references to the callbacks defined within the script
-	s.assignCallbacks()
+	// The pool of Lua states allows the callbacks to serve requests concurrently
+	s.concurrency = 1
+	if dsc := sys.Config().GetDataSourceConfig(name); dsc != nil && dsc.Concurrency > 1 {
+		s.concurrency = dsc.Concurrency
+	}
+	s.states = make(chan *scriptState, s.concurrency)
+	s.all = append(s.all, s.main)
+	s.states <- s.main
 	go s.manageOutput()
 	go s.requests()
-	return s
+	return s, nil
+}
+
+// ScriptName returns the name declared by the script without starting the data source.
+func ScriptName(script string, sys systems.System) (string, error) {
+	s, err := newScript(script, "<string>", sys)
+	if err != nil {
+		return "", err
+	}
+
+	s.Release()
+	return s.String(), nil
+}
+
+// Release frees the resources of a script that was never started. The scripts
+// that have been started release their resources when they are stopped.
+func (s *Script) Release() {
+	s.cancel()
+	s.main.L.Close()
 }
 
 // Setup the Lua state with desired constraints and access to necessary functionality.
 func (s *Script) newLuaState(cfg *config.Config) *lua.LState {
-	L := lua.NewState(lua.Options{
+	opts := lua.Options{
 		CallStackSize:       120,
+		RegistrySize:        lua.RegistrySize,
+		RegistryMaxSize:     math.MaxInt32,
 		MinimizeStackMemory: true,
-	})
+	}
+	// The registry holds the values on the stack of the state and is allowed to grow to the limit
+	if max := cfg.ScriptLimits.Registry; max > 0 {
+		opts.RegistryMaxSize = max
+		if max < opts.RegistrySize {
+			opts.RegistrySize = max
+		}
+	}
+
+	L := lua.NewState(opts)
 
 	registerSocketType(L)
 	L.PreloadModule("url", luaurl.Loader)
 	L.PreloadModule("json", luajson.Loader)
+	for name, source := range s.modules {
+		L.PreloadModule(name, moduleLoader(name, source))
+	}
 	L.SetGlobal("config", L.NewFunction(s.config))
 	L.SetGlobal("datasrc_config", L.NewFunction(s.dataSourceConfig))
 	L.SetGlobal("brute_wordlist", L.NewFunction(s.bruteWordlist))
@@ -126,15 +200,15 @@ func (s *Script) newLuaState(cfg *config.Config) *lua.LState {
 	L.SetGlobal("check_rate_limit", L.NewFunction(s.checkRateLimit))
 	L.SetGlobal("obtain_response", L.NewFunction(s.obtainResponse))
 	L.SetGlobal("cache_response", L.NewFunction(s.cacheResponse))
+	L.SetGlobal("shared_get", L.NewFunction(s.sharedGet))
+	L.SetGlobal("shared_set", L.NewFunction(s.sharedSet))
 	L.SetGlobal("subdomain_regex", lua.LString(dns.AnySubdomainRegexString()))
 	return L
 }
 
 // Save references to the script functions that serve as callbacks for Amass events.
-func (s *Script) assignCallbacks() {
-	L := s.luaState
-
-	s.cbs = &callbacks{
+func assignCallbacks(L *lua.LState) *callbacks {
+	return &callbacks{
 		Start:      L.GetGlobal("start"),
 		Stop:       L.GetGlobal("stop"),
 		Check:      L.GetGlobal("check"),
@@ -148,8 +222,7 @@ func (s *Script) assignCallbacks() {
 }
 
 // Acquires the script name of the script by accessing the global variable.
-func (s *Script) scriptName() (string, error) {
-	L := s.luaState
+func scriptName(L *lua.LState) (string, error) {
 	lv := L.GetGlobal("name")
 
 	if lv.Type() == lua.LTNil {
@@ -162,8 +235,7 @@ func (s *Script) scriptName() (string, error) {
 }
 
 // Acquires the script type of the script by accessing the global variable.
-func (s *Script) scriptType() (string, error) {
-	L := s.luaState
+func scriptType(L *lua.LState) (string, error) {
 	lv := L.GetGlobal("type")
 
 	if lv.Type() == lua.LTNil {
@@ -182,66 +254,69 @@ func (s *Script) Description() string {
 
 // OnStart implements the Service interface.
 func (s *Script) OnStart() error {
-	s.active.Lock()
-	defer s.active.Unlock()
-
-	if L := s.luaState; s.cbs.Start.Type() != lua.LTNil {
-		err := L.CallByParam(lua.P{
-			Fn:      s.cbs.Start,
-			NRet:    0,
-			Protect: true,
-		})
-		if err != nil {
-			s.sys.Config().Log.Printf("%s: start callback: %v", s.String(), err)
-		}
+	if s.meta.RateLimit > 0 {
+		s.setRateLimitSeconds(s.meta.RateLimit)
 	}
-	if s.seconds > 0 {
+	s.startCallback(s.main)
+	if s.rateLimitSeconds() > 0 {
 		s.SetRateLimit(1)
 	}
-	return s.checkConfig()
+	if err := s.checkConfig(); err != nil {
+		s.lock.Lock()
+		s.unavailable = err.Error()
+		s.lock.Unlock()
+
+		estr := fmt.Sprintf("%s: %v", s.String(), err)
+		s.sys.Config().Log.Print(estr)
+		return errors.New(estr)
+	}
+
+	s.fillStatePool()
+	return nil
 }
 
 // OnStop implements the Service interface.
 func (s *Script) OnStop() error {
 	s.cancel()
-
-	s.active.Lock()
-	defer s.active.Unlock()
+	// Wait for the executing callbacks to finish
+	s.drainStatePool()
 
 	var err error
-	if L := s.luaState; s.cbs.Stop.Type() != lua.LTNil {
-		err = L.CallByParam(lua.P{
-			Fn:      s.cbs.Stop,
-			NRet:    0,
-			Protect: true,
-		})
+	if s.main.cbs.Stop.Type() != lua.LTNil && !s.isDisabled() {
+		// The script context has already been cancelled
+		ctx, cancel := s.callbackContext(context.Background())
+		err = s.call(ctx, s.main, s.main.cbs.Stop, 0)
+		cancel()
 		if err != nil {
 			err = fmt.Errorf("%s: stop callback: %v", s.String(), err)
 			s.sys.Config().Log.Print(err.Error())
 		}
 	}
 
-	s.luaState.Close()
+	for _, st := range s.all {
+		st.L.Close()
+	}
 	return err
 }
 
 func (s *Script) checkConfig() error {
-	L := s.luaState
+	L := s.main.L
 
-	if s.cbs.Check.Type() == lua.LTNil {
+	if s.isDisabled() {
+		return errors.New("the script was disabled")
+	}
+	if err := s.checkMetadata(); err != nil {
+		return err
+	}
+	if s.main.cbs.Check.Type() == lua.LTNil {
 		return nil
 	}
 
-	err := L.CallByParam(lua.P{
-		Fn:      s.cbs.Check,
-		NRet:    1,
-		Protect: true,
-	})
-	if err != nil {
-		estr := fmt.Sprintf("%s: check callback: %v", s.String(), err)
+	ctx, cancel := s.callbackContext(s.ctx)
+	defer cancel()
 
-		s.sys.Config().Log.Print(estr)
-		return errors.New(estr)
+	if err := s.call(ctx, s.main, s.main.cbs.Check, 1); err != nil {
+		return fmt.Errorf("check callback: %v", err)
 	}
 
 	ret := L.Get(-1)
@@ -251,10 +326,29 @@ func (s *Script) checkConfig() error {
 	if ok && bool(passed) {
 		return nil
 	}
+	return errors.New("check callback failed for the configuration")
+}
+
+// Returns the number of rate limit checks performed before each request.
+func (s *Script) rateLimitSeconds() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	estr := fmt.Sprintf("%s: check callback failed for the configuration", s.String())
-	s.sys.Config().Log.Print(estr)
-	return errors.New(estr)
+	return s.seconds
+}
+
+func (s *Script) setRateLimitSeconds(seconds int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.seconds = seconds
+}
+
+func (s *Script) isDisabled() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.disabled
 }
 
 func (s *Script) manageOutput() {
@@ -312,7 +406,15 @@ loop:
 			case <-s.ctx.Done():
 				break loop
 			case in := <-s.Input():
-				s.dispatch(in)
+				st, err := s.acquireState()
+				if err != nil {
+					break loop
+				}
+				// The callback executes while the next request is obtained
+				go func() {
+					defer s.releaseState(st)
+					_ = s.dispatch(st, in)
+				}()
 			}
 		case <-t.C:
 			if s.queue.Len() == 0 {
@@ -334,154 +436,133 @@ loop:
 	}
 }
 
-func (s *Script) dispatch(in interface{}) {
-	s.active.Lock()
-	defer s.active.Unlock()
+func (s *Script) dispatch(st *scriptState, in interface{}) error {
+	domain := requests.RequestDomain(in)
+	if s.isDisabled() || s.sys.Config().SourceBudgetExhausted(s.String(), domain) {
+		return nil
+	}
+
+	ctx, cancel := s.callbackContext(s.ctx)
+	defer cancel()
+	// The domain is used to account for the requests sent by the callback
+	ctx = config.WithSourceDomain(ctx, domain)
 
+	var err error
 	switch req := in.(type) {
 	case *requests.DNSRequest:
-		if s.cbs.Vertical.Type() != lua.LTNil && req != nil && req.Domain != "" {
+		if st.cbs.Vertical.Type() != lua.LTNil && req != nil && req.Domain != "" {
 			s.CheckRateLimit()
-			s.dnsRequest(s.ctx, req)
+			err = s.dnsRequest(ctx, st, req)
 		}
 	case *requests.ResolvedRequest:
-		if s.cbs.Resolved.Type() != lua.LTNil && req != nil && req.Name != "" && len(req.Records) > 0 {
+		if st.cbs.Resolved.Type() != lua.LTNil && req != nil && req.Name != "" && len(req.Records) > 0 {
 			s.CheckRateLimit()
-			s.resolvedRequest(s.ctx, req)
+			err = s.resolvedRequest(ctx, st, req)
 		}
 	case *requests.SubdomainRequest:
-		if s.cbs.Subdomain.Type() != lua.LTNil && req != nil && req.Name != "" {
+		if st.cbs.Subdomain.Type() != lua.LTNil && req != nil && req.Name != "" {
 			s.CheckRateLimit()
-			s.subdomainRequest(s.ctx, req)
+			err = s.subdomainRequest(ctx, st, req)
 		}
 	case *requests.AddrRequest:
-		if s.cbs.Address.Type() != lua.LTNil && req != nil && req.Address != "" {
+		if st.cbs.Address.Type() != lua.LTNil && req != nil && req.Address != "" {
 			s.CheckRateLimit()
-			s.addrRequest(s.ctx, req)
+			err = s.addrRequest(ctx, st, req)
 		}
 	case *requests.ASNRequest:
-		if s.cbs.Asn.Type() != lua.LTNil && req != nil && (req.Address != "" || req.ASN != 0) {
+		if st.cbs.Asn.Type() != lua.LTNil && req != nil && (req.Address != "" || req.ASN != 0) {
 			s.CheckRateLimit()
-			s.asnRequest(s.ctx, req)
+			err = s.asnRequest(ctx, st, req)
 		}
 	case *requests.WhoisRequest:
-		if s.cbs.Horizontal.Type() != lua.LTNil {
+		if st.cbs.Horizontal.Type() != lua.LTNil {
 			s.CheckRateLimit()
-			s.whoisRequest(s.ctx, req)
+			err = s.whoisRequest(ctx, st, req)
 		}
 	}
+	if err != nil {
+		s.sys.Config().SourceError(ctx, s.String(), err)
+	}
+	return err
 }
 
-func (s *Script) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
-	L := s.luaState
-
+func (s *Script) dnsRequest(ctx context.Context, st *scriptState, req *requests.DNSRequest) error {
 	if contextExpired(ctx) {
-		return
+		return nil
 	}
 
 	s.sys.Config().Log.Printf("Querying %s for %s subdomains", s.String(), req.Domain)
 
-	err := L.CallByParam(lua.P{
-		Fn:      s.cbs.Vertical,
-		NRet:    0,
-		Protect: true,
-	}, s.contextToUserData(ctx), lua.LString(req.Domain))
+	err := s.call(ctx, st, st.cbs.Vertical, 0, contextToUserData(st.L, ctx), lua.LString(req.Domain))
 	if err != nil {
-		s.sys.Config().Log.Printf("%s: vertical callback: %v", s.String(), err)
+		return fmt.Errorf("vertical callback: %v", err)
 	}
+	return nil
 }
 
-func (s *Script) resolvedRequest(ctx context.Context, req *requests.ResolvedRequest) {
-	L := s.luaState
+func (s *Script) resolvedRequest(ctx context.Context, st *scriptState, req *requests.ResolvedRequest) error {
+	L := st.L
 
 	if contextExpired(ctx) {
-		return
+		return nil
 	}
 
 	records := L.NewTable()
 	for _, rec := range req.Records {
-		tb := L.NewTable()
-
-		tb.RawSetString("rrname", lua.LString(rec.Name))
-		tb.RawSetString("rrtype", lua.LNumber(rec.Type))
-		tb.RawSetString("rrdata", lua.LString(rec.Data))
-		records.Append(tb)
+		records.Append(answerToTable(L, rec))
 	}
 
-	err := L.CallByParam(lua.P{
-		Fn:      s.cbs.Resolved,
-		NRet:    0,
-		Protect: true,
-	}, s.contextToUserData(ctx), lua.LString(req.Name), lua.LString(req.Domain), records)
+	err := s.call(ctx, st, st.cbs.Resolved, 0, contextToUserData(st.L, ctx), lua.LString(req.Name), lua.LString(req.Domain), records)
 	if err != nil {
-		s.sys.Config().Log.Printf("%s: resolved callback: %v", s.String(), err)
+		return fmt.Errorf("resolved callback: %v", err)
 	}
+	return nil
 }
 
-func (s *Script) subdomainRequest(ctx context.Context, req *requests.SubdomainRequest) {
-	L := s.luaState
-
+func (s *Script) subdomainRequest(ctx context.Context, st *scriptState, req *requests.SubdomainRequest) error {
 	if contextExpired(ctx) {
-		return
+		return nil
 	}
 
-	err := L.CallByParam(lua.P{
-		Fn:      s.cbs.Subdomain,
-		NRet:    0,
-		Protect: true,
-	}, s.contextToUserData(ctx), lua.LString(req.Name), lua.LString(req.Domain), lua.LNumber(req.Times))
+	err := s.call(ctx, st, st.cbs.Subdomain, 0, contextToUserData(st.L, ctx), lua.LString(req.Name), lua.LString(req.Domain), lua.LNumber(req.Times))
 	if err != nil {
-		s.sys.Config().Log.Printf("%s: subdomain callback: %v", s.String(), err)
+		return fmt.Errorf("subdomain callback: %v", err)
 	}
+	return nil
 }
 
-func (s *Script) addrRequest(ctx context.Context, req *requests.AddrRequest) {
-	L := s.luaState
-
+func (s *Script) addrRequest(ctx context.Context, st *scriptState, req *requests.AddrRequest) error {
 	if contextExpired(ctx) {
-		return
+		return nil
 	}
 
-	err := L.CallByParam(lua.P{
-		Fn:      s.cbs.Address,
-		NRet:    0,
-		Protect: true,
-	}, s.contextToUserData(ctx), lua.LString(req.Address))
+	err := s.call(ctx, st, st.cbs.Address, 0, contextToUserData(st.L, ctx), lua.LString(req.Address))
 	if err != nil {
-		s.sys.Config().Log.Printf("%s: address callback: %v", s.String(), err)
+		return fmt.Errorf("address callback: %v", err)
 	}
+	return nil
 }
 
-func (s *Script) asnRequest(ctx context.Context, req *requests.ASNRequest) {
-	L := s.luaState
-
+func (s *Script) asnRequest(ctx context.Context, st *scriptState, req *requests.ASNRequest) error {
 	if contextExpired(ctx) {
-		return
+		return nil
 	}
 
-	err := L.CallByParam(lua.P{
-		Fn:      s.cbs.Asn,
-		NRet:    0,
-		Protect: true,
-	}, s.contextToUserData(ctx), lua.LString(req.Address), lua.LNumber(req.ASN))
+	err := s.call(ctx, st, st.cbs.Asn, 0, contextToUserData(st.L, ctx), lua.LString(req.Address), lua.LNumber(req.ASN))
 	if err != nil {
-		s.sys.Config().Log.Printf("%s: asn callback: %v", s.String(), err)
+		return fmt.Errorf("asn callback: %v", err)
 	}
+	return nil
 }
 
-func (s *Script) whoisRequest(ctx context.Context, req *requests.WhoisRequest) {
-	L := s.luaState
-
+func (s *Script) whoisRequest(ctx context.Context, st *scriptState, req *requests.WhoisRequest) error {
 	if contextExpired(ctx) {
-		return
+		return nil
 	}
 
-	err := L.CallByParam(lua.P{
-		Fn:      s.cbs.Horizontal,
-		NRet:    0,
-		Protect: true,
-	}, s.contextToUserData(ctx), lua.LString(req.Domain))
+	err := s.call(ctx, st, st.cbs.Horizontal, 0, contextToUserData(st.L, ctx), lua.LString(req.Domain))
 	if err != nil {
-		s.sys.Config().Log.Printf("%s: horizontal callback: %v", s.String(), err)
+		return fmt.Errorf("horizontal callback: %v", err)
 	}
+	return nil
 }
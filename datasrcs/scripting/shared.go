@@ -0,0 +1,64 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// sharedStore holds the values shared by all the Lua states of a script. Only the immutable
+// Lua values are accepted, so the states never share a table.
+type sharedStore struct {
+	sync.Mutex
+	values map[string]lua.LValue
+}
+
+func (ss *sharedStore) get(key string) lua.LValue {
+	ss.Lock()
+	defer ss.Unlock()
+
+	if v, found := ss.values[key]; found {
+		return v
+	}
+	return lua.LNil
+}
+
+func (ss *sharedStore) set(key string, value lua.LValue) {
+	ss.Lock()
+	defer ss.Unlock()
+
+	if value == lua.LNil {
+		delete(ss.values, key)
+		return
+	}
+	if ss.values == nil {
+		ss.values = make(map[string]lua.LValue)
+	}
+	ss.values[key] = value
+}
+
+// Wrapper so that scripts can obtain the values shared by the Lua states.
+func (s *Script) sharedGet(L *lua.LState) int {
+	L.Push(s.shared.get(L.CheckString(1)))
+	return 1
+}
+
+// Wrapper so that scripts can share values with the other Lua states.
+func (s *Script) sharedSet(L *lua.LState) int {
+	key := L.CheckString(1)
+
+	value := L.Get(2)
+	switch value.Type() {
+	case lua.LTNil, lua.LTBool, lua.LTNumber, lua.LTString:
+	default:
+		L.ArgError(2, "only nil, boolean, number and string values can be shared")
+		return 0
+	}
+
+	s.shared.set(key, value)
+	return 0
+}
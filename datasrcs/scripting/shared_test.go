@@ -0,0 +1,57 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+func TestSharedValues(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.GetDataSourceConfig("shared").Concurrency = 2
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s, err := newScript(`
+		name="shared"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local n = shared_get("count")
+			if (n == nil) then n = 0 end
+			shared_set("count", n + 1)
+		end
+
+		function horizontal(ctx, domain)
+			shared_set("table", {})
+		end
+	`, "shared.ads", sys)
+	if err != nil {
+		t.Fatalf("Failed to load the script: %v", err)
+	}
+	if err := sys.AddAndStart(s); err != nil {
+		t.Fatalf("Failed to start the script: %v", err)
+	}
+	if n := len(s.all); n != 2 {
+		t.Fatalf("Expected 2 Lua states in the pool, got %d", n)
+	}
+
+	// Each callback is executed by a different Lua state
+	for _, st := range s.all {
+		if err := s.dispatch(st, &requests.DNSRequest{Domain: "owasp.org"}); err != nil {
+			t.Fatalf("The callback failed: %v", err)
+		}
+	}
+	if v := s.shared.get("count"); v.String() != "2" {
+		t.Errorf("The Lua states did not share the value: %v", v)
+	}
+
+	if err := s.dispatch(s.main, &requests.WhoisRequest{Domain: "owasp.org"}); err == nil {
+		t.Errorf("The script shared a table between the Lua states")
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"errors"
 	"os"
 	"regexp"
+	"strings"
 
+	"github.com/OWASP/Amass/v3/resources"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -18,8 +20,7 @@ type contextWrapper struct {
 }
 
 // Converts Go Context to Lua UserData.
-func (s *Script) contextToUserData(ctx context.Context) *lua.LUserData {
-	L := s.luaState
+func contextToUserData(L *lua.LState, ctx context.Context) *lua.LUserData {
 	ud := L.NewUserData()
 
 	ud.Value = &contextWrapper{Ctx: ctx}
@@ -27,6 +28,21 @@ func (s *Script) contextToUserData(ctx context.Context) *lua.LUserData {
 	return ud
 }
 
+// Returns the loader that executes the Lua module source when the module is required.
+func moduleLoader(name, source string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		fn, err := L.Load(strings.NewReader(source), name+resources.ScriptModuleExt)
+		if err != nil {
+			L.RaiseError("failed to load the %s module: %v", name, err)
+			return 0
+		}
+
+		L.Push(fn)
+		L.Call(0, 1)
+		return 1
+	}
+}
+
 func contextExpired(ctx context.Context) bool {
 	select {
 	case <-ctx.Done():
@@ -0,0 +1,72 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/resources"
+)
+
+func TestScriptModules(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Dir = t.TempDir()
+
+	dir := filepath.Join(cfg.Dir, "scripts", "private")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("Failed to create the scripts directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "helpers.lua"), []byte(`
+		local helpers = {}
+		function helpers.label(name) return "lib." .. name end
+		return helpers
+	`), 0600); err != nil {
+		t.Fatalf("Failed to write the module: %v", err)
+	}
+
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s, err := newScript(`
+		local utils = require("utils")
+		local helpers = require("private.helpers")
+
+		name = helpers.label(utils.split("modules.owasp.org", ".")[1])
+		type = "testing"
+	`, "modules.ads", sys)
+	if err != nil {
+		t.Fatalf("Failed to load the script: %v", err)
+	}
+	if s.String() != "lib.modules" {
+		t.Errorf("The script modules were not loaded: %s", s.String())
+	}
+
+	if _, err := newScript(`local missing = require("missing")`, "missing.ads", sys); err == nil {
+		t.Errorf("The script required a module that does not exist")
+	}
+}
+
+func TestDefaultScriptsLoad(t *testing.T) {
+	scripts, err := resources.GetDefaultScripts()
+	if err != nil {
+		t.Fatalf("Failed to obtain the default scripts: %v", err)
+	}
+
+	sys := newMockSystem(config.NewConfig())
+	defer func() { _ = sys.Shutdown() }()
+
+	for _, script := range scripts {
+		s, err := newScript(script, "default.ads", sys)
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		s.Release()
+	}
+}
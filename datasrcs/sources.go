@@ -6,10 +6,13 @@ package datasrcs
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
 	"sort"
 
 	"github.com/OWASP/Amass/v3/config"
 	"github.com/OWASP/Amass/v3/datasrcs/scripting"
+	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/service"
@@ -84,6 +87,54 @@ func genNewNameEvent(ctx context.Context, sys systems.System, srv service.Servic
 	}
 }
 
+// Sends the HTTP request for the data source, unless it has used up its request budget for the run or domain.
+// The response is reported for the credentials used by the request, so the credentials rejected by the API
+// are rotated out, and cred is nil for requests that were not authorized using credentials.
+func requestWebPage(ctx context.Context, sys systems.System, srv service.Service, domain, u string,
+	body io.Reader, hvals map[string]string, cred *config.Credentials) (string, error) {
+	if err := sys.Config().SpendSourceBudget(srv.String(), domain); err != nil {
+		return "", err
+	}
+
+	req := &http.Request{
+		Method: "GET",
+		URL:    u,
+		Header: hvals,
+	}
+	if body != nil {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+
+		req.Method = "POST"
+		req.Body = string(b)
+	}
+
+	resp, err := http.SendRequest(ctx, req)
+	sys.Config().ReportCredentials(srv.String(), cred, resp)
+	if resp == nil {
+		return "", err
+	}
+	return resp.Body, err
+}
+
+// Returns the credentials with an API key that the data source uses for the next request, or nil
+// when the credentials were not provided or all of them were rejected by the API.
+func apiCredentials(sys systems.System, srv service.Service) *config.Credentials {
+	if cred := sys.Config().GetDataSourceConfig(srv.String()).GetCredentials(); cred != nil && cred.Key != "" {
+		return cred
+	}
+	return nil
+}
+
+// Returns true when the data source has credentials with an API key available for its requests.
+func hasAPICredentials(sys systems.System, srv service.Service) bool {
+	cred := sys.Config().GetDataSourceConfig(srv.String()).PeekCredentials()
+
+	return cred != nil && cred.Key != ""
+}
+
 func numRateLimitChecks(srv service.Service, num int) {
 	for i := 0; i < num; i++ {
 		srv.CheckRateLimit()
@@ -6,10 +6,9 @@ package datasrcs
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
 
 	"github.com/OWASP/Amass/v3/config"
 	"github.com/OWASP/Amass/v3/net/http"
@@ -17,17 +16,20 @@ import (
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/service"
 	"github.com/dghubble/go-twitter/twitter"
-	"golang.org/x/oauth2"
 )
 
+// The endpoint used to obtain the bearer tokens when another has not been configured.
+const twitterTokenURL = "https://api.twitter.com/oauth2/token"
+
 // Twitter is the Service that handles access to the Twitter data source.
 type Twitter struct {
 	service.BaseService
 
 	SourceType string
 	sys        systems.System
-	creds      *config.Credentials
-	client     *twitter.Client
+	lock       sync.Mutex
+	// The clients are kept for each set of credentials, so the bearer tokens are reused
+	clients map[string]*twitter.Client
 }
 
 // NewTwitter returns he object initialized, but not yet started.
@@ -35,6 +37,7 @@ func NewTwitter(sys systems.System) *Twitter {
 	t := &Twitter{
 		SourceType: requests.API,
 		sys:        sys,
+		clients:    make(map[string]*twitter.Client),
 	}
 
 	go t.requests()
@@ -49,19 +52,10 @@ func (t *Twitter) Description() string {
 
 // OnStart implements the Service interface.
 func (t *Twitter) OnStart() error {
-	t.creds = t.sys.Config().GetDataSourceConfig(t.String()).GetCredentials()
+	creds := t.sys.Config().GetDataSourceConfig(t.String()).PeekCredentials()
 
-	if t.creds == nil || t.creds.Key == "" || t.creds.Secret == "" {
+	if creds == nil || creds.Key == "" || creds.Secret == "" {
 		t.sys.Config().Log.Printf("%s: API key data was not provided", t.String())
-	} else {
-		if bearer, err := t.getBearerToken(); err == nil {
-			config := &oauth2.Config{}
-			token := &oauth2.Token{AccessToken: bearer}
-			// OAuth2 http.Client will automatically authorize Requests
-			httpClient := config.Client(context.Background(), token)
-			// Twitter client
-			t.client = twitter.NewClient(httpClient)
-		}
 	}
 
 	t.SetRateLimit(1)
@@ -70,7 +64,7 @@ func (t *Twitter) OnStart() error {
 
 // CheckConfig implements the Service interface.
 func (t *Twitter) checkConfig() error {
-	creds := t.sys.Config().GetDataSourceConfig(t.String()).GetCredentials()
+	creds := t.sys.Config().GetDataSourceConfig(t.String()).PeekCredentials()
 
 	if creds == nil || creds.Key == "" || creds.Secret == "" {
 		estr := fmt.Sprintf("%s: check callback failed for the configuration", t.String())
@@ -87,10 +81,12 @@ func (t *Twitter) requests() {
 		case <-t.Done():
 			return
 		case in := <-t.Input():
+			// The errors are reported with the domain of the request
+			ctx := config.WithSourceDomain(context.TODO(), requests.RequestDomain(in))
 			switch req := in.(type) {
 			case *requests.DNSRequest:
 				t.CheckRateLimit()
-				t.dnsRequest(context.TODO(), req)
+				t.dnsRequest(ctx, req)
 			}
 		}
 	}
@@ -98,7 +94,16 @@ func (t *Twitter) requests() {
 
 func (t *Twitter) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 	re := t.sys.Config().DomainRegex(req.Domain)
-	if t.client == nil || re == nil {
+	if re == nil {
+		return
+	}
+
+	cred := t.sys.Config().GetDataSourceConfig(t.String()).GetCredentials()
+	if cred == nil || cred.Key == "" || cred.Secret == "" {
+		return
+	}
+
+	if t.sys.Config().SpendSourceBudget(t.String(), req.Domain) != nil {
 		return
 	}
 
@@ -109,9 +114,15 @@ func (t *Twitter) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 		Query: req.Domain,
 		Count: 100,
 	}
-	search, _, err := t.client.Search.Tweets(searchParams)
+	search, resp, err := t.client(cred).Search.Tweets(searchParams)
+	if resp != nil {
+		t.sys.Config().ReportCredentials(t.String(), cred, &http.Response{
+			Status: resp.StatusCode,
+			Header: resp.Header,
+		})
+	}
 	if err != nil {
-		t.sys.Config().Log.Printf("%s: %v", t.String(), err)
+		t.sys.Config().SourceError(ctx, t.String(), err)
 		return
 	}
 
@@ -129,26 +140,25 @@ func (t *Twitter) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 	}
 }
 
-func (t *Twitter) getBearerToken() (string, error) {
-	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded;charset=UTF-8"}
-	page, err := http.RequestWebPage(context.Background(), "https://api.twitter.com/oauth2/token",
-		strings.NewReader("grant_type=client_credentials"), headers,
-		&http.BasicAuth{
-			Username: t.creds.Key,
-			Password: t.creds.Secret,
-		})
-	if err != nil {
-		return "", fmt.Errorf("token request failed: %+v", err)
-	}
+// Returns the client that sends requests using the credentials.
+func (t *Twitter) client(cred *config.Credentials) *twitter.Client {
+	t.lock.Lock()
+	defer t.lock.Unlock()
 
-	var v struct {
-		AccessToken string `json:"access_token"`
-	}
-	if err := json.Unmarshal([]byte(page), &v); err != nil {
-		return "", fmt.Errorf("error parsing json in token response: %+v", err)
+	if c, found := t.clients[cred.Name]; found {
+		return c
 	}
-	if v.AccessToken == "" {
-		return "", fmt.Errorf("token response does not have access_token")
+
+	cc := t.sys.Config().GetDataSourceConfig(t.String()).ClientCredentials(cred)
+	if cc == nil {
+		cc = &http.ClientCredentials{
+			TokenURL:     twitterTokenURL,
+			ClientID:     cred.Key,
+			ClientSecret: cred.Secret,
+		}
 	}
-	return v.AccessToken, nil
+	// The OAuth2 http.Client will automatically authorize requests and refresh the bearer tokens
+	c := twitter.NewClient(http.OAuth2Client(context.Background(), cc))
+	t.clients[cred.Name] = c
+	return c
 }
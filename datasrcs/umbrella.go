@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"github.com/OWASP/Amass/v3/config"
-	"github.com/OWASP/Amass/v3/net/http"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/systems"
 	"github.com/caffix/resolve"
@@ -28,7 +27,6 @@ type Umbrella struct {
 
 	SourceType string
 	sys        systems.System
-	creds      *config.Credentials
 }
 
 // NewUmbrella returns he object initialized, but not yet started.
@@ -50,9 +48,7 @@ func (u *Umbrella) Description() string {
 
 // OnStart implements the Service interface.
 func (u *Umbrella) OnStart() error {
-	u.creds = u.sys.Config().GetDataSourceConfig(u.String()).GetCredentials()
-
-	if u.creds == nil || u.creds.Key == "" {
+	if !hasAPICredentials(u.sys, u) {
 		u.sys.Config().Log.Printf("%s: API key data was not provided", u.String())
 	}
 
@@ -62,9 +58,7 @@ func (u *Umbrella) OnStart() error {
 
 // CheckConfig implements the Service interface.
 func (u *Umbrella) checkConfig() error {
-	creds := u.sys.Config().GetDataSourceConfig(u.String()).GetCredentials()
-
-	if creds == nil || creds.Key == "" {
+	if !hasAPICredentials(u.sys, u) {
 		estr := fmt.Sprintf("%s: check callback failed for the configuration", u.String())
 		u.sys.Config().Log.Print(estr)
 		return errors.New(estr)
@@ -79,26 +73,28 @@ func (u *Umbrella) requests() {
 		case <-u.Done():
 			return
 		case in := <-u.Input():
+			// The errors are reported with the domain of the request
+			ctx := config.WithSourceDomain(context.TODO(), requests.RequestDomain(in))
 			switch req := in.(type) {
 			case *requests.DNSRequest:
 				u.CheckRateLimit()
-				u.dnsRequest(context.TODO(), req)
+				u.dnsRequest(ctx, req)
 			case *requests.AddrRequest:
 				u.CheckRateLimit()
-				u.addrRequest(context.TODO(), req)
+				u.addrRequest(ctx, req)
 			case *requests.ASNRequest:
 				u.CheckRateLimit()
-				u.asnRequest(context.TODO(), req)
+				u.asnRequest(ctx, req)
 			case *requests.WhoisRequest:
 				u.CheckRateLimit()
-				u.whoisRequest(context.TODO(), req)
+				u.whoisRequest(ctx, req)
 			}
 		}
 	}
 }
 
 func (u *Umbrella) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
-	if u.creds == nil || u.creds.Key == "" {
+	if !hasAPICredentials(u.sys, u) {
 		return
 	}
 	if !u.sys.Config().IsDomainInScope(req.Domain) {
@@ -107,11 +103,10 @@ func (u *Umbrella) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 
 	u.sys.Config().Log.Printf("Querying %s for %s subdomains", u.String(), req.Domain)
 
-	headers := u.restHeaders()
 	url := u.restDNSURL(req.Domain)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := u.requestPage(ctx, req.Domain, url)
 	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+		u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	}
 	// Extract the subdomain names from the REST API results
@@ -129,18 +124,17 @@ func (u *Umbrella) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 }
 
 func (u *Umbrella) addrRequest(ctx context.Context, req *requests.AddrRequest) {
-	if u.creds == nil || u.creds.Key == "" {
+	if !hasAPICredentials(u.sys, u) {
 		return
 	}
 	if req.Address == "" {
 		return
 	}
 
-	headers := u.restHeaders()
 	url := u.restAddrURL(req.Address)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := u.requestPage(ctx, req.Domain, url)
 	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+		u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	}
 	// Extract the subdomain names from the REST API results
@@ -161,7 +155,7 @@ func (u *Umbrella) addrRequest(ctx context.Context, req *requests.AddrRequest) {
 }
 
 func (u *Umbrella) asnRequest(ctx context.Context, req *requests.ASNRequest) {
-	if u.creds == nil || u.creds.Key == "" {
+	if !hasAPICredentials(u.sys, u) {
 		return
 	}
 	if req.Address == "" && req.ASN == 0 {
@@ -175,11 +169,10 @@ func (u *Umbrella) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 }
 
 func (u *Umbrella) executeASNAddrQuery(ctx context.Context, req *requests.ASNRequest) {
-	headers := u.restHeaders()
 	url := u.restAddrToASNURL(req.Address)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := u.requestPage(ctx, "", url)
 	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+		u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	}
 	// Extract the AS information from the REST API results
@@ -233,11 +226,10 @@ func (u *Umbrella) executeASNAddrQuery(ctx context.Context, req *requests.ASNReq
 }
 
 func (u *Umbrella) executeASNQuery(ctx context.Context, req *requests.ASNRequest) {
-	headers := u.restHeaders()
 	url := u.restASNToCIDRsURL(req.ASN)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+	page, err := u.requestPage(ctx, "", url)
 	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+		u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", url, err))
 		return
 	}
 	// Extract the netblock information from the REST API results
@@ -329,19 +321,18 @@ func (u *Umbrella) collateEmails(ctx context.Context, record *whoisRecord) []str
 
 func (u *Umbrella) queryWhois(ctx context.Context, domain string) *whoisRecord {
 	var whois whoisRecord
-	headers := u.restHeaders()
 	whoisURL := u.whoisRecordURL(domain)
 
 	u.CheckRateLimit()
-	record, err := http.RequestWebPage(ctx, whoisURL, nil, headers, nil)
+	record, err := u.requestPage(ctx, domain, whoisURL)
 	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), whoisURL, err)
+		u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", whoisURL, err))
 		return nil
 	}
 
 	err = json.Unmarshal([]byte(record), &whois)
 	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), whoisURL, err)
+		u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", whoisURL, err))
 		return nil
 	}
 	return &whois
@@ -351,21 +342,20 @@ func (u *Umbrella) queryReverseWhois(ctx context.Context, apiURL string) []strin
 	domains := stringset.New()
 	defer domains.Close()
 
-	headers := u.restHeaders()
 	var whois map[string]rWhoisResponse
 	// Umbrella provides data in 500 piece chunks
 	for count, more := 0, true; more; count = count + 500 {
 		u.CheckRateLimit()
 		fullAPIURL := fmt.Sprintf("%s&offset=%d", apiURL, count)
-		record, err := http.RequestWebPage(ctx, fullAPIURL, nil, headers, nil)
+		record, err := u.requestPage(ctx, "", fullAPIURL)
 		if err != nil {
-			u.sys.Config().Log.Printf("%s: %s: %v", u.String(), apiURL, err)
+			u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", apiURL, err))
 			return domains.Slice()
 		}
 
 		err = json.Unmarshal([]byte(record), &whois)
 		if err != nil {
-			u.sys.Config().Log.Printf("%s: %s: %v", u.String(), apiURL, err)
+			u.sys.Config().SourceError(ctx, u.String(), fmt.Errorf("%s: %v", apiURL, err))
 			return domains.Slice()
 		}
 
@@ -394,7 +384,7 @@ func (u *Umbrella) validateScope(ctx context.Context, input string) bool {
 }
 
 func (u *Umbrella) whoisRequest(ctx context.Context, req *requests.WhoisRequest) {
-	if u.creds == nil || u.creds.Key == "" {
+	if !hasAPICredentials(u.sys, u) {
 		return
 	}
 	if !u.sys.Config().IsDomainInScope(req.Domain) {
@@ -444,14 +434,18 @@ func (u *Umbrella) whoisRequest(ctx context.Context, req *requests.WhoisRequest)
 	}
 }
 
-func (u *Umbrella) restHeaders() map[string]string {
-	headers := map[string]string{"Content-Type": "application/json"}
-
-	if u.creds != nil && u.creds.Key != "" {
-		headers["Authorization"] = "Bearer " + u.creds.Key
+// Sends the request authorized by the next credentials of the data source.
+func (u *Umbrella) requestPage(ctx context.Context, domain, url string) (string, error) {
+	cred := apiCredentials(u.sys, u)
+	if cred == nil {
+		return "", errors.New("the API key is not available")
 	}
 
-	return headers
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer " + cred.Key,
+	}
+	return requestWebPage(ctx, u.sys, u, domain, url, nil, headers, cred)
 }
 
 func (u *Umbrella) whoisBaseURL() string {
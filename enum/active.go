@@ -252,8 +252,21 @@ func (a *activeTask) zoneWalk(ctx context.Context, req *requests.ZoneXFRRequest,
 	defer r.Stop()
 
 	names, err := r.NsecTraversal(ctx, req.Name)
-	if err != nil {
-		a.enum.Config.Log.Printf("DNS: Zone Walk failed: %s: %v", req.Name, err)
+	if err != nil || len(names) == 0 {
+		// Most signed zones use NSEC3, so the hashes are collected and cracked offline
+		if !a.enum.nsec3.claim(req.Name) {
+			return
+		}
+
+		chain, nerr := collectNSEC3(ctx, req.Name, r.QueryBlocking)
+		if nerr != nil {
+			a.enum.nsec3.release(req.Name)
+			a.enum.Config.Log.Printf("DNS: Zone Walk failed: %s: %v", req.Name, nerr)
+			return
+		}
+
+		a.enum.Config.Log.Printf("DNS: Collected %d NSEC3 hashes for %s", len(chain.next), req.Name)
+		a.enum.nsec3.addChain(chain)
 		return
 	}
 
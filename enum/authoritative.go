@@ -0,0 +1,570 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	amassnet "github.com/OWASP/Amass/v3/net"
+	"github.com/caffix/resolve"
+	"github.com/miekg/dns"
+)
+
+const (
+	// The maximum number of referrals followed to reach the zone containing a name
+	maxAuthReferrals int = 8
+	// The consecutive failures before a nameserver is skipped for a while
+	maxAuthServerFailures int = 3
+	// The time a nameserver is skipped after failing to respond
+	authServerCooldown time.Duration = 30 * time.Second
+	// The slowest pace the queries to a nameserver are backed off to
+	maxAuthServerInterval time.Duration = 2 * time.Second
+	authQueryTimeout      time.Duration = 2 * time.Second
+)
+
+// authZone contains the authoritative nameservers of a zone.
+type authZone struct {
+	// Held while the addresses of the nameservers are obtained
+	resolving sync.Mutex
+	name      string
+	hosts     []string
+	// The addresses provided as glue by the parent zone
+	glue  map[string][]string
+	addrs []string
+	lame  map[string]struct{}
+	idx   int
+}
+
+// authServer paces the queries sent to an authoritative nameserver and backs off when it stops responding.
+type authServer struct {
+	sync.Mutex
+	interval time.Duration
+	delay    time.Duration
+	next     time.Time
+	failures int
+	until    time.Time
+}
+
+func newAuthServer(qps int) *authServer {
+	if qps <= 0 {
+		qps = 1
+	}
+
+	interval := time.Second / time.Duration(qps)
+	return &authServer{
+		interval: interval,
+		delay:    interval,
+	}
+}
+
+// Returns false while the server is skipped after too many consecutive failures.
+func (s *authServer) available() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return time.Now().After(s.until)
+}
+
+func (s *authServer) wait(ctx context.Context) error {
+	s.Lock()
+	now := time.Now()
+	if s.next.Before(now) {
+		s.next = now
+	}
+	delay := s.next.Sub(now)
+	s.next = s.next.Add(s.delay)
+	s.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+	}
+	return nil
+}
+
+// The pace recovers gradually after the server responds again.
+func (s *authServer) success() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.failures = 0
+	if s.delay /= 2; s.delay < s.interval {
+		s.delay = s.interval
+	}
+}
+
+// Slows down the queries, since servers often drop queries when limiting the rate of responses.
+func (s *authServer) failure() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.delay *= 2; s.delay > maxAuthServerInterval {
+		s.delay = maxAuthServerInterval
+	}
+
+	s.failures++
+	if s.failures >= maxAuthServerFailures {
+		s.failures = 0
+		s.until = time.Now().Add(authServerCooldown)
+	}
+}
+
+// authResolver sends the queries of the trusted DNS task directly to the authoritative nameservers
+// of the zones containing the names, so the answers cannot come from the caches of recursive resolvers.
+type authResolver struct {
+	sync.Mutex
+	enum  *Enumeration
+	zones map[string]*authZone
+	// The names already checked for NS records in the graph
+	checked map[string]struct{}
+	servers map[string]*authServer
+	hosts   map[string][]string
+	// Sends the query to the address of an authoritative nameserver
+	exchange func(ctx context.Context, msg *dns.Msg, addr string) (*dns.Msg, error)
+	// Obtains the records used to locate the nameservers through the trusted resolvers
+	lookup func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+}
+
+func newAuthResolver(e *Enumeration) *authResolver {
+	a := &authResolver{
+		enum:     e,
+		zones:    make(map[string]*authZone),
+		checked:  make(map[string]struct{}),
+		servers:  make(map[string]*authServer),
+		hosts:    make(map[string][]string),
+		exchange: authExchange,
+	}
+
+	a.lookup = a.trustedQuery
+	return a
+}
+
+func (a *authResolver) trustedQuery(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	msg := resolve.QueryMsg(name, qtype)
+
+	for num := 0; num < maxDNSQueryAttempts; num++ {
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("context expired")
+		default:
+		}
+
+		resp, err := a.enum.Sys.TrustedResolvers().QueryBlocking(ctx, msg)
+		if err != nil {
+			continue
+		}
+		if resp.Rcode == dns.RcodeSuccess || resp.Rcode == dns.RcodeNameError {
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to obtain the %s records for %s", dns.TypeToString[qtype], name)
+}
+
+// query sends the response from the authoritative nameservers on the channel, or a server
+// failure when none of the nameservers answered authoritatively.
+func (a *authResolver) query(ctx context.Context, msg *dns.Msg, ch chan *dns.Msg) {
+	resp, err := a.resolve(ctx, msg)
+	if err != nil {
+		resp = new(dns.Msg)
+		resp.SetRcode(msg, dns.RcodeServerFailure)
+	}
+
+	resp.Id = msg.Id
+	ch <- resp
+}
+
+func (a *authResolver) resolve(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	name := strings.ToLower(resolve.RemoveLastDot(msg.Question[0].Name))
+
+	zone, err := a.zoneOf(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < maxAuthReferrals; i++ {
+		resp, next, err := a.queryZone(ctx, zone, name, msg)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return resp, nil
+		}
+		zone = next
+	}
+	return nil, fmt.Errorf("too many referrals were followed while resolving %s", name)
+}
+
+// Sends the query to the nameservers of the zone until one of them answers authoritatively
+// or refers the query to the nameservers of a child zone.
+func (a *authResolver) queryZone(ctx context.Context, z *authZone, name string, msg *dns.Msg) (*dns.Msg, *authZone, error) {
+	m := msg.Copy()
+	m.RecursionDesired = false
+
+	for _, addr := range a.serverAddrs(ctx, z) {
+		srv := a.server(addr)
+		if !srv.available() {
+			continue
+		}
+		if err := srv.wait(ctx); err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := a.exchange(ctx, m, addr)
+		if err != nil {
+			srv.failure()
+			continue
+		}
+
+		if resp.Authoritative && (resp.Rcode == dns.RcodeSuccess || resp.Rcode == dns.RcodeNameError) {
+			srv.success()
+			return resp, nil, nil
+		}
+		if resp.Rcode == dns.RcodeSuccess {
+			srv.success()
+			if next := a.referral(z, name, resp); next != nil {
+				return nil, next, nil
+			}
+			// The server does not answer authoritatively for the zone
+			a.markLame(z, addr)
+			continue
+		}
+		// Servers often refuse or fail the queries while limiting the rate of responses,
+		// so the queries are backed off without giving up on the server
+		srv.failure()
+	}
+	return nil, nil, fmt.Errorf("none of the nameservers for %s answered authoritatively", z.name)
+}
+
+// Returns the child zone when the response delegates the name to other nameservers.
+func (a *authResolver) referral(z *authZone, name string, resp *dns.Msg) *authZone {
+	if len(resp.Answer) > 0 {
+		return nil
+	}
+
+	var owner string
+	var hosts []string
+	for _, rr := range resp.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		child := strings.ToLower(resolve.RemoveLastDot(ns.Hdr.Name))
+		// Only referrals down the tree toward the name are followed
+		if child == z.name || !strings.HasSuffix(child, "."+z.name) ||
+			(name != child && !strings.HasSuffix(name, "."+child)) {
+			continue
+		}
+		if owner != "" && owner != child {
+			continue
+		}
+
+		owner = child
+		hosts = append(hosts, strings.ToLower(resolve.RemoveLastDot(ns.Ns)))
+	}
+	if owner == "" {
+		return nil
+	}
+
+	glue := make(map[string][]string)
+	for _, rr := range resp.Extra {
+		host := strings.ToLower(resolve.RemoveLastDot(rr.Header().Name))
+		// Glue outside the zone of the server providing it cannot be trusted
+		if !strings.HasSuffix(host, "."+z.name) {
+			continue
+		}
+
+		switch v := rr.(type) {
+		case *dns.A:
+			glue[host] = append(glue[host], v.A.String())
+		case *dns.AAAA:
+			glue[host] = append(glue[host], v.AAAA.String())
+		}
+	}
+	return a.addZone(owner, hosts, glue)
+}
+
+func (a *authResolver) addZone(name string, hosts []string, glue map[string][]string) *authZone {
+	a.Lock()
+	defer a.Unlock()
+
+	if z, found := a.zones[name]; found {
+		return z
+	}
+
+	z := &authZone{
+		name:  name,
+		hosts: hosts,
+		glue:  glue,
+		lame:  make(map[string]struct{}),
+	}
+	a.zones[name] = z
+	return z
+}
+
+// zoneOf returns the closest zone to the name with known nameservers. The NS records stored in
+// the graph are used before asking the trusted resolvers, and the referrals lead to the child zones.
+func (a *authResolver) zoneOf(ctx context.Context, name string) (*authZone, error) {
+	domain := a.enum.Config.WhichDomain(name)
+	if domain == "" {
+		return nil, fmt.Errorf("%s is not within the enumeration domains", name)
+	}
+
+	for n := name; ; n = n[strings.Index(n, ".")+1:] {
+		a.Lock()
+		z, found := a.zones[n]
+		_, checked := a.checked[n]
+		a.checked[n] = struct{}{}
+		a.Unlock()
+
+		if found {
+			return z, nil
+		}
+		if !checked {
+			if hosts := a.graphNS(ctx, n); len(hosts) > 0 {
+				return a.addZone(n, hosts, nil), nil
+			}
+		}
+		if n == domain || !strings.Contains(n, ".") {
+			break
+		}
+	}
+	return a.lookupZone(ctx, domain)
+}
+
+// Returns the nameservers of the name stored in the graph by previous validations.
+func (a *authResolver) graphNS(ctx context.Context, name string) []string {
+	if a.enum.graph == nil {
+		return nil
+	}
+
+	node, err := a.enum.graph.ReadNode(ctx, name, "fqdn")
+	if err != nil {
+		return nil
+	}
+
+	edges, err := a.enum.graph.ReadOutEdges(ctx, node, "ns_record")
+	if err != nil {
+		return nil
+	}
+
+	var hosts []string
+	for _, edge := range edges {
+		hosts = append(hosts, a.enum.graph.NodeToID(edge.To))
+	}
+	return hosts
+}
+
+// Asks the trusted resolvers for the nameservers of the domain, or of the zone containing it.
+func (a *authResolver) lookupZone(ctx context.Context, domain string) (*authZone, error) {
+	zone := domain
+
+	hosts := a.lookupNS(ctx, zone)
+	if len(hosts) == 0 {
+		// The domain is not the apex of a zone, so the SOA record identifies the zone containing it
+		if resp, err := a.lookup(ctx, domain, dns.TypeSOA); err == nil {
+			for _, rr := range append(resp.Answer, resp.Ns...) {
+				if soa, ok := rr.(*dns.SOA); ok {
+					zone = strings.ToLower(resolve.RemoveLastDot(soa.Hdr.Name))
+					break
+				}
+			}
+		}
+		if zone != domain {
+			hosts = a.lookupNS(ctx, zone)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("failed to find the nameservers for %s", domain)
+	}
+	return a.addZone(zone, hosts, nil), nil
+}
+
+func (a *authResolver) lookupNS(ctx context.Context, zone string) []string {
+	resp, err := a.lookup(ctx, zone, dns.TypeNS)
+	if err != nil {
+		return nil
+	}
+
+	var hosts []string
+	for _, rr := range resp.Answer {
+		if ns, ok := rr.(*dns.NS); ok && strings.EqualFold(resolve.RemoveLastDot(ns.Hdr.Name), zone) {
+			hosts = append(hosts, strings.ToLower(resolve.RemoveLastDot(ns.Ns)))
+		}
+	}
+	return hosts
+}
+
+// Returns the addresses of the nameservers for the zone that are not lame, starting
+// with a different nameserver each time to spread the queries across them.
+func (a *authResolver) serverAddrs(ctx context.Context, z *authZone) []string {
+	a.Lock()
+	known := len(z.addrs) > 0
+	a.Unlock()
+	if !known {
+		a.resolveServers(ctx, z)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	n := len(z.addrs)
+	var addrs []string
+	for i := 0; i < n; i++ {
+		if addr := z.addrs[(z.idx+i)%n]; !a.isLame(z, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	z.idx++
+	return addrs
+}
+
+// Obtains the addresses of the nameservers for the zone. The addresses are obtained again by the
+// following queries while none are known, since the lookups of the nameservers can fail.
+func (a *authResolver) resolveServers(ctx context.Context, z *authZone) {
+	z.resolving.Lock()
+	defer z.resolving.Unlock()
+
+	a.Lock()
+	known := len(z.addrs) > 0
+	a.Unlock()
+	if known {
+		return
+	}
+
+	var addrs []string
+	for _, host := range z.hosts {
+		ips := z.glue[host]
+		if len(ips) == 0 {
+			ips = a.hostAddrs(ctx, host)
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip, "53"))
+		}
+	}
+
+	a.Lock()
+	z.addrs = addrs
+	a.Unlock()
+}
+
+// Resolves the nameserver through the trusted resolvers, since the parent zone did not provide glue.
+// Only the addresses obtained are kept, so the nameservers that failed to resolve are looked up again.
+func (a *authResolver) hostAddrs(ctx context.Context, host string) []string {
+	a.Lock()
+	ips, found := a.hosts[host]
+	a.Unlock()
+	if found {
+		return ips
+	}
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		resp, err := a.lookup(ctx, host, qtype)
+		if err != nil {
+			continue
+		}
+
+		for _, rr := range resp.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				ips = append(ips, v.A.String())
+			case *dns.AAAA:
+				ips = append(ips, v.AAAA.String())
+			}
+		}
+		// The IPv6 addresses are only used when the nameserver has no IPv4 address
+		if len(ips) > 0 {
+			break
+		}
+	}
+
+	if len(ips) > 0 {
+		a.Lock()
+		a.hosts[host] = ips
+		a.Unlock()
+	}
+	return ips
+}
+
+func (a *authResolver) server(addr string) *authServer {
+	a.Lock()
+	defer a.Unlock()
+
+	srv, found := a.servers[addr]
+	if !found {
+		srv = newAuthServer(a.enum.Config.AuthoritativeQPS)
+		a.servers[addr] = srv
+	}
+	return srv
+}
+
+// The lame nameservers are no longer queried for the zone.
+func (a *authResolver) markLame(z *authZone, addr string) {
+	a.Lock()
+	defer a.Unlock()
+
+	z.lame[addr] = struct{}{}
+}
+
+func (a *authResolver) isLame(z *authZone, addr string) bool {
+	_, found := z.lame[addr]
+	return found
+}
+
+// authExchange queries the nameserver without recursion and uses TCP when the response is truncated.
+func authExchange(ctx context.Context, msg *dns.Msg, addr string) (*dns.Msg, error) {
+	resp, err := authExchangeOver(ctx, "udp", msg, addr)
+	if err == nil && resp.Truncated {
+		// Servers limiting the rate of responses also truncate them to have the query sent over TCP
+		resp, err = authExchangeOver(ctx, "tcp", msg, addr)
+	}
+	return resp, err
+}
+
+func authExchangeOver(ctx context.Context, network string, msg *dns.Msg, addr string) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(ctx, authQueryTimeout)
+	defer cancel()
+
+	conn, err := amassnet.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	co := &dns.Conn{Conn: conn, UDPSize: dns.DefaultMsgSize}
+	if err := co.WriteMsg(msg); err != nil {
+		return nil, err
+	}
+
+	for {
+		resp, err := co.ReadMsg()
+		if err != nil {
+			return nil, err
+		}
+		// Responses that do not match the query are ignored
+		if resp.Id == msg.Id && len(resp.Question) > 0 &&
+			strings.EqualFold(resp.Question[0].Name, msg.Question[0].Name) {
+			return resp, nil
+		}
+	}
+}
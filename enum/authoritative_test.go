@@ -0,0 +1,220 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/caffix/netmap"
+	"github.com/miekg/dns"
+)
+
+func authAnswer(msg *dns.Msg, addr string) *dns.Msg {
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	resp.Authoritative = true
+	resp.Answer = append(resp.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: msg.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+		A:   net.ParseIP(addr),
+	})
+	return resp
+}
+
+func TestAuthoritativeResolution(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomains("owasp.org", "example.org")
+	cfg.AuthoritativeQPS = 100
+
+	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer graph.Close()
+
+	ctx := context.Background()
+	for _, ns := range []string{"ns1.owasp.org", "ns2.owasp.org", "ns3.owasp.org"} {
+		if err := graph.UpsertNS(ctx, "owasp.org", ns, "DNS", cfg.UUID.String()); err != nil {
+			t.Fatalf("Failed to insert the NS record: %v", err)
+		}
+	}
+
+	a := newAuthResolver(&Enumeration{Config: cfg, graph: graph})
+	a.lookup = func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetQuestion(dns.Fqdn(name), qtype)
+
+		switch {
+		case name == "example.org" && qtype == dns.TypeNS:
+			resp.Answer = append(resp.Answer, &dns.NS{
+				Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 300},
+				Ns:  "ns.example.net.",
+			})
+			return resp, nil
+		case qtype == dns.TypeA:
+			addrs := map[string]string{
+				"ns1.owasp.org":  "192.0.2.1",
+				"ns2.owasp.org":  "192.0.2.2",
+				"ns3.owasp.org":  "192.0.2.4",
+				"ns.example.net": "192.0.2.10",
+			}
+			if addr, found := addrs[name]; found {
+				return authAnswer(resp, addr), nil
+			}
+		}
+		resp.Rcode = dns.RcodeNameError
+		return resp, nil
+	}
+
+	var lock sync.Mutex
+	queries := make(map[string]int)
+	a.exchange = func(ctx context.Context, msg *dns.Msg, addr string) (*dns.Msg, error) {
+		lock.Lock()
+		queries[addr]++
+		lock.Unlock()
+
+		if msg.RecursionDesired {
+			t.Errorf("Recursion was requested from the authoritative nameserver %s", addr)
+		}
+
+		name := msg.Question[0].Name
+		resp := new(dns.Msg)
+		resp.SetReply(msg)
+		switch addr {
+		case "192.0.2.1:53":
+			// This nameserver is a lame delegation
+		case "192.0.2.4:53":
+			// This nameserver limits the rate of responses
+			resp.Rcode = dns.RcodeRefused
+		case "192.0.2.2:53":
+			if dns.IsSubDomain("dev.owasp.org.", name) {
+				resp.Ns = append(resp.Ns, &dns.NS{
+					Hdr: dns.RR_Header{Name: "dev.owasp.org.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 300},
+					Ns:  "ns.dev.owasp.org.",
+				})
+				resp.Extra = append(resp.Extra, &dns.A{
+					Hdr: dns.RR_Header{Name: "ns.dev.owasp.org.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+					A:   net.ParseIP("192.0.2.3"),
+				})
+				return resp, nil
+			}
+			if name == "www.owasp.org." {
+				return authAnswer(msg, "192.0.2.80"), nil
+			}
+			resp.Authoritative = true
+			resp.Rcode = dns.RcodeNameError
+		case "192.0.2.3:53":
+			return authAnswer(msg, "192.0.2.81"), nil
+		case "192.0.2.10:53":
+			return authAnswer(msg, "192.0.2.82"), nil
+		default:
+			return nil, errors.New("timeout")
+		}
+		return resp, nil
+	}
+
+	tests := []struct {
+		name     string
+		rcode    int
+		expected string
+	}{
+		{"www.owasp.org", dns.RcodeSuccess, "192.0.2.80"},
+		{"api.dev.owasp.org", dns.RcodeSuccess, "192.0.2.81"},
+		{"www.example.org", dns.RcodeSuccess, "192.0.2.82"},
+		{"mail.owasp.org", dns.RcodeNameError, ""},
+		{"owasp.org", dns.RcodeNameError, ""},
+		{"www.google.com", dns.RcodeServerFailure, ""},
+	}
+
+	ch := make(chan *dns.Msg, 1)
+	for _, tt := range tests {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(tt.name), dns.TypeA)
+		a.query(ctx, msg, ch)
+
+		resp := <-ch
+		if resp.Id != msg.Id || resp.Rcode != tt.rcode {
+			t.Errorf("The query for %s returned the wrong response: %v", tt.name, resp)
+			continue
+		}
+		if tt.expected != "" && (len(resp.Answer) != 1 || resp.Answer[0].(*dns.A).A.String() != tt.expected) {
+			t.Errorf("The query for %s returned the wrong answer: %v", tt.name, resp.Answer)
+		}
+	}
+
+	if n := queries["192.0.2.1:53"]; n != 1 {
+		t.Errorf("The lame nameserver was queried %d times", n)
+	}
+	if n := queries["192.0.2.3:53"]; n != 1 {
+		t.Errorf("The nameserver of the delegated zone was queried %d times", n)
+	}
+
+	z := a.zones["owasp.org"]
+	if n := queries["192.0.2.4:53"]; n == 0 || a.isLame(z, "192.0.2.4:53") {
+		t.Errorf("The nameserver refusing %d queries was marked lame", n)
+	}
+	if srv := a.server("192.0.2.4:53"); srv.delay <= srv.interval {
+		t.Errorf("The queries to the nameserver refusing them were not backed off")
+	}
+}
+
+func TestAuthServerAddrsRetry(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	a := newAuthResolver(&Enumeration{Config: cfg})
+	var failing, empty bool
+	a.lookup = func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		if failing {
+			return nil, errors.New("timeout")
+		}
+
+		resp := new(dns.Msg)
+		resp.SetQuestion(dns.Fqdn(name), qtype)
+		if empty || qtype != dns.TypeA {
+			return resp, nil
+		}
+		return authAnswer(resp, "192.0.2.1"), nil
+	}
+
+	ctx := context.Background()
+	z := a.addZone("owasp.org", []string{"ns1.owasp.org"}, nil)
+	// The failed and empty lookups of the nameserver are not kept for the following queries
+	for _, f := range []*bool{&failing, &empty} {
+		*f = true
+		if addrs := a.serverAddrs(ctx, z); len(addrs) != 0 {
+			t.Errorf("Unexpected nameserver addresses: %v", addrs)
+		}
+		*f = false
+	}
+	if addrs := a.serverAddrs(ctx, z); len(addrs) != 1 || addrs[0] != "192.0.2.1:53" {
+		t.Errorf("The nameserver was not resolved again after the lookups failed: %v", addrs)
+	}
+}
+
+func TestAuthServerBackoff(t *testing.T) {
+	srv := newAuthServer(10)
+
+	for i := 0; i < maxAuthServerFailures; i++ {
+		if !srv.available() {
+			t.Errorf("The nameserver was skipped after %d failures", i)
+		}
+		srv.failure()
+	}
+	if srv.available() {
+		t.Errorf("The nameserver was not skipped after failing %d times", maxAuthServerFailures)
+	}
+	if srv.delay <= srv.interval {
+		t.Errorf("The queries to the failing nameserver were not slowed down")
+	}
+
+	for i := 0; i < maxAuthServerFailures; i++ {
+		srv.success()
+	}
+	if srv.delay != srv.interval {
+		t.Errorf("The pace of the queries did not recover after the nameserver responded")
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+const checkpointDirName = "checkpoints"
+
+// checkpoint represents the pending work of an enumeration saved to the output directory.
+type checkpoint struct {
+	UUID       string                          `json:"uuid"`
+	Timestamp  time.Time                       `json:"timestamp"`
+	Domains    []string                        `json:"domains"`
+	Names      []*requests.DNSRequest          `json:"names,omitempty"`
+	Addrs      []*requests.AddrRequest         `json:"addrs,omitempty"`
+	Subdomains map[string]int                  `json:"subdomains,omitempty"`
+	Backlog    map[string][]*checkpointRequest `json:"backlog,omitempty"`
+}
+
+// checkpointRequest holds exactly one of the request types sent to the data sources.
+type checkpointRequest struct {
+	DNS       *requests.DNSRequest       `json:"dns,omitempty"`
+	Resolved  *requests.ResolvedRequest  `json:"resolved,omitempty"`
+	Subdomain *requests.SubdomainRequest `json:"subdomain,omitempty"`
+	Addr      *requests.AddrRequest      `json:"addr,omitempty"`
+	ASN       *requests.ASNRequest       `json:"asn,omitempty"`
+	Whois     *requests.WhoisRequest     `json:"whois,omitempty"`
+}
+
+func newCheckpointRequest(element interface{}) *checkpointRequest {
+	switch v := element.(type) {
+	case *requests.DNSRequest:
+		return &checkpointRequest{DNS: v}
+	case *requests.ResolvedRequest:
+		return &checkpointRequest{Resolved: v}
+	case *requests.SubdomainRequest:
+		return &checkpointRequest{Subdomain: v}
+	case *requests.AddrRequest:
+		return &checkpointRequest{Addr: v}
+	case *requests.ASNRequest:
+		return &checkpointRequest{ASN: v}
+	case *requests.WhoisRequest:
+		return &checkpointRequest{Whois: v}
+	}
+	return nil
+}
+
+func (c *checkpointRequest) element() interface{} {
+	switch {
+	case c.DNS != nil:
+		return c.DNS
+	case c.Resolved != nil:
+		return c.Resolved
+	case c.Subdomain != nil:
+		return c.Subdomain
+	case c.Addr != nil:
+		return c.Addr
+	case c.ASN != nil:
+		return c.ASN
+	case c.Whois != nil:
+		return c.Whois
+	}
+	return nil
+}
+
+func encodeBacklog(backlog map[string][]interface{}) map[string][]*checkpointRequest {
+	results := make(map[string][]*checkpointRequest, len(backlog))
+
+	for name, elements := range backlog {
+		for _, element := range elements {
+			if req := newCheckpointRequest(element); req != nil {
+				results[name] = append(results[name], req)
+			}
+		}
+	}
+	return results
+}
+
+func decodeBacklog(backlog map[string][]*checkpointRequest) map[string][]interface{} {
+	results := make(map[string][]interface{}, len(backlog))
+
+	for name, reqs := range backlog {
+		for _, req := range reqs {
+			if element := req.element(); element != nil {
+				results[name] = append(results[name], element)
+			}
+		}
+	}
+	return results
+}
+
+// checkpointPath returns the path of the file holding the saved state for the identified enumeration.
+func checkpointPath(cfg *config.Config) string {
+	return filepath.Join(config.OutputDirectory(cfg.Dir), checkpointDirName, cfg.UUID.String()+".json")
+}
+
+func loadCheckpoint(cfg *config.Config) (*checkpoint, error) {
+	data, err := ioutil.ReadFile(checkpointPath(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the checkpoint for enumeration %s: %v", cfg.UUID.String(), err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse the checkpoint for enumeration %s: %v", cfg.UUID.String(), err)
+	}
+	if cp.UUID != cfg.UUID.String() {
+		return nil, errors.New("the checkpoint does not belong to the requested enumeration")
+	}
+	return &cp, nil
+}
+
+func saveCheckpoint(cfg *config.Config, cp *checkpoint) error {
+	path := checkpointPath(cfg)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so an interruption cannot corrupt the last good checkpoint
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func removeCheckpoint(cfg *config.Config) {
+	_ = os.Remove(checkpointPath(cfg))
+}
+
+// Collect the pending work of the enumeration into a checkpoint.
+func (e *Enumeration) buildCheckpoint() *checkpoint {
+	cp := &checkpoint{
+		UUID:      e.Config.UUID.String(),
+		Timestamp: time.Now(),
+		Domains:   e.Config.Domains(),
+		Backlog:   encodeBacklog(e.dataSrcBacklog()),
+	}
+
+	if e.nameSrc != nil {
+		for _, element := range e.nameSrc.pending() {
+			switch req := element.(type) {
+			case *requests.DNSRequest:
+				cp.Names = append(cp.Names, req)
+			case *requests.AddrRequest:
+				cp.Addrs = append(cp.Addrs, req)
+			}
+		}
+	}
+	if e.subTask != nil {
+		cp.Subdomains = e.subTask.subdomainTimes()
+	}
+	return cp
+}
+
+func (e *Enumeration) writeCheckpoint() {
+	if err := saveCheckpoint(e.Config, e.buildCheckpoint()); err != nil {
+		e.Config.Log.Printf("Failed to save the enumeration checkpoint: %v", err)
+	}
+}
+
+func (e *Enumeration) periodicCheckpoints() {
+	if e.Config.CheckpointInterval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(time.Duration(e.Config.CheckpointInterval) * time.Minute)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-e.ctx.Done():
+			return
+		case <-t.C:
+			e.writeCheckpoint()
+		}
+	}
+}
+
+// Bring the pending work saved in the checkpoint back into the enumeration.
+func (e *Enumeration) restoreCheckpoint(cp *checkpoint) {
+	for _, req := range cp.Names {
+		e.nameSrc.newName(req)
+	}
+	for _, req := range cp.Addrs {
+		if req.Valid() && e.nameSrc.accept(req.Address, req.Tag, req.Source, false) {
+			e.nameSrc.enqueue(req)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/pipeline"
+	"github.com/caffix/queue"
+)
+
+func TestCheckpointSaveAndLoad(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Dir = t.TempDir()
+
+	backlog := map[string][]interface{}{
+		"AlienVault": {
+			&requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"},
+			&requests.SubdomainRequest{Name: "www.owasp.org", Domain: "owasp.org", Times: 2},
+		},
+		"ASNLookup": {&requests.ASNRequest{ASN: 26808}},
+	}
+	cp := &checkpoint{
+		UUID:       cfg.UUID.String(),
+		Timestamp:  time.Now(),
+		Domains:    []string{"owasp.org"},
+		Names:      []*requests.DNSRequest{{Name: "ftp.owasp.org", Domain: "owasp.org", Tag: requests.DNS, Source: "DNS"}},
+		Addrs:      []*requests.AddrRequest{{Address: "104.22.27.77", InScope: true, Domain: "owasp.org"}},
+		Subdomains: map[string]int{"owasp.org": 3},
+		Backlog:    encodeBacklog(backlog),
+	}
+
+	if err := saveCheckpoint(cfg, cp); err != nil {
+		t.Fatalf("Failed to save the checkpoint: %v", err)
+	}
+
+	cfg.Resume = true
+	got, err := loadCheckpoint(cfg)
+	if err != nil {
+		t.Fatalf("Failed to load the checkpoint: %v", err)
+	}
+	if len(got.Domains) != 1 || got.Domains[0] != "owasp.org" {
+		t.Errorf("The checkpoint domains were not restored: %v", got.Domains)
+	}
+	if len(got.Names) != 1 || got.Names[0].Name != "ftp.owasp.org" {
+		t.Errorf("The checkpoint names were not restored: %v", got.Names)
+	}
+	if len(got.Addrs) != 1 || got.Addrs[0].Address != "104.22.27.77" || !got.Addrs[0].InScope {
+		t.Errorf("The checkpoint addresses were not restored: %v", got.Addrs)
+	}
+	if got.Subdomains["owasp.org"] != 3 {
+		t.Errorf("The checkpoint subdomain counters were not restored: %v", got.Subdomains)
+	}
+
+	restored := decodeBacklog(got.Backlog)
+	if reqs := restored["AlienVault"]; len(reqs) != 2 {
+		t.Errorf("Expected two requests in the AlienVault backlog, got %d", len(reqs))
+	} else if sub, ok := reqs[1].(*requests.SubdomainRequest); !ok || sub.Times != 2 {
+		t.Errorf("The subdomain request was not restored in order: %v", reqs[1])
+	}
+	if reqs := restored["ASNLookup"]; len(reqs) != 1 {
+		t.Errorf("Expected one request in the ASNLookup backlog, got %d", len(reqs))
+	} else if asn, ok := reqs[0].(*requests.ASNRequest); !ok || asn.ASN != 26808 {
+		t.Errorf("The ASN request was not restored: %v", reqs[0])
+	}
+
+	removeCheckpoint(cfg)
+	if _, err := loadCheckpoint(cfg); err == nil {
+		t.Error("The checkpoint was not removed")
+	}
+}
+
+func TestPendingSnapshot(t *testing.T) {
+	r := &enumSource{queue: queue.NewQueue(), inputsig: make(chan uint32, 10)}
+
+	names := []string{"www.owasp.org", "ftp.owasp.org", "mail.owasp.org"}
+	for _, name := range names {
+		r.enqueue(&requests.DNSRequest{Name: name, Domain: "owasp.org"})
+	}
+	// The pipeline takes a request while the checkpoint is built
+	taken, ok := r.Data().(*requests.DNSRequest)
+	if !ok {
+		t.Fatal("Failed to obtain a request from the queue")
+	}
+
+	pending := r.pending()
+	if len(pending) != len(names) || r.queue.Len() != len(names)-1 {
+		t.Fatalf("Expected %d pending requests, got %d with %d in the queue", len(names), len(pending), r.queue.Len())
+	}
+	for i, data := range pending {
+		if req := data.(*requests.DNSRequest); req.Name != names[i] {
+			t.Errorf("The pending requests are not in the order they were appended: %s at position %d", req.Name, i)
+		}
+	}
+	// The pipeline stores a clone of the request taken
+	r.finished(taken.Clone())
+
+	var expected []string
+	for _, name := range names {
+		if name != taken.Name {
+			expected = append(expected, name)
+		}
+	}
+
+	pending = r.pending()
+	if len(pending) != len(expected) {
+		t.Fatalf("Expected %d pending requests after the pipeline finished with one, got %d", len(expected), len(pending))
+	}
+	for i, data := range pending {
+		if req := data.(*requests.DNSRequest); req.Name != expected[i] {
+			t.Errorf("The pending requests are not in the order they were appended: %s at position %d", req.Name, i)
+		}
+	}
+
+	for _, data := range []pipeline.Data{r.Data(), r.Data()} {
+		r.finished(data)
+	}
+	if n := len(r.pending()); n != 0 || r.queue.Len() != 0 {
+		t.Errorf("The requests finished by the pipeline are still pending: %d", n)
+	}
+}
@@ -29,14 +29,28 @@ const (
 	maximumBackoffDelay time.Duration = 4 * time.Second
 )
 
-// FwdQueryTypes include the DNS record types that are queried for a discovered name.
+// FwdQueryTypes include the DNS record types that are queried for a discovered name
+// when the configuration does not select the record types.
 var FwdQueryTypes = []uint16{
 	dns.TypeCNAME,
 	dns.TypeA,
 	dns.TypeAAAA,
 }
 
-var fwdQueryTypesLookup = map[uint16]int{dns.TypeCNAME: 0, dns.TypeA: 1, dns.TypeAAAA: 2}
+// Returns the DNS record types selected by the configuration, in the order they are queried.
+func fwdQueryTypes(types []string) []uint16 {
+	var qtypes []uint16
+
+	for _, t := range types {
+		if qtype, found := dns.StringToType[strings.ToUpper(strings.TrimSpace(t))]; found {
+			qtypes = append(qtypes, qtype)
+		}
+	}
+	if len(qtypes) == 0 {
+		qtypes = FwdQueryTypes
+	}
+	return qtypes
+}
 
 type req struct {
 	Ctx        context.Context
@@ -58,8 +72,11 @@ type dnsTask struct {
 	enum      *Enumeration
 	done      chan struct{}
 	pool      *resolve.Resolvers
+	auth      *authResolver
 	params    pipeline.TaskParams
 	reqs      map[string]*req
+	qtypes    []uint16
+	qtypesIdx map[uint16]int
 	resps     chan *dns.Msg
 	respQueue queue.Queue
 	release   chan struct{}
@@ -84,11 +101,19 @@ func newDNSTask(e *Enumeration, trusted bool) *dnsTask {
 		done:      make(chan struct{}, 2),
 		pool:      pool,
 		reqs:      make(map[string]*req),
+		qtypes:    fwdQueryTypes(e.Config.RecordTypes),
+		qtypesIdx: make(map[uint16]int),
 		resps:     make(chan *dns.Msg, plen),
 		respQueue: queue.NewQueue(),
 		release:   make(chan struct{}, plen),
 	}
 
+	if trusted && e.Config.Authoritative {
+		dt.auth = newAuthResolver(e)
+	}
+	for i, qtype := range dt.qtypes {
+		dt.qtypesIdx[qtype] = i
+	}
 	for i := 0; i < plen; i++ {
 		dt.release <- struct{}{}
 	}
@@ -171,7 +196,7 @@ func (dt *dnsTask) Process(ctx context.Context, data pipeline.Data, tp pipeline.
 
 	switch v := data.(type) {
 	case *requests.DNSRequest:
-		qtype := FwdQueryTypes[0]
+		qtype := dt.qtypes[0]
 		msg := resolve.QueryMsg(v.Name, qtype)
 		k := key(msg.Id, msg.Question[0].Name)
 
@@ -182,7 +207,7 @@ func (dt *dnsTask) Process(ctx context.Context, data pipeline.Data, tp pipeline.
 			Attempts:   1,
 			HasRecords: len(v.Records) > 0,
 		}) {
-			dt.pool.Query(ctx, msg, dt.resps)
+			dt.query(ctx, msg)
 			return nil, nil
 		} else {
 			dt.enum.Config.Log.Printf("Failed to enter %s into the request registry on the %s DNS task", msg.Question[0].Name, dt.trust)
@@ -199,7 +224,7 @@ func (dt *dnsTask) Process(ctx context.Context, data pipeline.Data, tp pipeline.
 				Attempts: 1,
 				InScope:  v.InScope,
 			}) {
-				dt.pool.Query(ctx, msg, dt.resps)
+				dt.query(ctx, msg)
 				return nil, nil
 			} else {
 				dt.enum.Config.Log.Printf("Failed to enter %s into the request registry on the %s DNS task", msg.Question[0].Name, dt.trust)
@@ -275,14 +300,53 @@ func (dt *dnsTask) delReq(key string) *req {
 
 func (dt *dnsTask) delReqWithDecrement(key string) {
 	if req := dt.delReq(key); req != nil {
+		if dt.trusted && req.HasRecords {
+			dt.checkDNSSEC(req)
+		}
 		dt.release <- struct{}{}
 		_ = dt.params.Pipeline().DecDataItemCount()
 		if !req.Sent && (req.InScope || req.HasRecords) {
 			dt.nextStage(req.Ctx, req.Data)
+		} else if !req.Sent && req.Ctx.Err() == nil {
+			// The request was dropped, unless the enumeration was interrupted
+			dt.enum.nameSrc.finished(req.Data)
 		}
 	}
 }
 
+// Validates the zone of the resolved name without holding up the request.
+func (dt *dnsTask) checkDNSSEC(entry *req) {
+	r, ok := entry.Data.(*requests.DNSRequest)
+	if !ok || dt.enum.dnssec == nil {
+		return
+	}
+
+	_ = dt.params.Pipeline().IncDataItemCount()
+	go func() {
+		defer func() { _ = dt.params.Pipeline().DecDataItemCount() }()
+
+		dt.enum.dnssec.checkName(entry.Ctx, r.Name)
+	}()
+}
+
+func (dt *dnsTask) query(ctx context.Context, msg *dns.Msg) {
+	dnsQueries.WithLabelValues(dt.trust).Inc()
+	// Names outside the enumeration domains, such as reverse DNS names, are still sent to the resolvers
+	if dt.auth != nil && dt.enum.Config.WhichDomain(resolve.RemoveLastDot(msg.Question[0].Name)) != "" {
+		go dt.auth.query(ctx, msg, dt.resps)
+		return
+	}
+	dt.pool.Query(ctx, msg, dt.resps)
+}
+
+// pending returns the number of requests waiting for a response.
+func (dt *dnsTask) pending() int {
+	dt.Lock()
+	defer dt.Unlock()
+
+	return len(dt.reqs)
+}
+
 func (dt *dnsTask) processResponses() {
 	for {
 		select {
@@ -307,9 +371,16 @@ func (dt *dnsTask) processResp(resp *dns.Msg) {
 		return
 	}
 
+	if resp.Rcode == resolve.RcodeNoResponse {
+		dnsTimeouts.WithLabelValues(dt.trust).Inc()
+	}
+
 	switch resp.Rcode {
 	// check if the response indicates that the name doesn't exist
 	case dns.RcodeNameError:
+		if _, ok := entry.Data.(*requests.DNSRequest); ok && dt.auth != nil {
+			dt.enum.Config.Log.Printf("%s was answered by the untrusted resolvers, but does not exist on the authoritative nameservers", resp.Question[0].Name)
+		}
 		dt.delReqWithDecrement(k)
 		return
 	// the rest are errors that should not continue across many resolvers
@@ -360,7 +431,7 @@ func (dt *dnsTask) retry(msg *dns.Msg, id uint16, entry *req) {
 		dt.delReq(k)
 		dt.addReq(key(msg.Id, msg.Question[0].Name), entry)
 		time.Sleep(resolve.TruncatedExponentialBackoff(entry.Attempts-1, initialBackoffDelay, maximumBackoffDelay))
-		dt.pool.Query(entry.Ctx, msg, dt.resps)
+		dt.query(entry.Ctx, msg)
 	} else {
 		dt.enum.Config.Log.Printf("%s was dropped after failing to resolve %d times on the %s DNS task", msg.Question[0].Name, entry.Attempts-1, dt.trust)
 		dt.delReqWithDecrement(k)
@@ -370,21 +441,21 @@ func (dt *dnsTask) retry(msg *dns.Msg, id uint16, entry *req) {
 func (dt *dnsTask) nextType(ctx context.Context, name string, id, qtype uint16, entry *req) {
 	k := key(id, name)
 
-	if idx, found := fwdQueryTypesLookup[qtype]; found && idx+1 < len(FwdQueryTypes) {
+	if idx, found := dt.qtypesIdx[qtype]; found && idx+1 < len(dt.qtypes) {
 		entry.Attempts = 1
 		entry.Servfails = 0
-		entry.Qtype = FwdQueryTypes[idx+1]
+		entry.Qtype = dt.qtypes[idx+1]
 		msg := resolve.QueryMsg(name, entry.Qtype)
 		dt.delReq(k)
 		dt.addReq(key(msg.Id, msg.Question[0].Name), entry)
-		dt.pool.Query(ctx, msg, dt.resps)
+		dt.query(ctx, msg)
 	} else {
 		dt.delReqWithDecrement(k)
 	}
 }
 
 func (dt *dnsTask) processFwdRequest(ctx context.Context, resp *dns.Msg, name string, qtype uint16, req *requests.DNSRequest, entry *req) {
-	ans := resolve.ExtractAnswers(resp)
+	ans := extractAnswers(resp)
 	if len(ans) == 0 {
 		dt.nextType(ctx, name, resp.Id, qtype, entry)
 		return
@@ -398,6 +469,11 @@ func (dt *dnsTask) processFwdRequest(ctx context.Context, resp *dns.Msg, name st
 
 	k := key(resp.Id, resp.Question[0].Name)
 	if !dt.trusted {
+		// Only the names that resolve are checked by the trusted resolvers
+		if !resolvesName(convertAnswers(rr)) {
+			dt.nextType(ctx, name, resp.Id, qtype, entry)
+			return
+		}
 		dt.nextStage(ctx, req)
 		entry.Sent = true
 		dt.delReqWithDecrement(k)
@@ -410,9 +486,11 @@ func (dt *dnsTask) processFwdRequest(ctx context.Context, resp *dns.Msg, name st
 	}
 
 	req.Records = append(req.Records, convertAnswers(rr)...)
-	entry.HasRecords = len(req.Records) > 0
+	// The records of the other types are kept, but the name is only considered resolved
+	// when it has CNAME, A or AAAA records, which are always queried before the other types
+	entry.HasRecords = resolvesName(req.Records)
 	// are there additional record types to query for?
-	if idx, found := fwdQueryTypesLookup[qtype]; found && qtype != dns.TypeCNAME && idx+1 < len(FwdQueryTypes) {
+	if idx, found := dt.qtypesIdx[qtype]; found && qtype != dns.TypeCNAME && idx+1 < len(dt.qtypes) {
 		dt.nextType(ctx, name, resp.Id, qtype, entry)
 		return
 	}
@@ -420,6 +498,17 @@ func (dt *dnsTask) processFwdRequest(ctx context.Context, resp *dns.Msg, name st
 	dt.delReqWithDecrement(k)
 }
 
+// Returns true when the records include CNAME, A or AAAA records, which resolve the name.
+func resolvesName(records []requests.DNSAnswer) bool {
+	for _, rr := range records {
+		switch uint16(rr.Type) {
+		case dns.TypeCNAME, dns.TypeA, dns.TypeAAAA:
+			return true
+		}
+	}
+	return false
+}
+
 func (dt *dnsTask) processRevRequest(ctx context.Context, resp *dns.Msg, name string, qtype uint16, req *requests.AddrRequest, entry *req) {
 	defer dt.delReqWithDecrement(key(resp.Id, resp.Question[0].Name))
 
@@ -559,7 +648,7 @@ func (dt *dnsTask) querySPF(ctx context.Context, name string, ch chan []requests
 	defer tp.Pipeline().DecDataItemCount()
 	// Obtain the DNS answers for the SPF records related to the domain
 	if resp, err := dt.enum.dnsQuery(ctx, name, dns.TypeSPF, dt.enum.Sys.TrustedResolvers(), maxDNSQueryAttempts); err == nil {
-		if ans := resolve.ExtractAnswers(resp); len(ans) > 0 {
+		if ans := extractAnswers(resp); len(ans) > 0 {
 			if rr := resolve.AnswersByType(ans, dns.TypeSPF); len(rr) > 0 {
 				ch <- convertAnswers(rr)
 				return
@@ -681,3 +770,45 @@ func convertAnswers(ans []*resolve.ExtractedAnswer) []requests.DNSAnswer {
 	}
 	return answers
 }
+
+// Returns the answers extracted from the DNS message, including the record
+// types that are not handled by the resolve package.
+func extractAnswers(msg *dns.Msg) []*resolve.ExtractedAnswer {
+	ans := resolve.ExtractAnswers(msg)
+	if msg == nil {
+		return ans
+	}
+
+	for _, rr := range msg.Answer {
+		var value string
+
+		switch v := rr.(type) {
+		case *dns.CAA:
+			value = fmt.Sprintf("%d %s %s", v.Flag, v.Tag, v.Value)
+		case *dns.HTTPS:
+			value = svcbValue(&v.SVCB)
+		case *dns.SVCB:
+			value = svcbValue(v)
+		case *dns.SPF:
+			value = strings.Join(v.Txt, " ")
+		}
+		if value != "" {
+			ans = append(ans, &resolve.ExtractedAnswer{
+				Name: strings.ToLower(resolve.RemoveLastDot(rr.Header().Name)),
+				Type: rr.Header().Rrtype,
+				Data: strings.TrimSpace(value),
+			})
+		}
+	}
+	return ans
+}
+
+// Returns the priority, target name and parameters of the service binding record.
+func svcbValue(rr *dns.SVCB) string {
+	value := fmt.Sprintf("%d %s", rr.Priority, strings.ToLower(resolve.RemoveLastDot(rr.Target)))
+
+	for _, kv := range rr.Value {
+		value += " " + kv.Key().String() + "=" + kv.String()
+	}
+	return value
+}
@@ -0,0 +1,75 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"testing"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+func TestFwdQueryTypes(t *testing.T) {
+	qtypes := fwdQueryTypes([]string{"CNAME", "a", "CAA", "HTTPS", "BOGUS"})
+	expected := []uint16{dns.TypeCNAME, dns.TypeA, dns.TypeCAA, dns.TypeHTTPS}
+
+	if len(qtypes) != len(expected) {
+		t.Fatalf("Expected %d record types, got %v", len(expected), qtypes)
+	}
+	for i, qtype := range expected {
+		if qtypes[i] != qtype {
+			t.Errorf("Expected the record type %s at position %d", dns.TypeToString[qtype], i)
+		}
+	}
+	if qtypes := fwdQueryTypes(nil); len(qtypes) != len(FwdQueryTypes) {
+		t.Errorf("The default record types were not used")
+	}
+}
+
+func TestExtractAnswers(t *testing.T) {
+	msg := new(dns.Msg)
+	for _, s := range []string{
+		`owasp.org. 300 IN A 104.22.27.77`,
+		`owasp.org. 300 IN CAA 0 issue "letsencrypt.org"`,
+		`owasp.org. 300 IN HTTPS 1 svc.owasp.org. alpn="h2,h3"`,
+		`owasp.org. 300 IN SPF "v=spf1 -all"`,
+	} {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatalf("Failed to parse the record %s: %v", s, err)
+		}
+		msg.Answer = append(msg.Answer, rr)
+	}
+
+	expected := map[uint16]string{
+		dns.TypeA:     "104.22.27.77",
+		dns.TypeCAA:   "0 issue letsencrypt.org",
+		dns.TypeHTTPS: "1 svc.owasp.org alpn=h2,h3",
+		dns.TypeSPF:   "v=spf1 -all",
+	}
+
+	ans := extractAnswers(msg)
+	if len(ans) != len(expected) {
+		t.Fatalf("Expected %d answers, got %d", len(expected), len(ans))
+	}
+	for _, a := range ans {
+		if a.Name != "owasp.org" || a.Data != expected[a.Type] {
+			t.Errorf("Unexpected %s answer: %s %s", dns.TypeToString[a.Type], a.Name, a.Data)
+		}
+	}
+}
+
+func TestResolvesName(t *testing.T) {
+	txt := requests.DNSAnswer{Name: "owasp.org", Type: int(dns.TypeTXT), Data: "v=spf1 -all"}
+	mx := requests.DNSAnswer{Name: "owasp.org", Type: int(dns.TypeMX), Data: "mail.owasp.org"}
+	aaaa := requests.DNSAnswer{Name: "owasp.org", Type: int(dns.TypeAAAA), Data: "2606:4700::6816:1b4d"}
+
+	if resolvesName([]requests.DNSAnswer{txt, mx}) {
+		t.Errorf("The name was resolved without CNAME, A or AAAA records")
+	}
+	if !resolvesName([]requests.DNSAnswer{txt, aaaa}) {
+		t.Errorf("The name with an AAAA record was not resolved")
+	}
+}
@@ -0,0 +1,503 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/resolve"
+	"github.com/miekg/dns"
+)
+
+// The DNSSEC validation status of a zone.
+const (
+	DNSSECSecure        = "secure"
+	DNSSECInsecure      = "insecure"
+	DNSSECBogus         = "bogus"
+	DNSSECIndeterminate = "indeterminate"
+)
+
+// The DS records of the root zone key signing keys published by IANA.
+var rootTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// The algorithms that the signatures can be verified with, and the digest types of the DS records
+// that can be computed. The delegations without a usable DS record are treated as insecure, as
+// described in RFC 4035 section 5.2 and RFC 6840 section 5.2.
+var (
+	supportedAlgorithms = map[uint8]struct{}{
+		dns.RSASHA1:          {},
+		dns.RSASHA1NSEC3SHA1: {},
+		dns.RSASHA256:        {},
+		dns.RSASHA512:        {},
+		dns.ECDSAP256SHA256:  {},
+		dns.ECDSAP384SHA384:  {},
+		dns.ED25519:          {},
+	}
+	supportedDigests = map[uint8]struct{}{
+		dns.SHA1:   {},
+		dns.SHA256: {},
+		dns.SHA384: {},
+	}
+)
+
+func defaultTrustAnchors() []*dns.DS {
+	var anchors []*dns.DS
+
+	for _, anchor := range rootTrustAnchors {
+		if rr, err := dns.NewRR(anchor); err == nil {
+			if ds, ok := rr.(*dns.DS); ok {
+				anchors = append(anchors, ds)
+			}
+		}
+	}
+	return anchors
+}
+
+type dnssecZone struct {
+	sync.Mutex
+	validated bool
+	status    string
+	algorithm string
+	keys      []*dns.DNSKEY
+}
+
+// dnssecValidator verifies the chain of trust from the root trust anchors to the zones of resolved names.
+type dnssecValidator struct {
+	sync.Mutex
+	enum    *Enumeration
+	anchors []*dns.DS
+	zones   map[string]*dnssecZone
+	// The names already checked and the in-scope zones reported
+	names    map[string]struct{}
+	reported map[string]*requests.DNSSECStatus
+	query    func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+}
+
+func newDNSSECValidator(e *Enumeration) *dnssecValidator {
+	v := &dnssecValidator{
+		enum:     e,
+		anchors:  defaultTrustAnchors(),
+		zones:    make(map[string]*dnssecZone),
+		names:    make(map[string]struct{}),
+		reported: make(map[string]*requests.DNSSECStatus),
+	}
+
+	v.query = v.trustedQuery
+	return v
+}
+
+// Requests the DNSSEC records from the trusted resolvers without having them perform the validation,
+// so the responses can be validated locally.
+func (v *dnssecValidator) trustedQuery(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	msg := resolve.WalkMsg(name, qtype)
+	msg.CheckingDisabled = true
+
+	for num := 0; num < maxDNSQueryAttempts; num++ {
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("context expired")
+		default:
+		}
+
+		resp, err := v.enum.Sys.TrustedResolvers().QueryBlocking(ctx, msg)
+		if err != nil {
+			continue
+		}
+		if resp.Rcode == dns.RcodeSuccess || resp.Rcode == dns.RcodeNameError {
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to obtain the %s records for %s", dns.TypeToString[qtype], name)
+}
+
+// checkName validates the zone the name belongs to and reports the status the first time an in-scope zone is validated.
+func (v *dnssecValidator) checkName(ctx context.Context, name string) {
+	name = strings.ToLower(resolve.RemoveLastDot(name))
+
+	v.Lock()
+	_, found := v.names[name]
+	if !found {
+		v.names[name] = struct{}{}
+	}
+	v.Unlock()
+	if found {
+		return
+	}
+
+	zone, err := v.zoneOf(ctx, name)
+	if err != nil {
+		v.expired(ctx, name, "")
+		return
+	}
+	zone = resolve.RemoveLastDot(zone)
+	if !v.enum.Config.IsDomainInScope(zone) {
+		return
+	}
+
+	v.Lock()
+	_, found = v.reported[zone]
+	if !found {
+		// Reserve the zone, so it is only reported once
+		v.reported[zone] = nil
+	}
+	v.Unlock()
+	if found {
+		return
+	}
+
+	z := v.validateZone(ctx, dns.Fqdn(zone))
+	if v.expired(ctx, name, zone) {
+		return
+	}
+
+	status := &requests.DNSSECStatus{
+		Zone:      zone,
+		Status:    z.status,
+		Algorithm: z.algorithm,
+	}
+
+	v.Lock()
+	v.reported[zone] = status
+	v.Unlock()
+	v.enum.dnssecValidated(ctx, status)
+}
+
+// Releases the name and the zone reserved by checkName when the context expired, so they
+// are checked again instead of being reported with the status of an interrupted validation.
+func (v *dnssecValidator) expired(ctx context.Context, name, zone string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+
+	v.Lock()
+	defer v.Unlock()
+
+	delete(v.names, name)
+	if zone != "" {
+		delete(v.reported, zone)
+	}
+	return true
+}
+
+// Returns the owner name of the SOA record for the zone that contains the name.
+func (v *dnssecValidator) zoneOf(ctx context.Context, name string) (string, error) {
+	resp, err := v.query(ctx, name, dns.TypeSOA)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rr := range append(resp.Answer, resp.Ns...) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return strings.ToLower(soa.Hdr.Name), nil
+		}
+	}
+	return "", fmt.Errorf("failed to find the zone containing %s", name)
+}
+
+func (v *dnssecValidator) validateZone(ctx context.Context, zone string) *dnssecZone {
+	v.Lock()
+	z, found := v.zones[zone]
+	if !found {
+		z = new(dnssecZone)
+		v.zones[zone] = z
+	}
+	v.Unlock()
+
+	z.Lock()
+	defer z.Unlock()
+
+	if z.validated {
+		return z
+	}
+	status, alg, keys := v.validate(ctx, zone)
+	// The results obtained after the context expired are not kept, so the zone can be validated again
+	if ctx.Err() != nil {
+		return &dnssecZone{status: DNSSECIndeterminate}
+	}
+
+	z.validated = true
+	z.status, z.algorithm, z.keys = status, alg, keys
+	return z
+}
+
+func (v *dnssecValidator) validate(ctx context.Context, zone string) (string, string, []*dns.DNSKEY) {
+	var dsset []*dns.DS
+
+	if zone == "." {
+		dsset = v.anchors
+	} else {
+		// The DS records are signed by the parent zone
+		parent, err := v.zoneOf(ctx, parentName(zone))
+		// The parent must be an ancestor of the zone, or the validation would never reach the root
+		if err != nil || strings.EqualFold(parent, zone) || !dns.IsSubDomain(parent, zone) {
+			return DNSSECIndeterminate, "", nil
+		}
+
+		p := v.validateZone(ctx, parent)
+		if p.status != DNSSECSecure {
+			return p.status, "", nil
+		}
+
+		resp, err := v.query(ctx, zone, dns.TypeDS)
+		if err != nil {
+			return DNSSECIndeterminate, "", nil
+		}
+
+		rrset, sigs := rrsetAndSignatures(resp.Answer, dns.TypeDS)
+		if len(rrset) == 0 {
+			// An authenticated denial of the DS records makes this an insecure delegation
+			if deniesDS(resp.Ns, zone, p.keys) {
+				return DNSSECInsecure, "", nil
+			}
+			return DNSSECBogus, "", nil
+		}
+		if !verifyRRset(rrset, sigs, p.keys) {
+			return DNSSECBogus, "", nil
+		}
+
+		for _, rr := range rrset {
+			dsset = append(dsset, rr.(*dns.DS))
+		}
+	}
+
+	usable := supportedDS(dsset)
+	if len(usable) == 0 && len(dsset) > 0 {
+		return DNSSECInsecure, algorithmName(dsset[0].Algorithm), nil
+	}
+
+	resp, err := v.query(ctx, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return DNSSECIndeterminate, "", nil
+	}
+
+	rrset, sigs := rrsetAndSignatures(resp.Answer, dns.TypeDNSKEY)
+	var keys []*dns.DNSKEY
+	for _, rr := range rrset {
+		keys = append(keys, rr.(*dns.DNSKEY))
+	}
+	// The key set must be signed by a key matching one of the DS records
+	for _, key := range keys {
+		if !matchesDS(key, usable) {
+			continue
+		}
+		if verifyRRset(rrset, sigs, []*dns.DNSKEY{key}) {
+			return DNSSECSecure, algorithmName(key.Algorithm), keys
+		}
+	}
+	return DNSSECBogus, "", nil
+}
+
+// Returns the DS records that use a supported algorithm and digest type.
+func supportedDS(dsset []*dns.DS) []*dns.DS {
+	var usable []*dns.DS
+
+	for _, ds := range dsset {
+		_, alg := supportedAlgorithms[ds.Algorithm]
+		_, digest := supportedDigests[ds.DigestType]
+		if alg && digest {
+			usable = append(usable, ds)
+		}
+	}
+	return usable
+}
+
+func algorithmName(alg uint8) string {
+	if name, found := dns.AlgorithmToString[alg]; found {
+		return name
+	}
+	return fmt.Sprintf("%d", alg)
+}
+
+func parentName(zone string) string {
+	labels := dns.SplitDomainName(zone)
+	if len(labels) <= 1 {
+		return "."
+	}
+	return dns.Fqdn(strings.Join(labels[1:], "."))
+}
+
+// Returns true when a signed NSEC or NSEC3 record in the section proves that the zone has no DS records.
+func deniesDS(section []dns.RR, zone string, keys []*dns.DNSKEY) bool {
+	for _, qtype := range []uint16{dns.TypeNSEC, dns.TypeNSEC3} {
+		rrset, sigs := rrsetAndSignatures(section, qtype)
+
+		for _, rr := range rrset {
+			if provesNoDS(rr, zone) && verifyRRset([]dns.RR{rr}, sigs, keys) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns true when the NSEC or NSEC3 record matches the zone name without the DS type in its
+// bitmap, or covers the zone name, which is only accepted for NSEC3 records when opt-out is set.
+func provesNoDS(rr dns.RR, zone string) bool {
+	switch n := rr.(type) {
+	case *dns.NSEC:
+		if strings.EqualFold(n.Hdr.Name, zone) {
+			return !hasType(n.TypeBitMap, dns.TypeDS)
+		}
+		return nsecCovers(n, zone)
+	case *dns.NSEC3:
+		if n.Match(zone) {
+			return !hasType(n.TypeBitMap, dns.TypeDS)
+		}
+		return n.Flags&nsec3OptOut != 0 && n.Cover(zone)
+	}
+	return false
+}
+
+// The NSEC3 flag indicating that the insecure delegations may not be covered by the chain.
+const nsec3OptOut = 0x01
+
+func hasType(bitmap []uint16, qtype uint16) bool {
+	for _, t := range bitmap {
+		if t == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true when the name falls between the owner name and the next name of the NSEC record.
+func nsecCovers(n *dns.NSEC, name string) bool {
+	after := canonicalCompare(name, n.Hdr.Name) > 0
+	before := canonicalCompare(name, n.NextDomain) < 0
+
+	// The last record of the chain refers back to the zone apex
+	if canonicalCompare(n.Hdr.Name, n.NextDomain) >= 0 {
+		return after || before
+	}
+	return after && before
+}
+
+// Compares the names using the canonical DNS name order, which sorts the names by their labels
+// starting from the rightmost label.
+func canonicalCompare(a, b string) int {
+	la := dns.SplitDomainName(strings.ToLower(a))
+	lb := dns.SplitDomainName(strings.ToLower(b))
+
+	for i := 1; i <= len(la) && i <= len(lb); i++ {
+		if c := strings.Compare(la[len(la)-i], lb[len(lb)-i]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// Returns the resource records of the type and the signatures covering them.
+func rrsetAndSignatures(section []dns.RR, qtype uint16) ([]dns.RR, []*dns.RRSIG) {
+	var rrset []dns.RR
+	var sigs []*dns.RRSIG
+
+	for _, rr := range section {
+		if rr.Header().Rrtype == qtype {
+			rrset = append(rrset, rr)
+		} else if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == qtype {
+			sigs = append(sigs, sig)
+		}
+	}
+	return rrset, sigs
+}
+
+// Returns true when one of the valid signatures, made by one of the keys, verifies the resource records.
+func verifyRRset(rrset []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY) bool {
+	now := time.Now()
+
+	for _, sig := range sigs {
+		if _, found := supportedAlgorithms[sig.Algorithm]; !found || !sig.ValidityPeriod(now) {
+			continue
+		}
+
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm ||
+				!strings.EqualFold(key.Hdr.Name, sig.SignerName) {
+				continue
+			}
+			// The RRSIG may only cover the records of a single owner name
+			var owned []dns.RR
+			for _, rr := range rrset {
+				if strings.EqualFold(rr.Header().Name, sig.Hdr.Name) {
+					owned = append(owned, rr)
+				}
+			}
+			if len(owned) > 0 && sig.Verify(key, owned) == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesDS(key *dns.DNSKEY, dsset []*dns.DS) bool {
+	for _, ds := range dsset {
+		if ds.KeyTag != key.KeyTag() || ds.Algorithm != key.Algorithm {
+			continue
+		}
+		if d := key.ToDS(ds.DigestType); d != nil && strings.EqualFold(d.Digest, ds.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stores the DNSSEC status of the zone in the graph and reports it through the events.
+func (e *Enumeration) dnssecValidated(ctx context.Context, status *requests.DNSSECStatus) {
+	domain := e.Config.WhichDomain(status.Zone)
+
+	start := time.Now()
+	node, err := e.graph.UpsertFQDN(ctx, status.Zone, "DNS", e.Config.UUID.String())
+	if err == nil {
+		err = e.graph.UpsertProperty(ctx, node, "dnssec", status.Status)
+	}
+	if err == nil && status.Algorithm != "" {
+		err = e.graph.UpsertProperty(ctx, node, "dnssec_algorithm", status.Algorithm)
+	}
+	observeGraphWrite(start)
+	if err != nil {
+		e.Config.Log.Printf("%s failed to insert the DNSSEC status of %s: %v", e.graph, status.Zone, err)
+	}
+
+	e.emit(&DNSSECEvent{
+		Time:      time.Now(),
+		Zone:      status.Zone,
+		Domain:    domain,
+		Status:    status.Status,
+		Algorithm: status.Algorithm,
+	})
+}
+
+// DNSSECStatus returns the DNSSEC validation status of the in-scope zones, when the validation is enabled.
+func (e *Enumeration) DNSSECStatus() []*requests.DNSSECStatus {
+	if e.dnssec == nil {
+		return nil
+	}
+
+	e.dnssec.Lock()
+	defer e.dnssec.Unlock()
+
+	var zones []*requests.DNSSECStatus
+	for _, status := range e.dnssec.reported {
+		if status != nil {
+			zones = append(zones, status)
+		}
+	}
+
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].Zone < zones[j].Zone
+	})
+	return zones
+}
@@ -0,0 +1,346 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"crypto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/caffix/netmap"
+	"github.com/caffix/queue"
+	"github.com/cayleygraph/quad"
+	"github.com/miekg/dns"
+)
+
+type signedZone struct {
+	key    *dns.DNSKEY
+	signer crypto.Signer
+	// The DS records published by the parent zone, or nil for an unsigned delegation
+	ds []dns.RR
+	// The records denying the DS records of an unsigned delegation, when not the default NSEC record
+	denial []dns.RR
+}
+
+// signedHierarchy serves the DNSSEC records of a small hierarchy of zones.
+type signedHierarchy struct {
+	t     *testing.T
+	zones map[string]*signedZone
+}
+
+func newSignedZone(t *testing.T, name string) *signedZone {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatalf("Failed to generate the key for %s: %v", name, err)
+	}
+	return &signedZone{key: key, signer: priv.(crypto.Signer)}
+}
+
+func (h *signedHierarchy) sign(z *signedZone, rrset ...dns.RR) []dns.RR {
+	now := time.Now()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Ttl: 3600},
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.key.Hdr.Name,
+		Algorithm:  z.key.Algorithm,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+
+	if err := sig.Sign(z.signer, rrset); err != nil {
+		h.t.Fatalf("Failed to sign the %s records: %v", rrset[0].Header().Name, err)
+	}
+	return append(rrset, sig)
+}
+
+func (h *signedHierarchy) zoneOf(name string) string {
+	for n := name; ; n = parentName(n) {
+		if _, found := h.zones[n]; found {
+			return n
+		}
+		if n == "." {
+			return n
+		}
+	}
+}
+
+func (h *signedHierarchy) query(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	name = dns.Fqdn(strings.ToLower(name))
+	zone := h.zoneOf(name)
+	z := h.zones[zone]
+	resp := new(dns.Msg)
+
+	switch qtype {
+	case dns.TypeSOA:
+		soa := &dns.SOA{
+			Hdr:  dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+			Ns:   "ns." + zone,
+			Mbox: "hostmaster." + zone,
+		}
+		if name == zone {
+			resp.Answer = []dns.RR{soa}
+		} else {
+			resp.Ns = []dns.RR{soa}
+		}
+	case dns.TypeDNSKEY:
+		resp.Answer = h.sign(z, z.key)
+	case dns.TypeDS:
+		parent := h.zones[h.zoneOf(parentName(zone))]
+		if z.ds != nil {
+			resp.Answer = h.sign(parent, z.ds...)
+			break
+		}
+		if z.denial != nil {
+			resp.Ns = h.sign(parent, z.denial...)
+			break
+		}
+
+		nsec := &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: zone, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 3600},
+			NextDomain: "www." + zone,
+			TypeBitMap: []uint16{dns.TypeNS},
+		}
+		resp.Ns = h.sign(parent, nsec)
+	}
+	return resp, nil
+}
+
+func TestDNSSECValidation(t *testing.T) {
+	h := &signedHierarchy{t: t, zones: make(map[string]*signedZone)}
+	for _, name := range []string{".", "org.", "owasp.org.", "example.org.", "bad.org."} {
+		h.zones[name] = newSignedZone(t, name)
+	}
+	for _, name := range []string{"org.", "owasp.org."} {
+		h.zones[name].ds = []dns.RR{h.zones[name].key.ToDS(dns.SHA256)}
+	}
+	// The DS record of this zone does not match the key that signed its key set
+	h.zones["bad.org."].ds = []dns.RR{newSignedZone(t, "bad.org.").key.ToDS(dns.SHA256)}
+
+	cfg := config.NewConfig()
+	cfg.AddDomains("owasp.org", "example.org", "bad.org")
+	cfg.DNSSEC = true
+
+	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer graph.Close()
+
+	e := &Enumeration{
+		Config:     cfg,
+		graph:      graph,
+		events:     queue.NewQueue(),
+		eventsDone: make(chan struct{}),
+	}
+	e.Events()
+	e.dnssec = newDNSSECValidator(e)
+	e.dnssec.anchors = []*dns.DS{h.zones["."].key.ToDS(dns.SHA256)}
+	e.dnssec.query = h.query
+
+	ctx := context.Background()
+	for _, name := range []string{"www.owasp.org", "owasp.org", "mail.example.org", "www.bad.org", "www.example.org"} {
+		e.dnssec.checkName(ctx, name)
+	}
+
+	expected := map[string]string{
+		"bad.org":     DNSSECBogus,
+		"example.org": DNSSECInsecure,
+		"owasp.org":   DNSSECSecure,
+	}
+	zones := e.DNSSECStatus()
+	if len(zones) != len(expected) {
+		t.Fatalf("Expected the status of %d zones, got %d", len(expected), len(zones))
+	}
+	for _, z := range zones {
+		if z.Status != expected[z.Zone] {
+			t.Errorf("Expected the %s zone to be %s, got %s", z.Zone, expected[z.Zone], z.Status)
+		}
+		if z.Zone == "owasp.org" && z.Algorithm != "ECDSAP256SHA256" {
+			t.Errorf("Unexpected algorithm for the %s zone: %s", z.Zone, z.Algorithm)
+		}
+	}
+	if n := e.events.Len(); n != len(expected) {
+		t.Errorf("Expected an event for each zone, got %d", n)
+	}
+
+	node, err := graph.ReadNode(ctx, "owasp.org", "fqdn")
+	if err != nil {
+		t.Fatalf("The zone was not stored in the graph: %v", err)
+	}
+	props, err := graph.ReadProperties(ctx, node, "dnssec")
+	if err != nil || len(props) != 1 || quad.ToString(props[0].Value) != DNSSECSecure {
+		t.Errorf("The DNSSEC status was not stored as a property of the zone")
+	}
+
+	// A signature that does not verify makes the zone bogus
+	e.dnssec = newDNSSECValidator(e)
+	e.dnssec.anchors = []*dns.DS{newSignedZone(t, ".").key.ToDS(dns.SHA256)}
+	e.dnssec.query = h.query
+	e.dnssec.checkName(ctx, "www.owasp.org")
+	if zones := e.DNSSECStatus(); len(zones) != 1 || zones[0].Status != DNSSECBogus {
+		t.Errorf("The zone was not reported bogus without a valid chain to the trust anchors")
+	}
+}
+
+func TestDNSSECDenial(t *testing.T) {
+	h := &signedHierarchy{t: t, zones: make(map[string]*signedZone)}
+	for _, name := range []string{".", "org.", "listed.org.", "other.org.", "optout.org.", "nooptout.org."} {
+		h.zones[name] = newSignedZone(t, name)
+	}
+	h.zones["org."].ds = []dns.RR{h.zones["org."].key.ToDS(dns.SHA256)}
+
+	// The NSEC record matches the zone, but claims that the DS records exist
+	h.zones["listed.org."].denial = []dns.RR{&dns.NSEC{
+		Hdr:        dns.RR_Header{Name: "listed.org.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 3600},
+		NextDomain: "www.listed.org.",
+		TypeBitMap: []uint16{dns.TypeNS, dns.TypeDS},
+	}}
+	// The NSEC record neither matches nor covers the zone
+	h.zones["other.org."].denial = []dns.RR{&dns.NSEC{
+		Hdr:        dns.RR_Header{Name: "aaa.org.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 3600},
+		NextDomain: "abc.org.",
+		TypeBitMap: []uint16{dns.TypeNS},
+	}}
+	// A single NSEC3 record covers every hash other than its own
+	nsec3 := func(flags uint8) []dns.RR {
+		hash := strings.Repeat("0", 32)
+		return []dns.RR{&dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: hash + ".org.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 3600},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			SaltLength: 0,
+			HashLength: 20,
+			NextDomain: hash,
+			TypeBitMap: []uint16{dns.TypeNS},
+		}}
+	}
+	h.zones["optout.org."].denial = nsec3(nsec3OptOut)
+	h.zones["nooptout.org."].denial = nsec3(0)
+
+	v := newDNSSECValidator(&Enumeration{Config: config.NewConfig()})
+	v.anchors = []*dns.DS{h.zones["."].key.ToDS(dns.SHA256)}
+	v.query = h.query
+
+	expected := map[string]string{
+		"listed.org.":   DNSSECBogus,
+		"other.org.":    DNSSECBogus,
+		"optout.org.":   DNSSECInsecure,
+		"nooptout.org.": DNSSECBogus,
+	}
+	for zone, status := range expected {
+		if z := v.validateZone(context.Background(), zone); z.status != status {
+			t.Errorf("Expected the %s zone to be %s, got %s", zone, status, z.status)
+		}
+	}
+}
+
+func TestDNSSECZoneLoop(t *testing.T) {
+	v := newDNSSECValidator(&Enumeration{Config: config.NewConfig()})
+	// The parent of the zone is reported to be the zone itself
+	v.query = func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.Ns = []dns.RR{&dns.SOA{
+			Hdr: dns.RR_Header{Name: "owasp.org.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+		}}
+		return resp, nil
+	}
+
+	done := make(chan string, 1)
+	go func() {
+		done <- v.validateZone(context.Background(), "owasp.org.").status
+	}()
+
+	select {
+	case status := <-done:
+		if status != DNSSECIndeterminate {
+			t.Errorf("Expected the zone to be %s, got %s", DNSSECIndeterminate, status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The validation of the zone did not return")
+	}
+}
+
+func TestDNSSECUnsupportedAlgorithm(t *testing.T) {
+	h := &signedHierarchy{t: t, zones: make(map[string]*signedZone)}
+	for _, name := range []string{".", "org.", "gost.org.", "digest.org."} {
+		h.zones[name] = newSignedZone(t, name)
+	}
+	h.zones["org."].ds = []dns.RR{h.zones["org."].key.ToDS(dns.SHA256)}
+
+	ds := func(zone string, alg, digest uint8) []dns.RR {
+		return []dns.RR{&dns.DS{
+			Hdr:        dns.RR_Header{Name: zone, Rrtype: dns.TypeDS, Class: dns.ClassINET, Ttl: 3600},
+			KeyTag:     h.zones[zone].key.KeyTag(),
+			Algorithm:  alg,
+			DigestType: digest,
+			Digest:     strings.Repeat("00", 32),
+		}}
+	}
+	// The signed DS records only refer to keys that cannot be verified
+	h.zones["gost.org."].ds = ds("gost.org.", dns.ECCGOST, dns.SHA256)
+	h.zones["digest.org."].ds = ds("digest.org.", dns.ECDSAP256SHA256, dns.GOST94)
+
+	v := newDNSSECValidator(&Enumeration{Config: config.NewConfig()})
+	v.anchors = []*dns.DS{h.zones["."].key.ToDS(dns.SHA256)}
+	v.query = h.query
+
+	expected := map[string]string{
+		"gost.org.":   "ECC-GOST",
+		"digest.org.": "ECDSAP256SHA256",
+	}
+	for zone, alg := range expected {
+		if z := v.validateZone(context.Background(), zone); z.status != DNSSECInsecure || z.algorithm != alg {
+			t.Errorf("Expected the %s zone to be insecure with %s, got %s with %s", zone, alg, z.status, z.algorithm)
+		}
+	}
+}
+
+func TestDNSSECExpiredContext(t *testing.T) {
+	h := &signedHierarchy{t: t, zones: make(map[string]*signedZone)}
+	for _, name := range []string{".", "org.", "owasp.org."} {
+		h.zones[name] = newSignedZone(t, name)
+		if name != "." {
+			h.zones[name].ds = []dns.RR{h.zones[name].key.ToDS(dns.SHA256)}
+		}
+	}
+
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer graph.Close()
+
+	e := &Enumeration{Config: cfg, graph: graph}
+	e.dnssec = newDNSSECValidator(e)
+	e.dnssec.anchors = []*dns.DS{h.zones["."].key.ToDS(dns.SHA256)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// The context expires while the zone is being validated
+	e.dnssec.query = func(qctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+		if qtype == dns.TypeDNSKEY {
+			cancel()
+		}
+		if err := qctx.Err(); err != nil {
+			return nil, err
+		}
+		return h.query(qctx, name, qtype)
+	}
+	e.dnssec.checkName(ctx, "www.owasp.org")
+	if zones := e.DNSSECStatus(); len(zones) != 0 {
+		t.Errorf("The status of the interrupted validation was reported: %s", zones[0].Status)
+	}
+
+	e.dnssec.query = h.query
+	e.dnssec.checkName(context.Background(), "www.owasp.org")
+	if zones := e.DNSSECStatus(); len(zones) != 1 || zones[0].Status != DNSSECSecure {
+		t.Errorf("The zone was not validated again after the context expired")
+	}
+}
@@ -6,6 +6,8 @@ package enum
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/OWASP/Amass/v3/config"
 	"github.com/OWASP/Amass/v3/datasrcs"
@@ -19,38 +21,92 @@ import (
 
 const maxActivePipelineTasks int = 25
 
+var (
+	sourceRunsLock sync.Mutex
+	// The number of enumerations using the data sources, keyed by the System configuration
+	sourceRuns = make(map[*config.Config]int)
+)
+
 // Enumeration is the object type used to execute a DNS enumeration.
 type Enumeration struct {
-	Config   *config.Config
-	Sys      systems.System
-	ctx      context.Context
-	graph    *netmap.Graph
-	srcs     []service.Service
-	done     chan struct{}
-	nameSrc  *enumSource
-	subTask  *subdomainTask
-	dnsTask  *dnsTask
-	valTask  *dnsTask
-	store    *dataManager
-	requests queue.Queue
+	Config      *config.Config
+	Sys         systems.System
+	ctx         context.Context
+	graph       *netmap.Graph
+	srcs        []service.Service
+	srcsLock    sync.Mutex
+	srcChanges  chan *sourceChange
+	done        chan struct{}
+	nameSrc     *enumSource
+	subTask     *subdomainTask
+	dnsTask     *dnsTask
+	valTask     *dnsTask
+	dnssec      *dnssecValidator
+	nsec3       *nsec3Cracker
+	store       *dataManager
+	requests    queue.Queue
+	checkpoint  *checkpoint
+	backlogReqs chan chan map[string][]interface{}
+	backlogDone chan struct{}
+	backlog     map[string][]interface{}
+	events      queue.Queue
+	eventsCh    chan Event
+	eventsOnce  sync.Once
+	eventsDone  chan struct{}
+	stats       *sourceStats
+	written     map[string][]requests.DNSAnswer
+	writtenLock sync.Mutex
 }
 
 // NewEnumeration returns an initialized Enumeration that has not been started yet.
 func NewEnumeration(cfg *config.Config, sys systems.System, graph *netmap.Graph) *Enumeration {
-	return &Enumeration{
-		Config:   cfg,
-		Sys:      sys,
-		graph:    graph,
-		srcs:     datasrcs.SelectedDataSources(cfg, sys.DataSources()),
-		requests: queue.NewQueue(),
+	srcs := datasrcs.SelectedDataSources(cfg, sys.DataSources())
+
+	e := &Enumeration{
+		Config:      cfg,
+		Sys:         sys,
+		graph:       graph,
+		srcs:        srcs,
+		requests:    queue.NewQueue(),
+		srcChanges:  make(chan *sourceChange),
+		backlogReqs: make(chan chan map[string][]interface{}),
+		backlogDone: make(chan struct{}),
+		events:      queue.NewQueue(),
+		eventsDone:  make(chan struct{}),
+		stats:       newSourceStats(srcs),
+		written:     make(map[string][]requests.DNSAnswer),
 	}
+
+	e.nsec3 = newNSEC3Cracker(e)
+	if cfg.DNSSEC {
+		e.dnssec = newDNSSECValidator(e)
+	}
+	return e
 }
 
 // Start begins the vertical domain correlation process.
 func (e *Enumeration) Start(ctx context.Context) error {
 	e.done = make(chan struct{})
 	defer close(e.done)
+	// Subscribers receive the events until all the components have been stopped
+	if e.eventsCh != nil {
+		forwardDone := make(chan struct{})
+		defer close(forwardDone)
+		go e.forwardEvents(forwardDone)
+	}
 
+	backlog := make(map[string][]interface{})
+	// Pick up the pending work of an interrupted enumeration
+	if e.Config.Resume {
+		cp, err := loadCheckpoint(e.Config)
+		if err != nil {
+			return err
+		}
+
+		e.checkpoint = cp
+		e.Config.AddDomains(cp.Domains...)
+		backlog = decodeBacklog(cp.Backlog)
+	}
 	if err := e.Config.CheckSettings(); err != nil {
 		return err
 	}
@@ -59,7 +115,10 @@ func (e *Enumeration) Start(ctx context.Context) error {
 	var cancel context.CancelFunc
 	e.ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
-	go e.manageDataSrcRequests()
+	// The data sources report their errors through the System configuration
+	defer e.Sys.Config().AddSourceErrorHook(e.sourceError)()
+	defer e.startSourceRun()()
+	go e.manageDataSrcRequests(backlog)
 
 	if !e.Config.Passive {
 		e.dnsTask = newDNSTask(e, false)
@@ -89,9 +148,20 @@ func (e *Enumeration) Start(ctx context.Context) error {
 	// The pipeline input source will receive all the names
 	e.nameSrc = newEnumSource(p, e)
 	defer e.nameSrc.Stop()
+	// Report the state of the enumeration through the metrics
+	e.setActive(true)
+	defer e.setActive(false)
 
-	e.submitASNs()
-	e.submitDomainNames()
+	if e.checkpoint != nil {
+		// The data sources already received the root domain names and ASNs
+		e.restoreCheckpoint(e.checkpoint)
+	} else {
+		e.submitASNs()
+		e.submitDomainNames()
+	}
+	go e.periodicCheckpoints()
+	// Changes to the user scripts are applied while the enumeration continues
+	defer e.watchScripts()()
 	/*
 	 * Now that the pipeline input source has been setup, names provided
 	 * by the user and names acquired from the graph database can be brought
@@ -108,6 +178,12 @@ func (e *Enumeration) Start(ctx context.Context) error {
 		// Ensure all data has been stored
 		<-e.store.Stop()
 	}
+	// Save the pending work if the enumeration was interrupted
+	if e.ctx.Err() != nil {
+		e.writeCheckpoint()
+	} else {
+		removeCheckpoint(e.Config)
+	}
 	return err
 }
 
@@ -138,19 +214,59 @@ func (e *Enumeration) sendRequests(element interface{}) {
 	e.requests.Append(element)
 }
 
-func (e *Enumeration) manageDataSrcRequests() {
+func (e *Enumeration) manageDataSrcRequests(requestsMap map[string][]interface{}) {
+	srcs := e.dataSources()
 	nameToSrc := make(map[string]service.Service)
-	for _, src := range e.srcs {
+	for _, src := range srcs {
 		nameToSrc[src.String()] = src
 	}
 
-	pending := make(map[string]bool)
-	for _, src := range e.srcs {
-		pending[src.String()] = false
+	finished := make(chan string, len(srcs))
+	// Requests that have been sent to a data source, but not yet received
+	inflight := make(map[string]interface{})
+	next := func(name string) {
+		if _, found := nameToSrc[name]; !found || len(requestsMap[name]) == 0 {
+			delete(inflight, name)
+			return
+		}
+
+		inflight[name] = requestsMap[name][0]
+		go e.fireRequest(nameToSrc[name], requestsMap[name][0], finished)
+		requestsMap[name] = requestsMap[name][1:]
 	}
+	distribute := func(element interface{}) {
+		for name := range nameToSrc {
+			if _, busy := inflight[name]; !busy && len(requestsMap[name]) == 0 {
+				inflight[name] = element
+				go e.fireRequest(nameToSrc[name], element, finished)
+			} else {
+				requestsMap[name] = append(requestsMap[name], element)
+			}
+		}
+	}
+	snapshot := func() map[string][]interface{} {
+		backlog := make(map[string][]interface{}, len(nameToSrc))
+
+		for name := range nameToSrc {
+			var reqs []interface{}
 
-	finished := make(chan string, len(e.srcs))
-	requestsMap := make(map[string][]interface{})
+			if req, found := inflight[name]; found {
+				reqs = append(reqs, req)
+			}
+			if reqs = append(reqs, requestsMap[name]...); len(reqs) > 0 {
+				backlog[name] = reqs
+			}
+		}
+		return backlog
+	}
+	// Continue the work restored from a checkpoint
+	for name := range requestsMap {
+		if _, found := nameToSrc[name]; found {
+			next(name)
+		} else {
+			delete(requestsMap, name)
+		}
+	}
 loop:
 	for {
 		select {
@@ -159,39 +275,90 @@ loop:
 		case <-e.ctx.Done():
 			break loop
 		case <-e.requests.Signal():
-			element, ok := e.requests.Next()
-			if !ok {
-				continue loop
-			}
-			for name := range nameToSrc {
-				if len(requestsMap[name]) == 0 && !pending[name] {
-					go e.fireRequest(nameToSrc[name], element, finished)
-					pending[name] = true
-				} else {
-					requestsMap[name] = append(requestsMap[name], element)
-				}
+			if element, ok := e.requests.Next(); ok {
+				distribute(element)
 			}
 		case name := <-finished:
-			if len(requestsMap[name]) == 0 {
-				pending[name] = false
-				continue loop
+			next(name)
+		case c := <-e.srcChanges:
+			if name := c.src.String(); c.remove {
+				delete(nameToSrc, name)
+				delete(requestsMap, name)
+			} else {
+				nameToSrc[name] = c.src
+				requestsMap[name] = append(requestsMap[name], c.backlog...)
+				if _, busy := inflight[name]; !busy {
+					next(name)
+				}
 			}
-
-			go e.fireRequest(nameToSrc[name], requestsMap[name][0], finished)
-			requestsMap[name] = requestsMap[name][1:]
+		case ch := <-e.backlogReqs:
+			e.requests.Process(distribute)
+			ch <- snapshot()
 		}
 	}
-	e.requests.Process(func(e interface{}) {})
+	// Keep the requests that were never handled by the data sources
+	e.requests.Process(func(element interface{}) {
+		for name := range nameToSrc {
+			requestsMap[name] = append(requestsMap[name], element)
+		}
+	})
+	e.backlog = snapshot()
+	close(e.backlogDone)
+}
+
+// dataSrcBacklog returns the requests not yet handled by each data source.
+func (e *Enumeration) dataSrcBacklog() map[string][]interface{} {
+	ch := make(chan map[string][]interface{}, 1)
+
+	select {
+	case <-e.backlogDone:
+		return e.backlog
+	case e.backlogReqs <- ch:
+	}
+	return <-ch
 }
 
 func (e *Enumeration) fireRequest(srv service.Service, req interface{}, finished chan string) {
+	// Data sources that have used up their request budget no longer receive requests
+	if !e.Sys.Config().SourceBudgetExhausted(srv.String(), requests.RequestDomain(req)) {
+		select {
+		case <-e.done:
+		case <-e.ctx.Done():
+		case <-srv.Done():
+		case srv.Input() <- req:
+			e.stats.request(srv.String())
+		}
+	}
+	// The data sources loaded during the enumeration can outnumber the buffered notifications
 	select {
 	case <-e.done:
-	case <-e.ctx.Done():
-	case <-srv.Done():
-	case srv.Input() <- req:
+	case finished <- srv.String():
+	}
+}
+
+// The data source budgets and credentials rotation apply to each run, so they are shared by the
+// enumerations executing on the System at the same time. The first enumeration resets them and
+// registers itself under the same lock, so concurrent enumerations cannot reset the budgets in use.
+func (e *Enumeration) startSourceRun() func() {
+	cfg := e.Sys.Config()
+
+	sourceRunsLock.Lock()
+	defer sourceRunsLock.Unlock()
+
+	if sourceRuns[cfg] == 0 {
+		cfg.ResetSourceBudgets()
+		cfg.ResetCredentialsRotation()
+	}
+	sourceRuns[cfg]++
+
+	return func() {
+		sourceRunsLock.Lock()
+		defer sourceRunsLock.Unlock()
+
+		if sourceRuns[cfg]--; sourceRuns[cfg] <= 0 {
+			delete(sourceRuns, cfg)
+		}
 	}
-	finished <- srv.String()
 }
 
 func (e *Enumeration) makeOutputSink() pipeline.SinkFunc {
@@ -199,11 +366,18 @@ func (e *Enumeration) makeOutputSink() pipeline.SinkFunc {
 		if !e.Config.Passive {
 			return nil
 		}
+		// Without the DNS stages, the pipeline is finished with the request here
+		e.nameSrc.finished(data)
 
 		req, ok := data.(*requests.DNSRequest)
 		if ok && req != nil && req.Name != "" && e.Config.IsDomainInScope(req.Name) {
-			if _, err := e.graph.UpsertFQDN(e.ctx, req.Name, req.Source, e.Config.UUID.String()); err != nil {
+			start := time.Now()
+			_, err := e.graph.UpsertFQDN(e.ctx, req.Name, req.Source, e.Config.UUID.String())
+			observeGraphWrite(start)
+			if err != nil {
 				e.Config.Log.Print(err.Error())
+			} else {
+				e.nameWritten(req)
 			}
 		}
 		return nil
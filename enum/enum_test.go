@@ -0,0 +1,38 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/systems"
+)
+
+func TestSourceRunBudgets(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Log = log.New(ioutil.Discard, "", 0)
+	cfg.GetDataSourceConfig("Budget").MaxRequests = 1
+	sys := &systems.SimpleSystem{Cfg: cfg}
+
+	finish := (&Enumeration{Sys: sys}).startSourceRun()
+	if err := cfg.SpendSourceBudget("Budget", ""); err != nil {
+		t.Fatalf("The budget was not available to the first enumeration: %v", err)
+	}
+	// The concurrent enumeration shares the budgets of the run
+	finishConcurrent := (&Enumeration{Sys: sys}).startSourceRun()
+	if !cfg.SourceBudgetExhausted("Budget", "") {
+		t.Error("The concurrent enumeration reset the budgets in use")
+	}
+
+	finish()
+	finishConcurrent()
+	(&Enumeration{Sys: sys}).startSourceRun()()
+	if cfg.SourceBudgetExhausted("Budget", "") {
+		t.Error("The budgets were not reset for the next run")
+	}
+}
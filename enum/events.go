@@ -0,0 +1,188 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"sort"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// Event is implemented by each type sent on the stream returned by Enumeration.Events.
+type Event interface {
+	// Timestamp returns the time the event was produced by the enumeration.
+	Timestamp() time.Time
+}
+
+// NameEvent reports an in-scope name the first time it is written into the enumeration graph.
+type NameEvent struct {
+	Time   time.Time
+	Name   string
+	Domain string
+	Tag    string
+	Source string
+}
+
+// Timestamp implements the Event interface.
+func (e *NameEvent) Timestamp() time.Time { return e.Time }
+
+// ResolvedEvent reports the DNS records obtained for a name.
+type ResolvedEvent struct {
+	Time    time.Time
+	Name    string
+	Domain  string
+	Records []requests.DNSAnswer
+	Source  string
+}
+
+// Timestamp implements the Event interface.
+func (e *ResolvedEvent) Timestamp() time.Time { return e.Time }
+
+// AddressEvent reports an IP address that a name resolved to.
+type AddressEvent struct {
+	Time    time.Time
+	Name    string
+	Domain  string
+	Address string
+	Source  string
+}
+
+// Timestamp implements the Event interface.
+func (e *AddressEvent) Timestamp() time.Time { return e.Time }
+
+// ASNEvent reports the infrastructure information associated with an address.
+type ASNEvent struct {
+	Time        time.Time
+	Address     string
+	ASN         int
+	Prefix      string
+	Description string
+	Source      string
+}
+
+// Timestamp implements the Event interface.
+func (e *ASNEvent) Timestamp() time.Time { return e.Time }
+
+// DNSSECEvent reports the DNSSEC validation status of an in-scope zone the first time it is validated.
+type DNSSECEvent struct {
+	Time      time.Time
+	Zone      string
+	Domain    string
+	Status    string
+	Algorithm string
+}
+
+// Timestamp implements the Event interface.
+func (e *DNSSECEvent) Timestamp() time.Time { return e.Time }
+
+// SourceErrorEvent reports an error returned to a data source during the enumeration.
+type SourceErrorEvent struct {
+	Time   time.Time
+	Source string
+	Err    error
+}
+
+// Timestamp implements the Event interface.
+func (e *SourceErrorEvent) Timestamp() time.Time { return e.Time }
+
+// FinishedEvent is the last event sent by Run and carries the result of the enumeration.
+type FinishedEvent struct {
+	Time time.Time
+	UUID string
+	Err  error
+}
+
+// Timestamp implements the Event interface.
+func (e *FinishedEvent) Timestamp() time.Time { return e.Time }
+
+// Events returns the stream of events produced by the enumeration. It must be called before
+// Start, and the channel is closed after the enumeration has finished.
+func (e *Enumeration) Events() <-chan Event {
+	e.eventsOnce.Do(func() {
+		e.eventsCh = make(chan Event, 100)
+	})
+	return e.eventsCh
+}
+
+func (e *Enumeration) emit(ev Event) {
+	if e.eventsCh == nil {
+		return
+	}
+
+	select {
+	case <-e.eventsDone:
+		return
+	default:
+	}
+	e.events.Append(ev)
+}
+
+func (e *Enumeration) nameWritten(req *requests.DNSRequest) {
+	if !e.Config.IsDomainInScope(req.Name) {
+		return
+	}
+
+	e.writtenLock.Lock()
+	records, found := e.written[req.Name]
+	// The records are kept for the data sources loaded later in the enumeration
+	if len(req.Records) > 0 || !found {
+		records = append(records[:0:0], req.Records...)
+	}
+	e.written[req.Name] = records
+	e.writtenLock.Unlock()
+
+	if !found && e.eventsCh != nil {
+		e.emit(&NameEvent{
+			Time:   time.Now(),
+			Name:   req.Name,
+			Domain: req.Domain,
+			Tag:    req.Tag,
+			Source: req.Source,
+		})
+	}
+}
+
+// Returns the in-scope names written into the enumeration graph, in sorted order.
+func (e *Enumeration) writtenNames() []string {
+	e.writtenLock.Lock()
+	names := make([]string, 0, len(e.written))
+	for name := range e.written {
+		names = append(names, name)
+	}
+	e.writtenLock.Unlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// Returns the DNS records last written into the enumeration graph for the in-scope name.
+func (e *Enumeration) writtenRecords(name string) []requests.DNSAnswer {
+	e.writtenLock.Lock()
+	defer e.writtenLock.Unlock()
+
+	return append([]requests.DNSAnswer(nil), e.written[name]...)
+}
+
+// Hand the events to the subscriber without ever blocking the pipeline.
+func (e *Enumeration) forwardEvents(done chan struct{}) {
+	defer close(e.eventsCh)
+
+	send := func(element interface{}) {
+		e.eventsCh <- element.(Event)
+	}
+	for {
+		select {
+		case <-done:
+			close(e.eventsDone)
+			e.events.Process(send)
+			return
+		case <-e.events.Signal():
+			if element, ok := e.events.Next(); ok {
+				send(element)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/netmap"
+	"github.com/caffix/queue"
+	"github.com/miekg/dns"
+)
+
+func TestEventsAndSourceErrors(t *testing.T) {
+	e := &Enumeration{
+		Config:     config.NewConfig(),
+		events:     queue.NewQueue(),
+		eventsDone: make(chan struct{}),
+	}
+	e.Config.AddDomain("owasp.org")
+	events := e.Events()
+
+	done := make(chan struct{})
+	go e.forwardEvents(done)
+
+	cfg := config.NewConfig()
+	var buf bytes.Buffer
+	cfg.Log = log.New(&buf, "", 0)
+	e.stats = &sourceStats{
+		stats: map[string]*requests.SourceStats{"AlienVault": {Source: "AlienVault"}},
+		names: make(map[string][]string),
+	}
+	remove := cfg.AddSourceErrorHook(e.sourceError)
+
+	e.emit(&NameEvent{Time: time.Now(), Name: "www.owasp.org", Domain: "owasp.org"})
+	// Informational messages written to the log are not reported as errors
+	cfg.Log.Printf("Querying %s for %s subdomains", "AlienVault", "owasp.org")
+	ctx := config.WithSourceDomain(context.Background(), "owasp.org")
+	cfg.SourceError(ctx, "AlienVault", fmt.Errorf("%s: %v", "https://otx.alienvault.com", "403 Forbidden"))
+	// Errors of data sources not used by the enumeration are ignored
+	cfg.SourceError(ctx, "Crtsh", errors.New("503 Service Unavailable"))
+	// Errors of requests about the domains of another enumeration sharing the System are ignored
+	other := config.WithSourceDomain(context.Background(), "example.com")
+	cfg.SourceError(other, "AlienVault", errors.New("429 Too Many Requests"))
+	remove()
+	cfg.SourceError(ctx, "AlienVault", errors.New("500 Internal Server Error"))
+	close(done)
+
+	var names, errs int
+	for ev := range events {
+		switch v := ev.(type) {
+		case *NameEvent:
+			names++
+			if v.Name != "www.owasp.org" {
+				t.Errorf("Unexpected name in the event: %s", v.Name)
+			}
+		case *SourceErrorEvent:
+			errs++
+			if v.Source != "AlienVault" || !strings.Contains(v.Err.Error(), "403 Forbidden") {
+				t.Errorf("Unexpected data source error event: %s: %v", v.Source, v.Err)
+			}
+		}
+	}
+	if names != 1 || errs != 1 {
+		t.Errorf("Expected one name and one error event, got %d and %d", names, errs)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 5 {
+		t.Errorf("Expected all the messages to be written to the log, got %d", lines)
+	}
+	// Events are no longer accepted after the stream has been closed
+	e.emit(&NameEvent{Time: time.Now(), Name: "ftp.owasp.org"})
+	if e.events.Len() != 0 {
+		t.Error("An event was accepted after the stream was closed")
+	}
+}
+
+func TestResolvedEvents(t *testing.T) {
+	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer graph.Close()
+
+	e := &Enumeration{
+		Config:     config.NewConfig(),
+		graph:      graph,
+		events:     queue.NewQueue(),
+		eventsDone: make(chan struct{}),
+		written:    make(map[string][]requests.DNSAnswer),
+	}
+	e.Config.AddDomain("owasp.org")
+	events := e.Events()
+	dm := &dataManager{enum: e}
+
+	caa := func(name, data string) *requests.DNSRequest {
+		return &requests.DNSRequest{
+			Name:    name,
+			Domain:  "owasp.org",
+			Source:  "DNS",
+			Records: []requests.DNSAnswer{{Name: name, Type: int(dns.TypeCAA), Data: data}},
+		}
+	}
+
+	ctx := context.Background()
+	if err := dm.dnsRequest(ctx, caa("www.owasp.org", "0 issue letsencrypt.org"), nil); err != nil {
+		t.Fatalf("Failed to store the records: %v", err)
+	}
+	// The records of out-of-scope names are stored without being reported
+	if err := dm.dnsRequest(ctx, caa("www.example.com", "0 issue letsencrypt.org"), nil); err != nil {
+		t.Fatalf("Failed to store the records: %v", err)
+	}
+	// The records are not reported when they could not be written into the graph
+	if err := dm.dnsRequest(ctx, caa("ftp.owasp.org", ""), nil); err == nil {
+		t.Error("The empty record was written into the graph")
+	}
+
+	done := make(chan struct{})
+	go e.forwardEvents(done)
+	close(done)
+
+	var resolved []string
+	for ev := range events {
+		if re, ok := ev.(*ResolvedEvent); ok {
+			resolved = append(resolved, re.Name)
+		}
+	}
+	if len(resolved) != 1 || resolved[0] != "www.owasp.org" {
+		t.Errorf("Expected only the records of www.owasp.org to be reported, got %v", resolved)
+	}
+}
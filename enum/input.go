@@ -8,6 +8,7 @@ import (
 	"context"
 	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -46,6 +47,18 @@ type enumSource struct {
 	max         int
 	countLock   sync.Mutex
 	count       uint32
+	// The requests appended to the queue that the pipeline has not finished with
+	waitLock sync.Mutex
+	waiting  map[string]*pendingReq
+	waitSeq  uint64
+}
+
+// pendingReq tracks a request from the time it is appended to the queue until the pipeline
+// either stores or drops it.
+type pendingReq struct {
+	data  pipeline.Data
+	seq   uint64
+	count int
 }
 
 // newEnumSource returns an initialized input source for the enumeration pipeline.
@@ -65,6 +78,10 @@ func newEnumSource(p *pipeline.Pipeline, e *Enumeration) *enumSource {
 		release:     make(chan struct{}, size),
 		inputsig:    make(chan uint32, size*2),
 		max:         size,
+		waiting:     make(map[string]*pendingReq),
+	}
+	for _, src := range e.dataSources() {
+		subscribeDataSrcOutput(src, r)
 	}
 	// Monitor the enumeration for completion or termination
 	go func() {
@@ -74,11 +91,11 @@ func newEnumSource(p *pipeline.Pipeline, e *Enumeration) *enumSource {
 		case <-r.enum.done:
 			r.markDone()
 		}
-	}()
 
-	for _, src := range e.srcs {
-		go r.monitorDataSrcOutput(src)
-	}
+		for _, src := range e.dataSources() {
+			unsubscribeDataSrcOutput(src, r)
+		}
+	}()
 	for i := 0; i < size; i++ {
 		r.release <- struct{}{}
 	}
@@ -90,6 +107,9 @@ func newEnumSource(p *pipeline.Pipeline, e *Enumeration) *enumSource {
 func (r *enumSource) Stop() {
 	r.markDone()
 	r.queue.Process(func(e interface{}) {})
+	r.waitLock.Lock()
+	r.waiting = make(map[string]*pendingReq)
+	r.waitLock.Unlock()
 	r.dups.Process(func(e interface{}) {})
 	r.sweeps.Process(func(e interface{}) {})
 	r.filter.Reset()
@@ -129,8 +149,16 @@ func (r *enumSource) newName(req *requests.DNSRequest) {
 			return
 		}
 	}
+	// The names generated by brute forcing and alterations are tried against the NSEC3 hashes,
+	// and are not queried when a complete chain proves that they do not exist
+	if (req.Tag == requests.BRUTE || req.Tag == requests.ALT) && r.enum.nsec3 != nil {
+		if !r.enum.nsec3.crack(req.Name) && r.enum.nsec3.absent(req.Name) {
+			return
+		}
+	}
 	if r.accept(req.Name, req.Tag, req.Source, true) {
-		r.queue.Append(req)
+		r.enqueue(req)
+		namesDiscovered.WithLabelValues(req.Tag).Inc()
 	}
 }
 
@@ -145,7 +173,7 @@ func (r *enumSource) newAddr(req *requests.AddrRequest) {
 		return
 	}
 
-	r.queue.Append(req)
+	r.enqueue(req)
 	// Does the address fall into a reserved address range?
 	if reserved, _ := amassnet.IsReservedAddress(req.Address); !reserved {
 		// Queue the request for later use in reverse DNS sweeps
@@ -153,6 +181,82 @@ func (r *enumSource) newAddr(req *requests.AddrRequest) {
 	}
 }
 
+// enqueue appends the request to the queue and tracks it until the pipeline finishes with it.
+func (r *enumSource) enqueue(req pipeline.Data) {
+	if k := pendingKey(req); k != "" {
+		r.waitLock.Lock()
+		if r.waiting == nil {
+			r.waiting = make(map[string]*pendingReq)
+		}
+		if p, found := r.waiting[k]; found {
+			p.count++
+		} else {
+			r.waitSeq++
+			r.waiting[k] = &pendingReq{
+				data:  req,
+				seq:   r.waitSeq,
+				count: 1,
+			}
+		}
+		r.waitLock.Unlock()
+	}
+
+	r.queue.Append(req)
+}
+
+// finished stops tracking the request once the pipeline has stored or dropped it.
+func (r *enumSource) finished(data pipeline.Data) {
+	k := pendingKey(data)
+	if r == nil || k == "" {
+		return
+	}
+
+	r.waitLock.Lock()
+	defer r.waitLock.Unlock()
+
+	if p, found := r.waiting[k]; found {
+		if p.count--; p.count <= 0 {
+			delete(r.waiting, k)
+		}
+	}
+}
+
+// pending returns a snapshot of the requests that the pipeline has not finished with,
+// whether waiting in the queue or still being processed, without touching the queue.
+func (r *enumSource) pending() []pipeline.Data {
+	r.waitLock.Lock()
+	defer r.waitLock.Unlock()
+
+	reqs := make([]*pendingReq, 0, len(r.waiting))
+	for _, p := range r.waiting {
+		reqs = append(reqs, p)
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].seq < reqs[j].seq
+	})
+
+	elements := make([]pipeline.Data, 0, len(reqs))
+	for _, p := range reqs {
+		elements = append(elements, p.data)
+	}
+	return elements
+}
+
+// pendingKey returns the key identifying the request, and its clones, while in the pipeline.
+func pendingKey(data pipeline.Data) string {
+	switch v := data.(type) {
+	case *requests.DNSRequest:
+		if v != nil {
+			return "name:" + strings.ToLower(v.Name)
+		}
+	case *requests.AddrRequest:
+		if v != nil {
+			return "addr:" + v.Address
+		}
+	}
+	return ""
+}
+
 func (r *enumSource) accept(s, tag, source string, name bool) bool {
 	trusted := requests.TrustedTag(tag)
 	// Do not submit names from untrusted sources, after already receiving the name
@@ -273,30 +377,32 @@ func (r *enumSource) releaseOutput(num int) {
 	}
 }
 
-func (r *enumSource) monitorDataSrcOutput(srv service.Service) {
-	for {
-		select {
-		case <-r.done:
-			return
-		case <-srv.Done():
-			return
-		case in := <-srv.Output():
-			select {
-			case <-r.done:
-				return
-			case <-srv.Done():
-				return
-			case <-r.release:
-			}
+func (r *enumSource) dataSrcOutput(srv service.Service, in interface{}) {
+	select {
+	case <-r.done:
+		return
+	case <-srv.Done():
+		return
+	case <-r.release:
+	}
 
-			switch req := in.(type) {
-			case *requests.DNSRequest:
-				r.newName(req)
-			case *requests.AddrRequest:
-				r.newAddr(req)
-			}
+	switch req := in.(type) {
+	case *requests.DNSRequest:
+		r.newName(req)
+		if req.Valid() && r.enum.Config.IsDomainInScope(req.Name) {
+			r.enum.stats.name(dataSrcName(srv, req.Source), req.Name)
 		}
+	case *requests.AddrRequest:
+		r.newAddr(req)
+		r.enum.stats.activity(dataSrcName(srv, req.Source))
+	}
+}
+
+func dataSrcName(srv service.Service, source string) string {
+	if source != "" {
+		return source
 	}
+	return srv.String()
 }
 
 func (r *enumSource) requestSweeps() {
@@ -331,7 +437,7 @@ func (r *enumSource) sweepAddrs(ctx context.Context, req *requests.AddrRequest)
 
 		if a := ip.String(); !r.sweepFilter.TestAndAdd([]byte(a)) {
 			count++
-			r.queue.Append(&requests.AddrRequest{
+			r.enqueue(&requests.AddrRequest{
 				Address: a,
 				Domain:  req.Domain,
 				Tag:     req.Tag,
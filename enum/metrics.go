@@ -0,0 +1,191 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"sync"
+	"time"
+
+	amassdns "github.com/OWASP/Amass/v3/net/dns"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/resolve"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "amass"
+
+var (
+	namesDiscovered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "names_discovered_total",
+		Help:      "Number of new names discovered by the enumerations, labeled by tag.",
+	}, []string{"tag"})
+	dnsQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dns_queries_total",
+		Help:      "Number of DNS queries sent to the resolver pools.",
+	}, []string{"pool"})
+	dnsTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dns_timeouts_total",
+		Help:      "Number of DNS queries that did not receive a response from the resolver pools.",
+	}, []string{"pool"})
+	graphWriteSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "graph_write_duration_seconds",
+		Help:      "Latency of the writes into the enumeration graph.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	})
+)
+
+var (
+	activeLock  sync.Mutex
+	activeEnums = make(map[*Enumeration]struct{})
+)
+
+// resolverStats is implemented by the Systems that count the queries and timeouts of each resolver.
+type resolverStats interface {
+	ResolverStats() map[string][]amassdns.ResolverStats
+}
+
+// enumCollector reports the state of the running enumerations and the System each time it is scraped.
+type enumCollector struct {
+	sys       systems.System
+	queueLen  *prometheus.Desc
+	poolSize  *prometheus.Desc
+	poolQPS   *prometheus.Desc
+	resQuery  *prometheus.Desc
+	resTime   *prometheus.Desc
+	memory    *prometheus.Desc
+	numActive *prometheus.Desc
+}
+
+// RegisterMetrics registers the collectors that expose the live telemetry of the enumerations
+// executed using the provided System.
+func RegisterMetrics(reg prometheus.Registerer, sys systems.System) error {
+	collectors := []prometheus.Collector{
+		namesDiscovered,
+		dnsQueries,
+		dnsTimeouts,
+		graphWriteSeconds,
+		newEnumCollector(sys),
+	}
+
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newEnumCollector(sys systems.System) *enumCollector {
+	return &enumCollector{
+		sys: sys,
+		queueLen: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "queue_length"),
+			"Number of elements waiting in the enumeration queues.", []string{"uuid", "queue"}, nil),
+		poolSize: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "resolvers"),
+			"Number of DNS resolvers in the pool.", []string{"pool"}, nil),
+		poolQPS: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "resolvers_max_qps"),
+			"Maximum number of DNS queries per second allowed for the pool.", []string{"pool"}, nil),
+		resQuery: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "resolver_queries_total"),
+			"Number of DNS queries sent to each resolver.", []string{"pool", "resolver"}, nil),
+		resTime: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "resolver_timeouts_total"),
+			"Number of DNS queries that did not receive a response from each resolver.", []string{"pool", "resolver"}, nil),
+		memory: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "memory_usage_bytes"),
+			"Number of bytes allocated to heap objects on the system.", nil, nil),
+		numActive: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "enumerations_running"),
+			"Number of enumerations currently running.", nil, nil),
+	}
+}
+
+// Describe implements the prometheus Collector interface.
+func (c *enumCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueLen
+	ch <- c.poolSize
+	ch <- c.poolQPS
+	ch <- c.resQuery
+	ch <- c.resTime
+	ch <- c.memory
+	ch <- c.numActive
+}
+
+// Collect implements the prometheus Collector interface.
+func (c *enumCollector) Collect(ch chan<- prometheus.Metric) {
+	activeLock.Lock()
+	enums := make([]*Enumeration, 0, len(activeEnums))
+	for e := range activeEnums {
+		enums = append(enums, e)
+	}
+	activeLock.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.numActive, prometheus.GaugeValue, float64(len(enums)))
+	for _, e := range enums {
+		for queue, length := range e.queueLengths() {
+			ch <- prometheus.MustNewConstMetric(c.queueLen,
+				prometheus.GaugeValue, float64(length), e.Config.UUID.String(), queue)
+		}
+	}
+
+	if c.sys == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.memory, prometheus.GaugeValue, float64(c.sys.GetMemoryUsage()))
+	for name, pool := range map[string]*resolve.Resolvers{
+		"untrusted": c.sys.Resolvers(),
+		"trusted":   c.sys.TrustedResolvers(),
+	} {
+		if pool == nil {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.poolSize, prometheus.GaugeValue, float64(pool.Len()), name)
+		ch <- prometheus.MustNewConstMetric(c.poolQPS, prometheus.GaugeValue, float64(pool.QPS()), name)
+	}
+
+	rs, ok := c.sys.(resolverStats)
+	if !ok {
+		return
+	}
+	for pool, stats := range rs.ResolverStats() {
+		for _, st := range stats {
+			ch <- prometheus.MustNewConstMetric(c.resQuery, prometheus.CounterValue, float64(st.Queries), pool, st.Resolver)
+			ch <- prometheus.MustNewConstMetric(c.resTime, prometheus.CounterValue, float64(st.Timeouts), pool, st.Resolver)
+		}
+	}
+}
+
+func (e *Enumeration) setActive(active bool) {
+	activeLock.Lock()
+	defer activeLock.Unlock()
+
+	if active {
+		activeEnums[e] = struct{}{}
+	} else {
+		delete(activeEnums, e)
+	}
+}
+
+func (e *Enumeration) queueLengths() map[string]int {
+	lengths := map[string]int{
+		"datasrc_requests": e.requests.Len(),
+		"input":            e.nameSrc.queue.Len(),
+	}
+
+	if e.dnsTask != nil {
+		lengths["dns"] = e.dnsTask.pending()
+	}
+	if e.valTask != nil {
+		lengths["validate"] = e.valTask.pending()
+	}
+	if e.store != nil {
+		lengths["store"] = e.store.queue.Len()
+	}
+	return lengths
+}
+
+func observeGraphWrite(start time.Time) {
+	graphWriteSeconds.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,118 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	amassdns "github.com/OWASP/Amass/v3/net/dns"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/queue"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if err := RegisterMetrics(reg, nil); err != nil {
+		t.Fatalf("Failed to register the metrics: %v", err)
+	}
+
+	e := &Enumeration{
+		Config:   config.NewConfig(),
+		requests: queue.NewQueue(),
+		nameSrc:  &enumSource{queue: queue.NewQueue()},
+	}
+	e.requests.Append(&requests.ASNRequest{ASN: 26808})
+	e.nameSrc.queue.Append(&requests.DNSRequest{Name: "www.owasp.org", Domain: "owasp.org"})
+	e.nameSrc.queue.Append(&requests.DNSRequest{Name: "ftp.owasp.org", Domain: "owasp.org"})
+	namesDiscovered.WithLabelValues(requests.CERT).Inc()
+
+	e.setActive(true)
+	defer e.setActive(false)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather the metrics: %v", err)
+	}
+
+	queues := make(map[string]float64)
+	var running float64
+	for _, f := range families {
+		switch f.GetName() {
+		case "amass_enumerations_running":
+			running = f.GetMetric()[0].GetGauge().GetValue()
+		case "amass_queue_length":
+			for _, m := range f.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "queue" {
+						queues[l.GetValue()] = m.GetGauge().GetValue()
+					}
+				}
+			}
+		}
+	}
+
+	if running != 1 {
+		t.Errorf("Expected one running enumeration, got %v", running)
+	}
+	if queues["datasrc_requests"] != 1 || queues["input"] != 2 {
+		t.Errorf("Unexpected queue lengths: %v", queues)
+	}
+	if _, found := queues["dns"]; found {
+		t.Error("The DNS queue was reported for an enumeration without DNS tasks")
+	}
+}
+
+type statsSystem struct {
+	*systems.SimpleSystem
+}
+
+func (s *statsSystem) ResolverStats() map[string][]amassdns.ResolverStats {
+	return map[string][]amassdns.ResolverStats{
+		"untrusted": {
+			{Resolver: "192.0.2.1", Queries: 10, Timeouts: 3},
+			{Resolver: "tls://192.0.2.2:853", Queries: 5},
+		},
+	}
+}
+
+func TestResolverMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(newEnumCollector(&statsSystem{SimpleSystem: &systems.SimpleSystem{}})); err != nil {
+		t.Fatalf("Failed to register the collector: %v", err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather the metrics: %v", err)
+	}
+
+	counts := make(map[string]float64)
+	for _, f := range families {
+		switch f.GetName() {
+		case "amass_resolver_queries_total", "amass_resolver_timeouts_total":
+			for _, m := range f.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "resolver" {
+						counts[f.GetName()+" "+l.GetValue()] = m.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+	}
+
+	expected := map[string]float64{
+		"amass_resolver_queries_total 192.0.2.1":            10,
+		"amass_resolver_timeouts_total 192.0.2.1":           3,
+		"amass_resolver_queries_total tls://192.0.2.2:853":  5,
+		"amass_resolver_timeouts_total tls://192.0.2.2:853": 0,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Unexpected resolver metrics: %v", counts)
+	}
+}
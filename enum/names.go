@@ -19,6 +19,7 @@ type subdomainTask struct {
 	cnames          *stringset.Set
 	withinWildcards *stringset.Set
 	timesChan       chan *timesReq
+	timesSnapshot   chan chan map[string]int
 	done            chan struct{}
 }
 
@@ -29,10 +30,18 @@ func newSubdomainTask(e *Enumeration) *subdomainTask {
 		cnames:          stringset.New(),
 		withinWildcards: stringset.New(),
 		timesChan:       make(chan *timesReq, 10),
+		timesSnapshot:   make(chan chan map[string]int),
 		done:            make(chan struct{}, 2),
 	}
 
-	go r.timesManager()
+	subdomains := make(map[string]int)
+	if e.checkpoint != nil {
+		for sub, times := range e.checkpoint.Subdomains {
+			subdomains[sub] = times
+		}
+	}
+
+	go r.timesManager(subdomains)
 	return r
 }
 
@@ -152,13 +161,29 @@ type timesReq struct {
 	Ch  chan int
 }
 
-func (r *subdomainTask) timesManager() {
-	subdomains := make(map[string]int)
+// subdomainTimes returns a copy of the counters maintained for each subdomain.
+func (r *subdomainTask) subdomainTimes() map[string]int {
+	ch := make(chan map[string]int, 1)
 
+	select {
+	case <-r.done:
+		return nil
+	case r.timesSnapshot <- ch:
+	}
+	return <-ch
+}
+
+func (r *subdomainTask) timesManager(subdomains map[string]int) {
 	for {
 		select {
 		case <-r.done:
 			return
+		case ch := <-r.timesSnapshot:
+			times := make(map[string]int, len(subdomains))
+			for sub, t := range subdomains {
+				times[sub] = t
+			}
+			ch <- times
 		case req := <-r.timesChan:
 			times, found := subdomains[req.Sub]
 			if found {
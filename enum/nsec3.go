@@ -0,0 +1,325 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/resolve"
+	"github.com/miekg/dns"
+)
+
+const (
+	// The maximum number of queries sent to collect the NSEC3 chain of a zone
+	maxNSEC3Queries int = 1000
+	// The collection stops after this many consecutive queries revealed no new hashes
+	maxNSEC3Misses int = 25
+	// The number of random names hashed while looking for a gap not yet covered by the chain
+	maxNSEC3Guesses int = 10000
+	// The queries answered without NSEC3 records before deciding the zone does not use them
+	maxNSEC3Probes int = 3
+)
+
+const nsec3LabelChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// nsec3Chain contains the NSEC3 records collected for a zone.
+type nsec3Chain struct {
+	zone       string
+	hash       uint8
+	salt       string
+	iterations uint16
+	// Set when the chain may not include the insecure delegations of the zone
+	optOut bool
+	// The owner hash of each record mapped to the next hash in the chain
+	next map[string]string
+}
+
+func newNSEC3Chain(zone string) *nsec3Chain {
+	return &nsec3Chain{
+		zone: strings.ToLower(resolve.RemoveLastDot(zone)),
+		next: make(map[string]string),
+	}
+}
+
+func (c *nsec3Chain) hashName(name string) string {
+	return dns.HashName(dns.Fqdn(name), c.hash, c.iterations, c.salt)
+}
+
+// Adds the NSEC3 records found in the response and returns the number of new hashes.
+func (c *nsec3Chain) add(resp *dns.Msg) int {
+	var added int
+
+	for _, rr := range append(resp.Answer, resp.Ns...) {
+		nsec3, ok := rr.(*dns.NSEC3)
+		if !ok {
+			continue
+		}
+
+		salt := nsec3.Salt
+		if salt == "-" {
+			salt = ""
+		}
+		// The parameters of the chain are learned from the first record
+		if len(c.next) == 0 {
+			c.hash = nsec3.Hash
+			c.salt = salt
+			c.iterations = nsec3.Iterations
+		} else if nsec3.Hash != c.hash || !strings.EqualFold(salt, c.salt) || nsec3.Iterations != c.iterations {
+			continue
+		}
+
+		if nsec3.Flags&nsec3OptOut != 0 {
+			c.optOut = true
+		}
+
+		owner := strings.ToUpper(dns.SplitDomainName(nsec3.Hdr.Name)[0])
+		if _, found := c.next[owner]; !found {
+			c.next[owner] = strings.ToUpper(nsec3.NextDomain)
+			added++
+		}
+	}
+	return added
+}
+
+// Returns true when the hash belongs to a record of the chain or falls within the gap it covers.
+func (c *nsec3Chain) covered(hash string) bool {
+	if _, found := c.next[hash]; found {
+		return true
+	}
+
+	for owner, next := range c.next {
+		if owner < next {
+			if hash > owner && hash < next {
+				return true
+			}
+		} else if hash > owner || hash < next {
+			// The last record of the chain wraps around to the first hash
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true when the hash of the name, or of one of its ancestors below the zone apex, belongs to the chain.
+func (c *nsec3Chain) matches(name string) bool {
+	for n := name; ; {
+		if _, found := c.next[c.hashName(n)]; found {
+			return true
+		}
+
+		idx := strings.Index(n, ".")
+		if n == c.zone || idx < 0 {
+			return false
+		}
+		if n = n[idx+1:]; n == c.zone {
+			return false
+		}
+	}
+}
+
+// Returns true when the next hash of every record has been collected, which closes the chain.
+func (c *nsec3Chain) complete() bool {
+	if len(c.next) == 0 {
+		return false
+	}
+
+	for _, next := range c.next {
+		if _, found := c.next[next]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a random name within the zone that hashes into a gap not yet covered by the chain.
+func (c *nsec3Chain) uncoveredName() (string, bool) {
+	for i := 0; i < maxNSEC3Guesses; i++ {
+		label := make([]byte, 12)
+		for j := range label {
+			label[j] = nsec3LabelChars[rand.Intn(len(nsec3LabelChars))]
+		}
+
+		name := string(label) + "." + c.zone
+		if len(c.next) == 0 || !c.covered(c.hashName(name)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// collectNSEC3 queries random names within the zone, selected to land in the gaps of the chain
+// collected so far, until the NSEC3 chain is complete or the queries stop revealing new hashes.
+func collectNSEC3(ctx context.Context, zone string, query func(context.Context, *dns.Msg) (*dns.Msg, error)) (*nsec3Chain, error) {
+	chain := newNSEC3Chain(zone)
+
+	var misses int
+	for num := 0; num < maxNSEC3Queries && misses < maxNSEC3Misses && !chain.complete(); num++ {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("the context expired while collecting the NSEC3 records of %s", zone)
+		default:
+		}
+
+		name, found := chain.uncoveredName()
+		if !found {
+			break
+		}
+
+		resp, err := query(ctx, resolve.WalkMsg(name, dns.TypeA))
+		if err != nil || resp == nil {
+			misses++
+			continue
+		}
+		if chain.add(resp) == 0 {
+			misses++
+			// Give up early on zones that do not use NSEC3
+			if len(chain.next) == 0 && misses >= maxNSEC3Probes {
+				break
+			}
+			continue
+		}
+		misses = 0
+	}
+
+	if len(chain.next) == 0 {
+		return nil, fmt.Errorf("no NSEC3 records were found for %s", zone)
+	}
+	return chain, nil
+}
+
+// nsec3Cracker recovers the names hidden by the NSEC3 chains by hashing candidate names offline.
+type nsec3Cracker struct {
+	sync.Mutex
+	enum    *Enumeration
+	chains  map[string]*nsec3Chain
+	cracked map[string]struct{}
+}
+
+func newNSEC3Cracker(e *Enumeration) *nsec3Cracker {
+	return &nsec3Cracker{
+		enum:    e,
+		chains:  make(map[string]*nsec3Chain),
+		cracked: make(map[string]struct{}),
+	}
+}
+
+// claim returns true when the zone is not already being walked through one of its other nameservers.
+func (c *nsec3Cracker) claim(zone string) bool {
+	zone = strings.ToLower(resolve.RemoveLastDot(zone))
+
+	c.Lock()
+	defer c.Unlock()
+
+	if _, found := c.chains[zone]; found {
+		return false
+	}
+	c.chains[zone] = nil
+	return true
+}
+
+// release allows the zone to be walked through another nameserver after the collection failed.
+func (c *nsec3Cracker) release(zone string) {
+	zone = strings.ToLower(resolve.RemoveLastDot(zone))
+
+	c.Lock()
+	defer c.Unlock()
+
+	if chain, found := c.chains[zone]; found && chain == nil {
+		delete(c.chains, zone)
+	}
+}
+
+// addChain makes the chain available for cracking and tries the brute forcing wordlist against it.
+// The embedded wordlist is used when brute forcing has not provided one.
+func (c *nsec3Cracker) addChain(chain *nsec3Chain) {
+	c.Lock()
+	c.chains[chain.zone] = chain
+	c.Unlock()
+
+	c.crack(chain.zone)
+	words := c.enum.Config.Wordlist
+	if len(words) == 0 {
+		var err error
+
+		words, err = config.DefaultWordlist()
+		if err != nil {
+			c.enum.Config.Log.Printf("Failed to obtain the wordlist for cracking the NSEC3 hashes of %s: %v", chain.zone, err)
+		}
+	}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			c.crack(word + "." + chain.zone)
+		}
+	}
+}
+
+// Returns the chains collected for the zones containing the name.
+func (c *nsec3Cracker) chainsOf(name string) []*nsec3Chain {
+	c.Lock()
+	defer c.Unlock()
+
+	var chains []*nsec3Chain
+	for zone, chain := range c.chains {
+		if chain != nil && (name == zone || strings.HasSuffix(name, "."+zone)) {
+			chains = append(chains, chain)
+		}
+	}
+	return chains
+}
+
+// absent returns true when a complete NSEC3 chain proves that the name does not exist, since neither
+// the name nor its ancestors within the zone match a hash of the chain. The ancestors are checked,
+// since the names below a delegation are not included in the chain of the parent zone.
+func (c *nsec3Cracker) absent(name string) bool {
+	name = strings.ToLower(resolve.RemoveLastDot(name))
+
+	for _, chain := range c.chainsOf(name) {
+		if !chain.optOut && chain.complete() && !chain.matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// crack hashes the name using the parameters of the chains for the zones containing the name,
+// and submits the name to the enumeration the first time it matches a hash collected from a chain.
+func (c *nsec3Cracker) crack(name string) bool {
+	name = strings.ToLower(resolve.RemoveLastDot(name))
+
+	for _, chain := range c.chainsOf(name) {
+		hash := chain.hashName(name)
+		if _, found := chain.next[hash]; !found {
+			continue
+		}
+
+		key := chain.zone + ":" + hash
+		c.Lock()
+		_, found := c.cracked[key]
+		if !found {
+			c.cracked[key] = struct{}{}
+		}
+		c.Unlock()
+		if found {
+			return false
+		}
+
+		if domain := c.enum.Config.WhichDomain(name); domain != "" && c.enum.nameSrc != nil {
+			c.enum.nameSrc.newName(&requests.DNSRequest{
+				Name:   name,
+				Domain: domain,
+				Tag:    requests.NSEC3,
+				Source: "NSEC3 Walk",
+			})
+		}
+		return true
+	}
+	return false
+}
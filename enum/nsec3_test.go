@@ -0,0 +1,128 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/miekg/dns"
+)
+
+// Returns a query function that answers as the authoritative server of a zone signed with NSEC3.
+func nsec3Zone(zone string, names []string, salt string, iterations uint16) func(context.Context, *dns.Msg) (*dns.Msg, error) {
+	var hashes []string
+	for _, name := range names {
+		hashes = append(hashes, dns.HashName(dns.Fqdn(name), dns.SHA1, iterations, salt))
+	}
+	sort.Strings(hashes)
+
+	records := make(map[string]*dns.NSEC3)
+	for i, hash := range hashes {
+		records[hash] = &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: hash + "." + dns.Fqdn(zone), Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 3600},
+			Hash:       dns.SHA1,
+			Iterations: iterations,
+			SaltLength: uint8(len(salt) / 2),
+			Salt:       salt,
+			HashLength: 20,
+			NextDomain: hashes[(i+1)%len(hashes)],
+			TypeBitMap: []uint16{dns.TypeA},
+		}
+	}
+
+	return func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetRcode(msg, dns.RcodeNameError)
+
+		hash := dns.HashName(msg.Question[0].Name, dns.SHA1, iterations, salt)
+		// Find the record covering the hash of the query name
+		idx := sort.SearchStrings(hashes, hash)
+		if idx == len(hashes) || hashes[idx] != hash {
+			idx = (idx + len(hashes) - 1) % len(hashes)
+		}
+		resp.Ns = append(resp.Ns, records[hashes[idx]])
+		return resp, nil
+	}
+}
+
+func TestCollectNSEC3(t *testing.T) {
+	names := []string{"owasp.org", "www.owasp.org", "mail.owasp.org", "dev-api.owasp.org", "vpn.owasp.org", "a.b.owasp.org"}
+	query := nsec3Zone("owasp.org", names, "AABBCCDD", 5)
+
+	chain, err := collectNSEC3(context.Background(), "owasp.org", query)
+	if err != nil {
+		t.Fatalf("Failed to collect the NSEC3 chain: %v", err)
+	}
+	if !chain.complete() || len(chain.next) != len(names) {
+		t.Errorf("Expected the complete chain of %d hashes, got %d", len(names), len(chain.next))
+	}
+	if chain.salt != "AABBCCDD" || chain.iterations != 5 || chain.hash != dns.SHA1 {
+		t.Errorf("The parameters of the chain were not collected")
+	}
+
+	empty := func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetRcode(msg, dns.RcodeNameError)
+		return resp, nil
+	}
+	if _, err := collectNSEC3(context.Background(), "example.org", empty); err == nil {
+		t.Errorf("A chain was collected from a zone without NSEC3 records")
+	}
+}
+
+func TestCrackNSEC3(t *testing.T) {
+	names := []string{"owasp.org", "www.owasp.org", "mail.owasp.org", "dev-api.owasp.org"}
+	query := nsec3Zone("owasp.org", names, "", 0)
+
+	chain, err := collectNSEC3(context.Background(), "owasp.org", query)
+	if err != nil {
+		t.Fatalf("Failed to collect the NSEC3 chain: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	cfg.Wordlist = []string{"www", "ftp", "mail"}
+
+	c := newNSEC3Cracker(&Enumeration{Config: cfg})
+	if !c.claim("owasp.org") || c.claim("owasp.org.") {
+		t.Errorf("The zone was walked more than once")
+	}
+	c.addChain(chain)
+	if n := len(c.cracked); n != 3 {
+		t.Errorf("Expected the zone and two words to be cracked, got %d", n)
+	}
+	// The names produced by alterations are cracked as they are generated
+	if !c.crack("dev-api.owasp.org") {
+		t.Errorf("Failed to crack the hash of an altered name")
+	}
+	if c.crack("dev-api.owasp.org") || c.crack("test.owasp.org") || c.crack("www.example.org") {
+		t.Errorf("A name was cracked that was not expected")
+	}
+
+	// The complete chain proves the names without a matching hash do not exist
+	if !c.absent("test.owasp.org") {
+		t.Errorf("The name missing from the complete chain was not reported absent")
+	}
+	for _, name := range []string{"owasp.org", "www.owasp.org", "host.www.owasp.org", "www.example.org"} {
+		if c.absent(name) {
+			t.Errorf("The name %s was reported absent", name)
+		}
+	}
+	chain.optOut = true
+	if c.absent("test.owasp.org") {
+		t.Errorf("The name was reported absent from a chain that can omit the insecure delegations")
+	}
+
+	// The embedded wordlist is used when brute forcing has not provided one
+	cfg.Wordlist = nil
+	c = newNSEC3Cracker(&Enumeration{Config: cfg})
+	c.addChain(chain)
+	if n := len(c.cracked); n != 3 {
+		t.Errorf("Expected the zone and two words of the embedded wordlist to be cracked, got %d", n)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// The types of records written by the RecordWriter.
+const (
+	FQDNRecordType     = "fqdn"
+	DNSRecordType      = "dns_record"
+	NetblockRecordType = "netblock"
+	ASNRecordType      = "asn"
+	DNSSECRecordType   = "dnssec"
+)
+
+// FQDNRecord reports a name discovered by the enumeration.
+type FQDNRecord struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"timestamp"`
+	Name   string    `json:"name"`
+	Domain string    `json:"domain"`
+	Tag    string    `json:"tag"`
+	Source string    `json:"source"`
+}
+
+// DNSRecord reports a DNS resource record obtained for a discovered name.
+type DNSRecord struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"timestamp"`
+	Name   string    `json:"name"`
+	Domain string    `json:"domain"`
+	RRName string    `json:"rrname"`
+	RRType string    `json:"rrtype"`
+	TTL    int       `json:"ttl"`
+	Data   string    `json:"data"`
+	Source string    `json:"source"`
+}
+
+// NetblockRecord reports the netblock and autonomous system an address belongs to.
+type NetblockRecord struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"timestamp"`
+	Address string    `json:"address"`
+	CIDR    string    `json:"cidr"`
+	ASN     int       `json:"asn"`
+	Source  string    `json:"source"`
+}
+
+// ASNRecord reports a netblock announced by an autonomous system the first time it is learned.
+type ASNRecord struct {
+	Type        string    `json:"type"`
+	Time        time.Time `json:"timestamp"`
+	ASN         int       `json:"asn"`
+	CIDR        string    `json:"cidr"`
+	Description string    `json:"description"`
+	Source      string    `json:"source"`
+}
+
+// DNSSECRecord reports the DNSSEC validation status of an in-scope zone.
+type DNSSECRecord struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"timestamp"`
+	Zone      string    `json:"zone"`
+	Domain    string    `json:"domain"`
+	Status    string    `json:"status"`
+	Algorithm string    `json:"algorithm,omitempty"`
+}
+
+// RecordWriter writes the events of an enumeration as typed NDJSON records,
+// so downstream tools can act on the findings as soon as they are stored.
+type RecordWriter struct {
+	sync.Mutex
+	enc       *json.Encoder
+	netblocks map[string]struct{}
+}
+
+// NewRecordWriter returns a RecordWriter that writes a record per line to w.
+func NewRecordWriter(w io.Writer) *RecordWriter {
+	return &RecordWriter{
+		enc:       json.NewEncoder(w),
+		netblocks: make(map[string]struct{}),
+	}
+}
+
+// Write converts the event into typed records and writes them. Events without
+// an associated record type are ignored.
+func (rw *RecordWriter) Write(ev Event) error {
+	rw.Lock()
+	defer rw.Unlock()
+
+	for _, rec := range rw.records(ev) {
+		if err := rw.enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rw *RecordWriter) records(ev Event) []interface{} {
+	var recs []interface{}
+
+	switch v := ev.(type) {
+	case *NameEvent:
+		recs = append(recs, &FQDNRecord{
+			Type:   FQDNRecordType,
+			Time:   v.Time,
+			Name:   v.Name,
+			Domain: v.Domain,
+			Tag:    v.Tag,
+			Source: v.Source,
+		})
+	case *ResolvedEvent:
+		for _, rr := range v.Records {
+			rrtype, found := dns.TypeToString[uint16(rr.Type)]
+			if !found {
+				rrtype = "TYPE" + strconv.Itoa(rr.Type)
+			}
+
+			recs = append(recs, &DNSRecord{
+				Type:   DNSRecordType,
+				Time:   v.Time,
+				Name:   v.Name,
+				Domain: v.Domain,
+				RRName: rr.Name,
+				RRType: rrtype,
+				TTL:    rr.TTL,
+				Data:   rr.Data,
+				Source: v.Source,
+			})
+		}
+	case *ASNEvent:
+		if key := strconv.Itoa(v.ASN) + v.Prefix; !rw.seen(key) {
+			recs = append(recs, &ASNRecord{
+				Type:        ASNRecordType,
+				Time:        v.Time,
+				ASN:         v.ASN,
+				CIDR:        v.Prefix,
+				Description: v.Description,
+				Source:      v.Source,
+			})
+		}
+		recs = append(recs, &NetblockRecord{
+			Type:    NetblockRecordType,
+			Time:    v.Time,
+			Address: v.Address,
+			CIDR:    v.Prefix,
+			ASN:     v.ASN,
+			Source:  v.Source,
+		})
+	case *DNSSECEvent:
+		recs = append(recs, &DNSSECRecord{
+			Type:      DNSSECRecordType,
+			Time:      v.Time,
+			Zone:      v.Zone,
+			Domain:    v.Domain,
+			Status:    v.Status,
+			Algorithm: v.Algorithm,
+		})
+	}
+	return recs
+}
+
+func (rw *RecordWriter) seen(key string) bool {
+	if _, found := rw.netblocks[key]; found {
+		return true
+	}
+
+	rw.netblocks[key] = struct{}{}
+	return false
+}
@@ -0,0 +1,91 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/queue"
+	"github.com/miekg/dns"
+)
+
+func TestRecordWriter(t *testing.T) {
+	var buf bytes.Buffer
+	rw := NewRecordWriter(&buf)
+	now := time.Now()
+
+	events := []Event{
+		&NameEvent{Time: now, Name: "www.owasp.org", Domain: "owasp.org", Tag: requests.CERT, Source: "Crtsh"},
+		&ResolvedEvent{Time: now, Name: "www.owasp.org", Domain: "owasp.org", Source: "Crtsh", Records: []requests.DNSAnswer{
+			{Name: "www.owasp.org", Type: int(dns.TypeA), TTL: 300, Data: "104.22.27.77"},
+			{Name: "www.owasp.org", Type: int(dns.TypeAAAA), TTL: 300, Data: "2606:4700:10::6816:1b4d"},
+		}},
+		&AddressEvent{Time: now, Name: "www.owasp.org", Domain: "owasp.org", Address: "104.22.27.77"},
+		&ASNEvent{Time: now, Address: "104.22.27.77", ASN: 13335, Prefix: "104.16.0.0/12", Description: "CLOUDFLARENET", Source: "RIR"},
+		&ASNEvent{Time: now, Address: "104.22.26.77", ASN: 13335, Prefix: "104.16.0.0/12", Description: "CLOUDFLARENET", Source: "RIR"},
+		&DNSSECEvent{Time: now, Zone: "owasp.org", Domain: "owasp.org", Status: DNSSECSecure, Algorithm: "ECDSAP256SHA256"},
+	}
+	for _, ev := range events {
+		if err := rw.Write(ev); err != nil {
+			t.Fatalf("Failed to write the %T: %v", ev, err)
+		}
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var rec struct {
+			Type   string `json:"type"`
+			RRType string `json:"rrtype"`
+		}
+
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Failed to decode the record %s: %v", scanner.Text(), err)
+		}
+		types = append(types, rec.Type+rec.RRType)
+	}
+
+	expected := []string{FQDNRecordType, DNSRecordType + "A", DNSRecordType + "AAAA",
+		ASNRecordType, NetblockRecordType, NetblockRecordType, DNSSECRecordType}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected the records %v, got %v", expected, types)
+	}
+	for i, typ := range expected {
+		if types[i] != typ {
+			t.Errorf("Expected the records %v, got %v", expected, types)
+			break
+		}
+	}
+}
+
+func TestNameWritten(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	e := &Enumeration{
+		Config:     cfg,
+		events:     queue.NewQueue(),
+		eventsDone: make(chan struct{}),
+		written:    make(map[string][]requests.DNSAnswer),
+	}
+	e.Events()
+
+	e.nameWritten(&requests.DNSRequest{Name: "www.owasp.org", Domain: "owasp.org", Tag: requests.CERT})
+	e.nameWritten(&requests.DNSRequest{Name: "www.owasp.org", Domain: "owasp.org", Tag: requests.DNS})
+	e.nameWritten(&requests.DNSRequest{Name: "77.27.22.104.in-addr.arpa", Tag: requests.DNS})
+
+	if n := e.events.Len(); n != 1 {
+		t.Fatalf("Expected a single name event, got %d", n)
+	}
+	if element, _ := e.events.Next(); element.(*NameEvent).Tag != requests.CERT {
+		t.Error("The name event was not produced by the first graph write")
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"sync"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/queue"
+	"github.com/caffix/service"
+)
+
+// dataSrcRouter distributes the output of a data source shared by concurrent enumerations.
+type dataSrcRouter struct {
+	sync.Mutex
+	srv  service.Service
+	subs map[*enumSource]*routerSub
+	done chan struct{}
+}
+
+// routerSub holds the output waiting for an enumeration, so a slow enumeration
+// cannot delay the delivery to the others.
+type routerSub struct {
+	queue queue.Queue
+	done  chan struct{}
+}
+
+var (
+	routersLock sync.Mutex
+	routers     = make(map[service.Service]*dataSrcRouter)
+)
+
+func subscribeDataSrcOutput(srv service.Service, r *enumSource) {
+	routersLock.Lock()
+	defer routersLock.Unlock()
+
+	rt, found := routers[srv]
+	if !found {
+		rt = &dataSrcRouter{
+			srv:  srv,
+			subs: make(map[*enumSource]*routerSub),
+			done: make(chan struct{}),
+		}
+		routers[srv] = rt
+		go rt.run()
+	}
+
+	rt.Lock()
+	defer rt.Unlock()
+
+	if _, found := rt.subs[r]; !found {
+		sub := &routerSub{
+			queue: queue.NewQueue(),
+			done:  make(chan struct{}),
+		}
+		rt.subs[r] = sub
+		go rt.feed(r, sub)
+	}
+}
+
+func unsubscribeDataSrcOutput(srv service.Service, r *enumSource) {
+	routersLock.Lock()
+	defer routersLock.Unlock()
+
+	rt, found := routers[srv]
+	if !found {
+		return
+	}
+
+	rt.Lock()
+	if sub, found := rt.subs[r]; found {
+		delete(rt.subs, r)
+		close(sub.done)
+	}
+	empty := len(rt.subs) == 0
+	rt.Unlock()
+	// Stop reading the data source output when no enumeration remains
+	if empty {
+		delete(routers, srv)
+		close(rt.done)
+	}
+}
+
+func (rt *dataSrcRouter) run() {
+	for {
+		select {
+		case <-rt.done:
+			return
+		case <-rt.srv.Done():
+			routersLock.Lock()
+			if routers[rt.srv] == rt {
+				delete(routers, rt.srv)
+			}
+			routersLock.Unlock()
+			return
+		case in := <-rt.srv.Output():
+			rt.deliver(in)
+		}
+	}
+}
+
+// feed hands the output queued for the enumeration over as it releases the pipeline.
+func (rt *dataSrcRouter) feed(r *enumSource, sub *routerSub) {
+	for {
+		select {
+		case <-sub.done:
+			return
+		case <-r.done:
+			return
+		case <-rt.srv.Done():
+			return
+		case <-sub.queue.Signal():
+			if in, ok := sub.queue.Next(); ok {
+				r.dataSrcOutput(rt.srv, in)
+			}
+		}
+	}
+}
+
+// deliver queues the output for the enumerations without waiting on any of them.
+func (rt *dataSrcRouter) deliver(in interface{}) {
+	rt.Lock()
+	defer rt.Unlock()
+
+	if len(rt.subs) == 1 {
+		for _, sub := range rt.subs {
+			sub.queue.Append(in)
+		}
+		return
+	}
+
+	subs := make([]*enumSource, 0, len(rt.subs))
+	for r := range rt.subs {
+		subs = append(subs, r)
+	}
+	// Names are only handed to the enumerations that have them in scope
+	if req, ok := in.(*requests.DNSRequest); ok {
+		var scoped []*enumSource
+
+		for _, r := range subs {
+			if r.enum.Config.WhichDomain(req.Name) != "" {
+				scoped = append(scoped, r)
+			}
+		}
+		if len(scoped) > 0 {
+			subs = scoped
+		}
+	}
+
+	for _, r := range subs {
+		switch req := in.(type) {
+		case *requests.DNSRequest:
+			rt.subs[r].queue.Append(req.Clone())
+		case *requests.AddrRequest:
+			rt.subs[r].queue.Append(req.Clone())
+		}
+	}
+}
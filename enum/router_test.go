@@ -0,0 +1,66 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/net/dns"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/queue"
+	"github.com/caffix/service"
+	bf "github.com/tylertreat/BoomFilters"
+)
+
+func routerTestSource(release int, domains ...string) *enumSource {
+	cfg := config.NewConfig()
+	cfg.AddDomains(domains...)
+
+	r := &enumSource{
+		enum:    &Enumeration{Config: cfg, stats: newSourceStats(nil)},
+		queue:   queue.NewQueue(),
+		dups:    queue.NewQueue(),
+		filter:  bf.NewDefaultStableBloomFilter(1000, 0.01),
+		subre:   dns.AnySubdomainRegex(),
+		done:    make(chan struct{}),
+		release: make(chan struct{}, release+1),
+	}
+	for i := 0; i < release; i++ {
+		r.release <- struct{}{}
+	}
+	return r
+}
+
+func TestRouterDoesNotBlockOnSlowEnumerations(t *testing.T) {
+	srv := service.NewBaseService(nil, "Router")
+	// The first enumeration never releases its pipeline
+	slow := routerTestSource(0, "owasp.org")
+	fast := routerTestSource(2, "owasp.org")
+	defer close(slow.done)
+	defer close(fast.done)
+
+	subscribeDataSrcOutput(srv, slow)
+	defer unsubscribeDataSrcOutput(srv, slow)
+	subscribeDataSrcOutput(srv, fast)
+	defer unsubscribeDataSrcOutput(srv, fast)
+
+	for _, name := range []string{"www.owasp.org", "ftp.owasp.org"} {
+		srv.Output() <- &requests.DNSRequest{Name: name, Domain: "owasp.org", Tag: requests.CERT, Source: "Router"}
+	}
+
+	timeout := time.After(5 * time.Second)
+	for fast.queue.Len() < 2 {
+		select {
+		case <-timeout:
+			t.Fatalf("The enumeration received %d of the names while the other was blocked", fast.queue.Len())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if slow.queue.Len() != 0 {
+		t.Errorf("The blocked enumeration received names without releasing its pipeline")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/datasrcs"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/netmap"
+	"github.com/caffix/service"
+)
+
+// Option is a setting that changes the environment Run prepares for an enumeration.
+type Option func(*runSettings)
+
+type runSettings struct {
+	sys     systems.System
+	sources func(systems.System) []service.Service
+	graph   *netmap.Graph
+}
+
+// WithSystem has the enumeration use the provided System instead of creating a LocalSystem.
+// The caller remains responsible for shutting down the System.
+func WithSystem(sys systems.System) Option {
+	return func(rs *runSettings) {
+		rs.sys = sys
+	}
+}
+
+// WithDataSources sets the function used to create the data sources added to the System.
+// By default, all the data sources returned by datasrcs.GetAllSources are used.
+func WithDataSources(f func(systems.System) []service.Service) Option {
+	return func(rs *runSettings) {
+		rs.sources = f
+	}
+}
+
+// WithGraph has the findings stored in the provided graph instead of an in-memory graph
+// that is migrated into the System graph databases after the enumeration completes.
+func WithGraph(g *netmap.Graph) Option {
+	return func(rs *runSettings) {
+		rs.graph = g
+	}
+}
+
+// Run prepares the System, data sources and graph required by an enumeration, starts it, and
+// returns the stream of events. The last event is always a FinishedEvent, and the channel is
+// closed once all the resources created by Run have been released.
+func Run(ctx context.Context, cfg *config.Config, opts ...Option) (<-chan Event, error) {
+	rs := &runSettings{sources: datasrcs.GetAllSources}
+	for _, opt := range opts {
+		opt(rs)
+	}
+	sys := rs.sys
+	if sys == nil {
+		local, err := systems.NewLocalSystem(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := local.SetDataSources(rs.sources(local)); err != nil {
+			_ = local.Shutdown()
+			return nil, err
+		}
+		sys = local
+	} else if len(sys.DataSources()) == 0 {
+		if err := sys.SetDataSources(rs.sources(sys)); err != nil {
+			return nil, err
+		}
+	}
+
+	graph := rs.graph
+	if graph == nil {
+		graph = netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	}
+
+	e := NewEnumeration(cfg, sys, graph)
+	if e == nil {
+		if rs.sys == nil {
+			_ = sys.Shutdown()
+		}
+		return nil, errors.New("failed to setup the enumeration")
+	}
+
+	events := e.Events()
+	output := make(chan Event, 100)
+	go func() {
+		defer close(output)
+
+		forwarded := make(chan struct{})
+		go func() {
+			defer close(forwarded)
+
+			for ev := range events {
+				output <- ev
+			}
+		}()
+
+		err := e.Start(ctx)
+		<-forwarded
+		// Copy the findings into the System graph databases
+		if rs.graph == nil {
+			for _, g := range sys.GraphDatabases() {
+				if merr := graph.Migrate(context.Background(), g); merr != nil && err == nil {
+					err = merr
+				}
+			}
+			graph.Close()
+		}
+
+		if rs.sys == nil {
+			_ = sys.Shutdown()
+		}
+		output <- &FinishedEvent{
+			Time: time.Now(),
+			UUID: cfg.UUID.String(),
+			Err:  err,
+		}
+	}()
+	return output, nil
+}
@@ -0,0 +1,331 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/datasrcs"
+	"github.com/OWASP/Amass/v3/datasrcs/scripting"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// The interval between the checks for changes to the user scripts.
+var scriptsPollInterval = 15 * time.Second
+
+// sourceChange adds or removes a data source from the requests managed by the enumeration.
+type sourceChange struct {
+	src     service.Service
+	remove  bool
+	backlog []interface{}
+}
+
+// scriptFile tracks the content of a user script and the name of the data source it implements.
+type scriptFile struct {
+	name string
+	data string
+}
+
+// Returns the data sources that receive requests from the enumeration.
+func (e *Enumeration) dataSources() []service.Service {
+	e.srcsLock.Lock()
+	defer e.srcsLock.Unlock()
+
+	srcs := make([]service.Service, len(e.srcs))
+	copy(srcs, e.srcs)
+	return srcs
+}
+
+var (
+	scriptWatchersLock sync.Mutex
+	// The watcher of the user scripts shared by the enumerations, keyed by the System
+	scriptWatchers = make(map[systems.System]*scriptWatcher)
+)
+
+// scriptWatcher loads, replaces or unloads the script data sources of a System when the
+// user scripts are created, modified or removed, and applies the changes to the enumerations
+// executing on the System.
+type scriptWatcher struct {
+	sync.Mutex
+	sys   systems.System
+	known map[string]*scriptFile
+	enums map[*Enumeration]struct{}
+	done  chan struct{}
+}
+
+func newScriptWatcher(sys systems.System) *scriptWatcher {
+	w := &scriptWatcher{
+		sys:   sys,
+		known: make(map[string]*scriptFile),
+		enums: make(map[*Enumeration]struct{}),
+		done:  make(chan struct{}),
+	}
+
+	files, _ := sys.Config().AcquireUserScripts()
+	for path, data := range files {
+		sf := &scriptFile{data: data}
+
+		if name, err := scripting.ScriptName(data, sys); err == nil {
+			sf.name = name
+		}
+		w.known[path] = sf
+	}
+	return w
+}
+
+// Has the enumeration receive the changes to the user scripts while it executes. The
+// scripts of a System are watched once, regardless of the number of enumerations using
+// it, and the returned function stops the enumeration from receiving the changes.
+func (e *Enumeration) watchScripts() func() {
+	scriptWatchersLock.Lock()
+	defer scriptWatchersLock.Unlock()
+
+	w, found := scriptWatchers[e.Sys]
+	if !found {
+		w = newScriptWatcher(e.Sys)
+		scriptWatchers[e.Sys] = w
+		go w.watch()
+	}
+	w.add(e)
+
+	return func() {
+		scriptWatchersLock.Lock()
+		defer scriptWatchersLock.Unlock()
+
+		if w.remove(e) == 0 {
+			close(w.done)
+			delete(scriptWatchers, e.Sys)
+		}
+	}
+}
+
+func (w *scriptWatcher) add(e *Enumeration) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.enums[e] = struct{}{}
+}
+
+// Returns the number of enumerations still receiving the changes.
+func (w *scriptWatcher) remove(e *Enumeration) int {
+	w.Lock()
+	defer w.Unlock()
+
+	delete(w.enums, e)
+	return len(w.enums)
+}
+
+func (w *scriptWatcher) enumerations() []*Enumeration {
+	w.Lock()
+	defer w.Unlock()
+
+	enums := make([]*Enumeration, 0, len(w.enums))
+	for e := range w.enums {
+		enums = append(enums, e)
+	}
+	return enums
+}
+
+func (w *scriptWatcher) watch() {
+	t := time.NewTicker(scriptsPollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-t.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *scriptWatcher) reload() {
+	cfg := w.sys.Config()
+
+	files, err := cfg.AcquireUserScripts()
+	if err != nil {
+		return
+	}
+
+	for path, sf := range w.known {
+		if _, found := files[path]; !found {
+			cfg.Log.Printf("The %s script was removed", path)
+			w.unload(sf.name)
+			delete(w.known, path)
+		}
+	}
+
+	for path, data := range files {
+		sf, found := w.known[path]
+		if found && sf.data == data {
+			continue
+		}
+		if !found {
+			sf = new(scriptFile)
+			w.known[path] = sf
+		}
+		// The failed versions are not loaded again until the file changes
+		sf.data = data
+
+		s := scripting.NewScript(data, w.sys)
+		if s == nil {
+			continue
+		}
+		// The data sources excluded by the configuration are not started
+		if len(datasrcs.SelectedDataSources(cfg, []service.Service{s})) == 0 {
+			s.Release()
+			if sf.name != s.String() {
+				w.unload(sf.name)
+			}
+			sf.name = ""
+			continue
+		}
+		if err := w.load(s); err != nil {
+			cfg.Log.Printf("Failed to load the %s script: %v", path, err)
+			continue
+		}
+		// The previous version is only removed once the new version replaced it
+		if sf.name != "" && sf.name != s.String() {
+			w.unload(sf.name)
+		}
+		sf.name = s.String()
+		cfg.Log.Printf("The %s script was loaded from %s", sf.name, path)
+	}
+}
+
+// Starts the data source and has it replace the data source with the same name on the System
+// and in each enumeration.
+func (w *scriptWatcher) load(src service.Service) error {
+	if err := src.Start(); err != nil {
+		// Stopping the data source releases the resources acquired while it was started
+		_ = src.Stop()
+		return err
+	}
+
+	w.unload(src.String())
+	if err := w.sys.AddSource(src); err != nil {
+		_ = src.Stop()
+		return err
+	}
+
+	for _, e := range w.enumerations() {
+		e.addSource(src)
+	}
+	return nil
+}
+
+// Stops the data source with the provided name and removes it from the System and the enumerations.
+func (w *scriptWatcher) unload(name string) {
+	if name == "" {
+		return
+	}
+
+	var src service.Service
+	for _, s := range w.sys.DataSources() {
+		if s != nil && s.String() == name {
+			src = s
+			break
+		}
+	}
+	if src == nil {
+		return
+	}
+
+	for _, e := range w.enumerations() {
+		e.removeSource(src)
+	}
+	_ = w.sys.RemoveSource(src)
+	_ = src.Stop()
+}
+
+// Has the enumeration send requests to the new data source, which receives the backlog of the
+// requests for the names in scope.
+func (e *Enumeration) addSource(src service.Service) {
+	if len(datasrcs.SelectedDataSources(e.Config, []service.Service{src})) == 0 {
+		return
+	}
+
+	e.srcsLock.Lock()
+	e.srcs = append(e.srcs, src)
+	e.srcsLock.Unlock()
+
+	e.stats.add(src.String())
+	subscribeDataSrcOutput(src, e.nameSrc)
+	select {
+	case <-e.done:
+	case <-e.ctx.Done():
+	case e.srcChanges <- &sourceChange{src: src, backlog: e.sourceBacklog()}:
+	}
+}
+
+// Stops the enumeration from sending requests to the data source.
+func (e *Enumeration) removeSource(src service.Service) {
+	var found bool
+
+	e.srcsLock.Lock()
+	for i, s := range e.srcs {
+		if s == src {
+			found = true
+			e.srcs = append(e.srcs[:i], e.srcs[i+1:]...)
+			break
+		}
+	}
+	e.srcsLock.Unlock()
+	if !found {
+		return
+	}
+
+	select {
+	case <-e.done:
+	case <-e.ctx.Done():
+	case e.srcChanges <- &sourceChange{src: src, remove: true}:
+	}
+	unsubscribeDataSrcOutput(src, e.nameSrc)
+}
+
+// Returns the requests that provide the scope of the enumeration to a new data source. Along
+// with the root domain names and ASNs, the data source receives the in-scope names resolved
+// so far, as the other data sources did.
+func (e *Enumeration) sourceBacklog() []interface{} {
+	var backlog []interface{}
+
+	for _, domain := range e.Config.Domains() {
+		backlog = append(backlog, &requests.DNSRequest{
+			Name:   domain,
+			Domain: domain,
+			Tag:    requests.DNS,
+			Source: "DNS",
+		})
+	}
+	for _, asn := range e.Config.ASNs {
+		backlog = append(backlog, &requests.ASNRequest{ASN: asn})
+	}
+	// The data sources only receive the discovered names once they have been resolved
+	if e.Config.Passive {
+		return backlog
+	}
+
+	for _, name := range e.writtenNames() {
+		domain := e.Config.WhichDomain(name)
+		if domain == "" || domain == name {
+			continue
+		}
+		// The data sources only act on the resolved names along with their records
+		if records := e.writtenRecords(name); len(records) > 0 {
+			backlog = append(backlog, &requests.ResolvedRequest{
+				Name:    name,
+				Domain:  domain,
+				Records: records,
+				Tag:     requests.DNS,
+				Source:  "DNS",
+			})
+		}
+	}
+	return backlog
+}
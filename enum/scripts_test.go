@@ -0,0 +1,282 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/OWASP/Amass/v3/systems"
+	"github.com/caffix/queue"
+	"github.com/miekg/dns"
+)
+
+// Returns an enumeration that sends the requests to the data sources loaded from the user scripts.
+func newScriptsEnumeration(t *testing.T, sys systems.System) *Enumeration {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e := &Enumeration{
+		Config:      sys.Config(),
+		Sys:         sys,
+		ctx:         ctx,
+		done:        make(chan struct{}),
+		srcChanges:  make(chan *sourceChange),
+		requests:    queue.NewQueue(),
+		backlogReqs: make(chan chan map[string][]interface{}),
+		backlogDone: make(chan struct{}),
+		stats:       newSourceStats(nil),
+		nameSrc:     &enumSource{done: make(chan struct{})},
+		written:     make(map[string][]requests.DNSAnswer),
+	}
+	go e.manageDataSrcRequests(make(map[string][]interface{}))
+
+	t.Cleanup(func() {
+		close(e.done)
+		cancel()
+	})
+	return e
+}
+
+// syncBuffer collects the log messages written by the data sources while the test reads them.
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.buf.String()
+}
+
+func TestReloadScripts(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.AddDomain("owasp.org")
+
+	dir := filepath.Join(cfg.Dir, "scripts")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("Failed to create the scripts directory: %v", err)
+	}
+	path := filepath.Join(dir, "reload.ads")
+	write := func(stype string) {
+		script := `name="Reload"
+			type="` + stype + `"
+			function vertical(ctx, domain) end`
+		if err := ioutil.WriteFile(path, []byte(script), 0600); err != nil {
+			t.Fatalf("Failed to write the script: %v", err)
+		}
+	}
+
+	sys := &systems.SimpleSystem{Cfg: cfg}
+	e := newScriptsEnumeration(t, sys)
+
+	w := newScriptWatcher(sys)
+	w.add(e)
+	write("scrape")
+	w.reload()
+
+	srcs := e.dataSources()
+	if len(srcs) != 1 || srcs[0].String() != "Reload" || sys.Service != srcs[0] {
+		t.Fatalf("The new script was not loaded")
+	}
+	// The new data source receives the root domain names in scope
+	deadline := time.Now().Add(5 * time.Second)
+	for e.stats.snapshot()[0].Requests == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("The new data source did not receive the domain names")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	old := srcs[0]
+	// Unchanged scripts are not loaded again
+	w.reload()
+	if srcs := e.dataSources(); len(srcs) != 1 || srcs[0] != old {
+		t.Errorf("The unchanged script was replaced")
+	}
+
+	write("api")
+	w.reload()
+	if srcs := e.dataSources(); len(srcs) != 1 || srcs[0].Description() != "api" || sys.Service != srcs[0] {
+		t.Errorf("The modified script did not replace the data source")
+	}
+	select {
+	case <-old.Done():
+	default:
+		t.Errorf("The replaced data source was not stopped")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove the script: %v", err)
+	}
+	w.reload()
+	if srcs := e.dataSources(); len(srcs) != 0 || sys.Service != nil {
+		t.Errorf("The removed script was not unloaded")
+	}
+}
+
+func TestScriptWatcherPerSystem(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Dir = t.TempDir()
+	sys := &systems.SimpleSystem{Cfg: cfg}
+
+	first := &Enumeration{Config: cfg, Sys: sys}
+	second := &Enumeration{Config: cfg, Sys: sys}
+
+	stopFirst := first.watchScripts()
+	stopSecond := second.watchScripts()
+	scriptWatchersLock.Lock()
+	w := scriptWatchers[sys]
+	scriptWatchersLock.Unlock()
+	if w == nil || len(w.enumerations()) != 2 {
+		t.Fatalf("The enumerations on the System did not share the script watcher")
+	}
+
+	stopFirst()
+	select {
+	case <-w.done:
+		t.Errorf("The script watcher stopped while an enumeration was executing")
+	default:
+	}
+
+	stopSecond()
+	select {
+	case <-w.done:
+	default:
+		t.Errorf("The script watcher was not stopped after the enumerations finished")
+	}
+	scriptWatchersLock.Lock()
+	defer scriptWatchersLock.Unlock()
+	if _, found := scriptWatchers[sys]; found {
+		t.Errorf("The script watcher was not removed")
+	}
+}
+
+func TestSourceBacklog(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	cfg.ASNs = []int{26808}
+
+	e := &Enumeration{Config: cfg, written: make(map[string][]requests.DNSAnswer)}
+	for _, name := range []string{"www.owasp.org", "owasp.org", "ftp.owasp.org"} {
+		e.nameWritten(&requests.DNSRequest{
+			Name:    name,
+			Domain:  "owasp.org",
+			Records: []requests.DNSAnswer{{Name: name, Type: int(dns.TypeA), Data: "192.0.2.1"}},
+		})
+	}
+	// Names that were written without being resolved are not sent to the data sources
+	e.nameWritten(&requests.DNSRequest{Name: "mail.owasp.org", Domain: "owasp.org"})
+
+	backlog := e.sourceBacklog()
+	if len(backlog) != 4 {
+		t.Fatalf("Expected 4 requests in the backlog, got %d", len(backlog))
+	}
+	if req, ok := backlog[0].(*requests.DNSRequest); !ok || req.Name != "owasp.org" {
+		t.Errorf("The backlog did not start with the root domain name")
+	}
+	if req, ok := backlog[1].(*requests.ASNRequest); !ok || req.ASN != 26808 {
+		t.Errorf("The backlog did not contain the ASN")
+	}
+	for i, name := range []string{"ftp.owasp.org", "www.owasp.org"} {
+		if req, ok := backlog[i+2].(*requests.ResolvedRequest); !ok || req.Name != name ||
+			req.Domain != "owasp.org" || len(req.Records) != 1 || req.Records[0].Data != "192.0.2.1" {
+			t.Errorf("The backlog did not contain the discovered name %s with its records", name)
+		}
+	}
+
+	cfg.Passive = true
+	if backlog := e.sourceBacklog(); len(backlog) != 2 {
+		t.Errorf("The backlog contained names that were not resolved in passive mode")
+	}
+}
+
+func TestReloadedScriptResolvedBacklog(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.AddDomain("owasp.org")
+	logs := new(syncBuffer)
+	cfg.Log = log.New(logs, "", 0)
+
+	dir := filepath.Join(cfg.Dir, "scripts")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("Failed to create the scripts directory: %v", err)
+	}
+	path := filepath.Join(dir, "resolved.ads")
+	write := func(script string) {
+		if err := ioutil.WriteFile(path, []byte(script), 0600); err != nil {
+			t.Fatalf("Failed to write the script: %v", err)
+		}
+	}
+
+	sys := &systems.SimpleSystem{Cfg: cfg}
+	e := newScriptsEnumeration(t, sys)
+	e.nameWritten(&requests.DNSRequest{
+		Name:    "www.owasp.org",
+		Domain:  "owasp.org",
+		Records: []requests.DNSAnswer{{Name: "www.owasp.org", Type: int(dns.TypeA), Data: "192.0.2.1"}},
+	})
+
+	w := newScriptWatcher(sys)
+	w.add(e)
+	write(`name="Resolved"
+		type="api"
+		function resolved(ctx, name, domain, records)
+			log(ctx, "resolved " .. name .. " " .. records[1].rrdata)
+		end`)
+	w.reload()
+
+	// The names resolved before the script was loaded are provided to the callback with their records
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "Resolved: resolved www.owasp.org 192.0.2.1") {
+		if time.Now().After(deadline) {
+			t.Fatalf("The loaded script did not receive the resolved name: %s", logs.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	old := e.dataSources()[0]
+	// A new version that fails to start does not replace the running version
+	write(`name="Renamed"
+		type="api"
+		function check() return false end`)
+	w.reload()
+	if srcs := e.dataSources(); len(srcs) != 1 || srcs[0] != old || sys.Service != old {
+		t.Fatalf("The script that failed to start replaced the running version")
+	}
+
+	// The file of the running version is tracked under the name of that version
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove the script: %v", err)
+	}
+	w.reload()
+	if srcs := e.dataSources(); len(srcs) != 0 || sys.Service != nil {
+		t.Errorf("The removed script was not unloaded")
+	}
+	select {
+	case <-old.Done():
+	default:
+		t.Errorf("The unloaded data source was not stopped")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/caffix/service"
+)
+
+// sourceStats collects the activity of the data sources used by the enumeration.
+type sourceStats struct {
+	sync.Mutex
+	stats map[string]*requests.SourceStats
+	// The data sources that provided each name
+	names map[string][]string
+}
+
+func newSourceStats(srcs []service.Service) *sourceStats {
+	s := &sourceStats{
+		stats: make(map[string]*requests.SourceStats, len(srcs)),
+		names: make(map[string][]string),
+	}
+
+	for _, src := range srcs {
+		s.stats[src.String()] = &requests.SourceStats{Source: src.String()}
+	}
+	return s
+}
+
+// Adds a data source loaded after the enumeration started.
+func (s *sourceStats) add(src string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, found := s.stats[src]; !found {
+		s.stats[src] = &requests.SourceStats{Source: src}
+	}
+}
+
+func (s *sourceStats) has(src string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	_, found := s.stats[src]
+	return found
+}
+
+func (s *sourceStats) request(src string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if st, found := s.stats[src]; found {
+		now := time.Now()
+
+		st.Requests++
+		if st.First.IsZero() {
+			st.First = now
+		}
+		st.Last = now
+	}
+}
+
+func (s *sourceStats) name(src, name string) {
+	s.Lock()
+	defer s.Unlock()
+
+	st, found := s.stats[src]
+	if !found {
+		return
+	}
+	st.Last = time.Now()
+
+	for _, n := range s.names[name] {
+		if n == src {
+			return
+		}
+	}
+	s.names[name] = append(s.names[name], src)
+}
+
+func (s *sourceStats) activity(src string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if st, found := s.stats[src]; found {
+		st.Last = time.Now()
+	}
+}
+
+func (s *sourceStats) error(src string, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if st, found := s.stats[src]; found {
+		st.Errors++
+		st.LastError = err.Error()
+	}
+}
+
+func (s *sourceStats) snapshot() []*requests.SourceStats {
+	s.Lock()
+	defer s.Unlock()
+
+	stats := make(map[string]*requests.SourceStats, len(s.stats))
+	for name, st := range s.stats {
+		c := *st
+		c.Names = 0
+		c.UniqueNames = 0
+		stats[name] = &c
+	}
+	for _, srcs := range s.names {
+		for _, src := range srcs {
+			stats[src].Names++
+		}
+		if len(srcs) == 1 {
+			stats[srcs[0]].UniqueNames++
+		}
+	}
+
+	list := make([]*requests.SourceStats, 0, len(stats))
+	for _, st := range stats {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Source < list[j].Source
+	})
+	return list
+}
+
+// SourceStats returns the activity of each data source selected for the enumeration,
+// including the use of the credentials sets configured for the data source.
+func (e *Enumeration) SourceStats() []*requests.SourceStats {
+	stats := e.stats.snapshot()
+
+	for _, st := range stats {
+		dsc := e.Sys.Config().GetDataSourceConfig(st.Source)
+		if dsc == nil {
+			continue
+		}
+
+		for _, u := range dsc.CredentialsUsage() {
+			st.Credentials = append(st.Credentials, &requests.CredentialsStats{
+				Name:     u.Name,
+				Requests: u.Requests,
+				Status:   u.Status,
+			})
+		}
+	}
+	return stats
+}
+
+// sourceError records the error reported by a data source used by the enumeration and emits the event.
+// The System configuration is shared by the enumerations executing at the same time, so the errors
+// of requests about domains outside the scope belong to another enumeration. The errors of requests
+// not about a domain cannot be attributed, and are recorded by each enumeration using the data source.
+func (e *Enumeration) sourceError(source, domain string, err error) {
+	if e.stats == nil || !e.stats.has(source) {
+		return
+	}
+	if domain != "" && !e.Config.IsDomainInScope(domain) {
+		return
+	}
+
+	e.stats.error(source, err)
+	e.emit(&SourceErrorEvent{
+		Time:   time.Now(),
+		Source: source,
+		Err:    err,
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+func TestSourceStats(t *testing.T) {
+	s := &sourceStats{
+		stats: map[string]*requests.SourceStats{
+			"AlienVault": {Source: "AlienVault"},
+			"Crtsh":      {Source: "Crtsh"},
+			"Wayback":    {Source: "Wayback"},
+		},
+		names: make(map[string][]string),
+	}
+
+	s.request("AlienVault")
+	s.request("Crtsh")
+	s.name("AlienVault", "www.owasp.org")
+	s.name("AlienVault", "www.owasp.org")
+	s.name("AlienVault", "ftp.owasp.org")
+	s.name("Crtsh", "www.owasp.org")
+	s.name("Unknown", "mail.owasp.org")
+
+	s.error("Wayback", errors.New("https://web.archive.org: 429 Too Many Requests"))
+	s.error("Unknown", errors.New("403 Forbidden"))
+
+	expected := map[string]requests.SourceStats{
+		"AlienVault": {Requests: 1, Names: 2, UniqueNames: 1},
+		"Crtsh":      {Requests: 1, Names: 1},
+		"Wayback":    {Errors: 1, LastError: "https://web.archive.org: 429 Too Many Requests"},
+	}
+	stats := s.snapshot()
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected statistics for %d data sources, got %d", len(expected), len(stats))
+	}
+	for _, st := range stats {
+		exp := expected[st.Source]
+
+		if st.Requests != exp.Requests || st.Errors != exp.Errors || st.LastError != exp.LastError ||
+			st.Names != exp.Names || st.UniqueNames != exp.UniqueNames {
+			t.Errorf("Unexpected statistics for %s: %+v", st.Source, st)
+		}
+	}
+}
@@ -83,6 +83,8 @@ func (dm *dataManager) Process(ctx context.Context, data pipeline.Data, tp pipel
 		}
 	}
 
+	// The pipeline is finished with the request once it has been stored
+	dm.enum.nameSrc.finished(data)
 	if id != "" && dm.filter.TestAndAdd([]byte(id)) {
 		return nil, nil
 	}
@@ -94,16 +96,36 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 		return nil
 	}
 	// Check for CNAME records first
+	cname := -1
 	for i, r := range req.Records {
 		req.Records[i].Name = strings.Trim(strings.ToLower(r.Name), ".")
 		req.Records[i].Data = strings.Trim(strings.ToLower(r.Data), ".")
 
-		if uint16(r.Type) == dns.TypeCNAME {
-			// Do not enter more than the CNAME record
-			return dm.insertCNAME(ctx, req, i, tp)
+		if cname == -1 && uint16(r.Type) == dns.TypeCNAME {
+			cname = i
 		}
 	}
+	var err error
+	if cname != -1 {
+		// Do not enter more than the CNAME record
+		err = dm.insertCNAME(ctx, req, cname, tp)
+	} else {
+		err = dm.insertRecords(ctx, req, tp)
+	}
+	// The records are only reported for in-scope names after they have all been written into the graph
+	if err == nil && ctx.Err() == nil && len(req.Records) > 0 && dm.enum.Config.IsDomainInScope(req.Name) {
+		dm.enum.emit(&ResolvedEvent{
+			Time:    time.Now(),
+			Name:    req.Name,
+			Domain:  req.Domain,
+			Records: append([]requests.DNSAnswer(nil), req.Records...),
+			Source:  req.Source,
+		})
+	}
+	return err
+}
 
+func (dm *dataManager) insertRecords(ctx context.Context, req *requests.DNSRequest, tp pipeline.TaskParams) error {
 	var err error
 	for i, r := range req.Records {
 		select {
@@ -131,6 +153,10 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 			err = dm.insertSOA(ctx, req, i, tp)
 		case dns.TypeSPF:
 			err = dm.insertSPF(ctx, req, i, tp)
+		case dns.TypeCAA:
+			err = dm.insertCAA(ctx, req, i, tp)
+		case dns.TypeHTTPS, dns.TypeSVCB:
+			err = dm.insertSVCB(ctx, req, i, tp)
 		}
 		if err != nil {
 			break
@@ -156,9 +182,13 @@ func (dm *dataManager) insertCNAME(ctx context.Context, req *requests.DNSRequest
 		Tag:    requests.DNS,
 		Source: "DNS",
 	})
-	if err := dm.enum.graph.UpsertCNAME(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
+	start := time.Now()
+	err = dm.enum.graph.UpsertCNAME(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String())
+	observeGraphWrite(start)
+	if err != nil {
 		return fmt.Errorf("%s failed to insert CNAME: %v", dm.enum.graph, err)
 	}
+	dm.enum.nameWritten(req)
 	return nil
 }
 
@@ -175,9 +205,20 @@ func (dm *dataManager) insertA(ctx context.Context, req *requests.DNSRequest, re
 		Tag:     requests.DNS,
 		Source:  "DNS",
 	})
-	if err := dm.enum.graph.UpsertA(ctx, req.Name, addr, req.Source, dm.enum.Config.UUID.String()); err != nil {
+	start := time.Now()
+	err := dm.enum.graph.UpsertA(ctx, req.Name, addr, req.Source, dm.enum.Config.UUID.String())
+	observeGraphWrite(start)
+	if err != nil {
 		return fmt.Errorf("%s failed to insert A record: %v", dm.enum.graph, err)
 	}
+	dm.enum.nameWritten(req)
+	dm.enum.emit(&AddressEvent{
+		Time:    time.Now(),
+		Name:    req.Name,
+		Domain:  req.Domain,
+		Address: addr,
+		Source:  req.Source,
+	})
 	return nil
 }
 
@@ -194,9 +235,20 @@ func (dm *dataManager) insertAAAA(ctx context.Context, req *requests.DNSRequest,
 		Tag:     requests.DNS,
 		Source:  "DNS",
 	})
-	if err := dm.enum.graph.UpsertAAAA(ctx, req.Name, addr, req.Source, dm.enum.Config.UUID.String()); err != nil {
+	start := time.Now()
+	err := dm.enum.graph.UpsertAAAA(ctx, req.Name, addr, req.Source, dm.enum.Config.UUID.String())
+	observeGraphWrite(start)
+	if err != nil {
 		return fmt.Errorf("%s failed to insert AAAA record: %v", dm.enum.graph, err)
 	}
+	dm.enum.nameWritten(req)
+	dm.enum.emit(&AddressEvent{
+		Time:    time.Now(),
+		Name:    req.Name,
+		Domain:  req.Domain,
+		Address: addr,
+		Source:  req.Source,
+	})
 	return nil
 }
 
@@ -217,7 +269,10 @@ func (dm *dataManager) insertPTR(ctx context.Context, req *requests.DNSRequest,
 		Tag:    requests.DNS,
 		Source: "Reverse DNS",
 	})
-	if err := dm.enum.graph.UpsertPTR(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
+	start := time.Now()
+	err := dm.enum.graph.UpsertPTR(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String())
+	observeGraphWrite(start)
+	if err != nil {
 		return fmt.Errorf("%s failed to insert PTR record: %v", dm.enum.graph, err)
 	}
 	return nil
@@ -237,9 +292,13 @@ func (dm *dataManager) insertSRV(ctx context.Context, req *requests.DNSRequest,
 			Source: "DNS",
 		})
 	}
-	if err := dm.enum.graph.UpsertSRV(ctx, req.Name, service, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
+	start := time.Now()
+	err := dm.enum.graph.UpsertSRV(ctx, req.Name, service, target, req.Source, dm.enum.Config.UUID.String())
+	observeGraphWrite(start)
+	if err != nil {
 		return fmt.Errorf("%s failed to insert SRV record: %v", dm.enum.graph, err)
 	}
+	dm.enum.nameWritten(req)
 	return nil
 }
 
@@ -261,9 +320,13 @@ func (dm *dataManager) insertNS(ctx context.Context, req *requests.DNSRequest, r
 			Source: "DNS",
 		})
 	}
-	if err := dm.enum.graph.UpsertNS(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
+	start := time.Now()
+	err = dm.enum.graph.UpsertNS(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String())
+	observeGraphWrite(start)
+	if err != nil {
 		return fmt.Errorf("%s failed to insert NS record: %v", dm.enum.graph, err)
 	}
+	dm.enum.nameWritten(req)
 	return nil
 }
 
@@ -285,9 +348,13 @@ func (dm *dataManager) insertMX(ctx context.Context, req *requests.DNSRequest, r
 			Source: "DNS",
 		})
 	}
-	if err := dm.enum.graph.UpsertMX(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
+	start := time.Now()
+	err = dm.enum.graph.UpsertMX(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String())
+	observeGraphWrite(start)
+	if err != nil {
 		return fmt.Errorf("%s failed to insert MX record: %v", dm.enum.graph, err)
 	}
+	dm.enum.nameWritten(req)
 	return nil
 }
 
@@ -295,7 +362,7 @@ func (dm *dataManager) insertTXT(ctx context.Context, req *requests.DNSRequest,
 	if dm.enum.Config.IsDomainInScope(req.Name) {
 		dm.findNamesAndAddresses(ctx, req.Records[recidx].Data, req.Domain, tp)
 	}
-	return nil
+	return dm.insertRecordProperty(ctx, req, "txt_record", req.Records[recidx].Data)
 }
 
 func (dm *dataManager) insertSOA(ctx context.Context, req *requests.DNSRequest, recidx int, tp pipeline.TaskParams) error {
@@ -312,6 +379,52 @@ func (dm *dataManager) insertSPF(ctx context.Context, req *requests.DNSRequest,
 	return nil
 }
 
+func (dm *dataManager) insertCAA(ctx context.Context, req *requests.DNSRequest, recidx int, tp pipeline.TaskParams) error {
+	return dm.insertRecordProperty(ctx, req, "caa_record", req.Records[recidx].Data)
+}
+
+func (dm *dataManager) insertSVCB(ctx context.Context, req *requests.DNSRequest, recidx int, tp pipeline.TaskParams) error {
+	data := req.Records[recidx].Data
+	// The target name of the service binding follows the priority
+	if fields := strings.Fields(data); len(fields) > 1 {
+		target := resolve.RemoveLastDot(fields[1])
+
+		if domain := dm.enum.Config.WhichDomain(target); domain != "" && target != req.Name {
+			dm.enum.nameSrc.newName(&requests.DNSRequest{
+				Name:   target,
+				Domain: domain,
+				Tag:    requests.DNS,
+				Source: "DNS",
+			})
+		}
+	}
+
+	predicate := "svcb_record"
+	if uint16(req.Records[recidx].Type) == dns.TypeHTTPS {
+		predicate = "https_record"
+	}
+	return dm.insertRecordProperty(ctx, req, predicate, data)
+}
+
+// The graph has no edges for some record types, so the data is stored as a property of the FQDN.
+func (dm *dataManager) insertRecordProperty(ctx context.Context, req *requests.DNSRequest, predicate, data string) error {
+	if data == "" {
+		return fmt.Errorf("failed to extract the %s data from the DNS answer", predicate)
+	}
+
+	start := time.Now()
+	node, err := dm.enum.graph.UpsertFQDN(ctx, req.Name, req.Source, dm.enum.Config.UUID.String())
+	if err == nil {
+		err = dm.enum.graph.UpsertProperty(ctx, node, predicate, data)
+	}
+	observeGraphWrite(start)
+	if err != nil {
+		return fmt.Errorf("%s failed to insert the %s property: %v", dm.enum.graph, predicate, err)
+	}
+	dm.enum.nameWritten(req)
+	return nil
+}
+
 func (dm *dataManager) findNamesAndAddresses(ctx context.Context, data, domain string, tp pipeline.TaskParams) {
 	ipre := regexp.MustCompile(amassnet.IPv4RE)
 	for _, ip := range ipre.FindAllString(data, -1) {
@@ -349,7 +462,7 @@ func (dm *dataManager) addrRequest(ctx context.Context, req *requests.AddrReques
 	}
 	if yes, prefix := amassnet.IsReservedAddress(req.Address); yes {
 		var err error
-		if e := dm.enum.graph.UpsertInfrastructure(ctx, 0,
+		if e := dm.upsertInfrastructure(ctx, 0,
 			amassnet.ReservedCIDRDescription, req.Address, prefix, "RIR", uuid); e != nil {
 			err = e
 		}
@@ -357,7 +470,7 @@ func (dm *dataManager) addrRequest(ctx context.Context, req *requests.AddrReques
 	}
 	if r := dm.enum.Sys.Cache().AddrSearch(req.Address); r != nil {
 		var err error
-		if e := dm.enum.graph.UpsertInfrastructure(ctx, r.ASN,
+		if e := dm.upsertInfrastructure(ctx, r.ASN,
 			r.Description, req.Address, r.Prefix, r.Source, uuid); e != nil {
 			err = e
 		}
@@ -394,7 +507,7 @@ func (dm *dataManager) nextInfraInfo() {
 	req := e.(*requests.AddrRequest)
 	uuid := dm.enum.Config.UUID.String()
 	if r := dm.enum.Sys.Cache().AddrSearch(req.Address); r != nil {
-		_ = dm.enum.graph.UpsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid)
+		_ = dm.upsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid)
 		return
 	}
 
@@ -409,7 +522,7 @@ loop:
 
 		time.Sleep(2 * time.Second)
 		if r := dm.enum.Sys.Cache().AddrSearch(req.Address); r != nil {
-			_ = dm.enum.graph.UpsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid)
+			_ = dm.upsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid)
 			return
 		}
 	}
@@ -417,7 +530,7 @@ loop:
 	asn := 0
 	desc := "Unknown"
 	prefix := fakePrefix(req.Address)
-	_ = dm.enum.graph.UpsertInfrastructure(ctx, asn, desc, req.Address, prefix, "RIR", uuid)
+	_ = dm.upsertInfrastructure(ctx, asn, desc, req.Address, prefix, "RIR", uuid)
 
 	first, cidr, _ := net.ParseCIDR(prefix)
 	dm.enum.Sys.Cache().Update(&requests.ASNRequest{
@@ -430,6 +543,25 @@ loop:
 	})
 }
 
+func (dm *dataManager) upsertInfrastructure(ctx context.Context, asn int, desc, addr, prefix, source, uuid string) error {
+	start := time.Now()
+	err := dm.enum.graph.UpsertInfrastructure(ctx, asn, desc, addr, prefix, source, uuid)
+	observeGraphWrite(start)
+	if err != nil {
+		return err
+	}
+
+	dm.enum.emit(&ASNEvent{
+		Time:        time.Now(),
+		Address:     addr,
+		ASN:         asn,
+		Prefix:      prefix,
+		Description: desc,
+		Source:      source,
+	})
+	return nil
+}
+
 func fakePrefix(addr string) string {
 	bits := 24
 	total := 32
@@ -0,0 +1,119 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+// Separates the multiple values held by a single CSV field.
+const csvValueSep = ";"
+
+var csvHeader = []string{"name", "domain", "tag", "sources", "addresses", "cidrs", "asns", "descriptions", "records"}
+
+type csvSink struct {
+	sync.Mutex
+	name string
+	c    io.Closer
+	w    *csv.Writer
+}
+
+// Writes the findings as rows of the CSV file provided as the target, or to STDOUT if named "-".
+func newCSVSink(target string) (OutputSink, error) {
+	s := &csvSink{name: "CSV sink: " + target}
+
+	if target == "-" {
+		s.name = "CSV sink: STDOUT"
+		s.w = csv.NewWriter(os.Stdout)
+	} else {
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		s.c = f
+		s.w = csv.NewWriter(f)
+	}
+
+	if err := s.w.Write(csvHeader); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// String implements the Stringer interface.
+func (s *csvSink) String() string {
+	return s.name
+}
+
+// Write implements the OutputSink interface.
+func (s *csvSink) Write(ctx context.Context, res *Result) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.w.Write(csvRecord(res)); err != nil {
+		return err
+	}
+
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// Close implements the OutputSink interface.
+func (s *csvSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.w.Flush()
+	if s.c == nil {
+		return s.w.Error()
+	}
+	return s.c.Close()
+}
+
+func csvRecord(res *Result) []string {
+	var addrs, cidrs, asns, descs, records []string
+
+	for _, a := range res.Addresses {
+		addrs = append(addrs, a.Address.String())
+		cidrs = append(cidrs, a.CIDRStr)
+		asns = append(asns, strconv.Itoa(a.ASN))
+		descs = append(descs, a.Description)
+	}
+	for _, rr := range res.Records {
+		records = append(records, RecordString(rr))
+	}
+
+	return []string{
+		res.Name,
+		res.Domain,
+		res.Tag,
+		strings.Join(res.Sources, csvValueSep),
+		strings.Join(addrs, csvValueSep),
+		strings.Join(cidrs, csvValueSep),
+		strings.Join(asns, csvValueSep),
+		strings.Join(descs, csvValueSep),
+		strings.Join(records, csvValueSep),
+	}
+}
+
+// RecordString returns the DNS record in a presentation format similar to zone files, without the TTL.
+func RecordString(rr requests.DNSAnswer) string {
+	rrtype, found := dns.TypeToString[uint16(rr.Type)]
+	if !found {
+		rrtype = "TYPE" + strconv.Itoa(rr.Type)
+	}
+	return rr.Name + " " + rrtype + " " + rr.Data
+}
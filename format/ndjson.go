@@ -0,0 +1,81 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// streamSink writes each finding as a line of JSON to the underlying writer.
+type streamSink struct {
+	sync.Mutex
+	name string
+	w    io.Writer
+	c    io.Closer
+	enc  *json.Encoder
+}
+
+func newStreamSink(name string, w io.Writer, c io.Closer) *streamSink {
+	return &streamSink{
+		name: name,
+		w:    w,
+		c:    c,
+		enc:  json.NewEncoder(w),
+	}
+}
+
+// Writes the findings to the file provided as the target, or to STDOUT if named "-".
+func newNDJSONSink(target string) (OutputSink, error) {
+	if target == "-" {
+		return newStreamSink("NDJSON sink: STDOUT", os.Stdout, nil), nil
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamSink("NDJSON sink: "+target, f, f), nil
+}
+
+// Streams the findings to the process listening on the Unix domain socket provided as the target.
+func newSocketSink(target string) (OutputSink, error) {
+	conn, err := net.Dial("unix", target)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamSink("Unix socket sink: "+target, conn, conn), nil
+}
+
+// String implements the Stringer interface.
+func (s *streamSink) String() string {
+	return s.name
+}
+
+// Write implements the OutputSink interface.
+func (s *streamSink) Write(ctx context.Context, res *Result) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.enc.Encode(res)
+}
+
+// Close implements the OutputSink interface.
+func (s *streamSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if f, ok := s.w.(*os.File); ok {
+		_ = f.Sync()
+	}
+	if s.c == nil {
+		return nil
+	}
+	return s.c.Close()
+}
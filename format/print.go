@@ -10,6 +10,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	amassnet "github.com/OWASP/Amass/v3/net"
 	"github.com/OWASP/Amass/v3/requests"
@@ -47,6 +48,7 @@ var (
 	b      = color.New(color.FgHiBlue)
 	y      = color.New(color.FgHiYellow)
 	r      = color.New(color.FgHiRed)
+	red    = color.New(color.FgHiRed).SprintFunc()
 	yellow = color.New(color.FgHiYellow).SprintFunc()
 	green  = color.New(color.FgHiGreen).SprintFunc()
 	blue   = color.New(color.FgHiBlue).SprintFunc()
@@ -146,6 +148,65 @@ func FprintEnumerationSummary(out io.Writer, total int, tags map[string]int, asn
 	}
 }
 
+// FprintSourceStats outputs the activity of each data source used by the enumeration.
+func FprintSourceStats(out io.Writer, stats []*requests.SourceStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	for i := 0; i < 8; i++ {
+		b.Fprint(out, "----------")
+	}
+	fmt.Fprintf(out, "\n%s\n", blue(fmt.Sprintf("%-24s %9s %7s %7s %7s %10s",
+		"Data Source", "Requests", "Errors", "Names", "Unique", "Time")))
+
+	for _, st := range stats {
+		errs := green(fmt.Sprintf("%7d", st.Errors))
+		if st.Errors > 0 {
+			errs = red(fmt.Sprintf("%7d", st.Errors))
+		}
+
+		fmt.Fprintf(out, "%s %s %s %s %s\n", green(fmt.Sprintf("%-24s", st.Source)),
+			yellow(fmt.Sprintf("%9d", st.Requests)), errs,
+			yellow(fmt.Sprintf("%7d %7d", st.Names, st.UniqueNames)),
+			yellow(fmt.Sprintf("%10s", st.Duration().Round(time.Second))))
+
+		for _, cs := range st.Credentials {
+			status := green(cs.Status)
+			if cs.Status != "active" {
+				status = red(cs.Status)
+			}
+
+			fmt.Fprintf(out, "  %s %s %s\n", blue(fmt.Sprintf("%-22s", cs.Name)),
+				yellow(fmt.Sprintf("%9d", cs.Requests)), status)
+		}
+	}
+}
+
+// FprintDNSSECStatus outputs the DNSSEC validation status of the zones to the writer.
+func FprintDNSSECStatus(out io.Writer, zones []*requests.DNSSECStatus) {
+	if len(zones) == 0 {
+		return
+	}
+
+	for i := 0; i < 8; i++ {
+		b.Fprint(out, "----------")
+	}
+	fmt.Fprintf(out, "\n%s\n", blue(fmt.Sprintf("%-40s %-14s %s", "DNSSEC Zone", "Status", "Algorithm")))
+
+	for _, z := range zones {
+		status := yellow(fmt.Sprintf("%-14s", z.Status))
+		switch z.Status {
+		case "secure":
+			status = green(fmt.Sprintf("%-14s", z.Status))
+		case "bogus":
+			status = red(fmt.Sprintf("%-14s", z.Status))
+		}
+
+		fmt.Fprintf(out, "%s %s %s\n", green(fmt.Sprintf("%-40s", z.Zone)), status, yellow(z.Algorithm))
+	}
+}
+
 // PrintBanner outputs the Amass banner to stderr.
 func PrintBanner() {
 	FprintBanner(color.Error)
@@ -0,0 +1,85 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/OWASP/Amass/v3/requests"
+)
+
+// Result is a finding delivered to the output sinks, including the raw DNS records obtained for the name.
+type Result struct {
+	*requests.Output
+	Records []requests.DNSAnswer `json:"records,omitempty"`
+}
+
+// OutputSink is implemented by each destination that enumeration findings can be written to.
+type OutputSink interface {
+	fmt.Stringer
+
+	// Write delivers a single finding to the sink.
+	Write(ctx context.Context, res *Result) error
+
+	// Close flushes the pending findings and releases the resources held by the sink.
+	Close() error
+}
+
+// SinkFactory creates an OutputSink for the target provided in the sink specification.
+type SinkFactory func(target string) (OutputSink, error)
+
+var (
+	sinksLock sync.Mutex
+	sinks     = map[string]SinkFactory{
+		"csv":     newCSVSink,
+		"ndjson":  newNDJSONSink,
+		"socket":  newSocketSink,
+		"webhook": newWebhookSink,
+	}
+)
+
+// RegisterOutputSink makes the sink type available to NewOutputSink under the provided name.
+func RegisterOutputSink(name string, factory SinkFactory) {
+	sinksLock.Lock()
+	defer sinksLock.Unlock()
+
+	sinks[strings.ToLower(name)] = factory
+}
+
+// OutputSinkTypes returns the names of the registered sink types.
+func OutputSinkTypes() []string {
+	sinksLock.Lock()
+	defer sinksLock.Unlock()
+
+	var names []string
+	for name := range sinks {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// NewOutputSink returns the sink described by the specification, which has the form 'type:target'.
+func NewOutputSink(spec string) (OutputSink, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("the output sink %q is not of the form type:target", spec)
+	}
+
+	sinksLock.Lock()
+	factory, found := sinks[strings.ToLower(parts[0])]
+	sinksLock.Unlock()
+	if !found {
+		return nil, fmt.Errorf("the output sink type %q is not supported: %s",
+			parts[0], strings.Join(OutputSinkTypes(), ", "))
+	}
+
+	return factory(parts[1])
+}
@@ -0,0 +1,179 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	amasshttp "github.com/OWASP/Amass/v3/net/http"
+	"github.com/OWASP/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+func testSinkResult() *Result {
+	_, ipnet, _ := net.ParseCIDR("104.16.0.0/12")
+
+	return &Result{
+		Output: &requests.Output{
+			Name:   "www.owasp.org",
+			Domain: "owasp.org",
+			Addresses: []requests.AddressInfo{{
+				Address:     net.ParseIP("104.22.27.77"),
+				Netblock:    ipnet,
+				CIDRStr:     ipnet.String(),
+				ASN:         13335,
+				Description: "CLOUDFLARENET",
+			}},
+			Tag:     requests.CERT,
+			Sources: []string{"Crtsh", "DNS"},
+		},
+		Records: []requests.DNSAnswer{
+			{Name: "www.owasp.org", Type: int(dns.TypeCNAME), TTL: 300, Data: "owasp.org"},
+			{Name: "owasp.org", Type: int(dns.TypeA), TTL: 300, Data: "104.22.27.77"},
+		},
+	}
+}
+
+func TestNewOutputSink(t *testing.T) {
+	for _, spec := range []string{"", "ndjson", "ndjson:", "unknown:/tmp/amass.out", "webhook:/tmp/amass.out"} {
+		if _, err := NewOutputSink(spec); err == nil {
+			t.Errorf("The output sink %q was accepted", spec)
+		}
+	}
+
+	RegisterOutputSink("Test", func(target string) (OutputSink, error) {
+		return newStreamSink("Test sink: "+target, new(bufio.Writer), nil), nil
+	})
+	sink, err := NewOutputSink("test:inventory")
+	if err != nil {
+		t.Fatalf("Failed to create the registered output sink: %v", err)
+	}
+	if sink.String() != "Test sink: inventory" {
+		t.Errorf("The wrong output sink was created: %s", sink)
+	}
+}
+
+func TestNDJSONSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "amass.ndjson")
+
+	sink, err := NewOutputSink("ndjson:" + path)
+	if err != nil {
+		t.Fatalf("Failed to create the NDJSON sink: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(context.Background(), testSinkResult()); err != nil {
+			t.Fatalf("Failed to write the result: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close the NDJSON sink: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open the NDJSON file: %v", err)
+	}
+	defer f.Close()
+
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var res Result
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatalf("Failed to decode line %d: %v", lines+1, err)
+		}
+		if res.Output == nil || res.Name != "www.owasp.org" || len(res.Addresses) != 1 || len(res.Records) != 2 {
+			t.Errorf("Line %d does not contain the full result: %s", lines+1, scanner.Text())
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("Expected two lines in the NDJSON file, got %d", lines)
+	}
+}
+
+func TestCSVSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "amass.csv")
+
+	sink, err := NewOutputSink("csv:" + path)
+	if err != nil {
+		t.Fatalf("Failed to create the CSV sink: %v", err)
+	}
+	if err := sink.Write(context.Background(), testSinkResult()); err != nil {
+		t.Fatalf("Failed to write the result: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close the CSV sink: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open the CSV file: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse the CSV file: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected a header and a single row, got %d rows", len(rows))
+	}
+
+	expected := []string{"www.owasp.org", "owasp.org", requests.CERT, "Crtsh;DNS", "104.22.27.77",
+		"104.16.0.0/12", "13335", "CLOUDFLARENET", "www.owasp.org CNAME owasp.org;owasp.org A 104.22.27.77"}
+	for i, field := range rows[1] {
+		if field != expected[i] {
+			t.Errorf("Field %s was %q, expected %q", rows[0][i], field, expected[i])
+		}
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan *Result, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var res Result
+
+		if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- &res
+	}))
+	defer srv.Close()
+
+	// The findings must not be served from, or recorded to, the archive used for the data sources
+	path := filepath.Join(t.TempDir(), "archive.ndjson")
+	if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("Failed to create the archive: %v", err)
+	}
+	if err := amasshttp.ReplayResponses(path); err != nil {
+		t.Fatalf("Failed to replay the archive: %v", err)
+	}
+	defer func() { _ = amasshttp.CloseArchive() }()
+
+	sink, err := NewOutputSink("webhook:" + srv.URL)
+	if err != nil {
+		t.Fatalf("Failed to create the webhook sink: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Write(context.Background(), testSinkResult()); err != nil {
+		t.Fatalf("Failed to write the result: %v", err)
+	}
+	if res := <-received; res.Name != "www.owasp.org" || len(res.Records) != 2 {
+		t.Errorf("The webhook received the wrong finding: %+v", res)
+	}
+}
@@ -0,0 +1,122 @@
+//go:build cgo
+// +build cgo
+
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	// Registers the sqlite3 driver used by the SQLite sink
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS names (
+	name       TEXT PRIMARY KEY,
+	domain     TEXT NOT NULL,
+	tag        TEXT NOT NULL,
+	sources    TEXT NOT NULL,
+	first_seen TIMESTAMP NOT NULL,
+	last_seen  TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS addresses (
+	name        TEXT NOT NULL REFERENCES names(name),
+	address     TEXT NOT NULL,
+	cidr        TEXT,
+	asn         INTEGER,
+	description TEXT,
+	PRIMARY KEY (name, address)
+);
+CREATE TABLE IF NOT EXISTS records (
+	name  TEXT NOT NULL REFERENCES names(name),
+	type  INTEGER NOT NULL,
+	ttl   INTEGER,
+	data  TEXT NOT NULL,
+	PRIMARY KEY (name, type, data)
+);`
+
+func init() {
+	RegisterOutputSink("sqlite", newSQLiteSink)
+}
+
+// sqliteSink keeps the findings in an SQLite database, which can be shared by many enumerations.
+type sqliteSink struct {
+	path string
+	db   *sql.DB
+}
+
+// Writes the findings into the SQLite database file provided as the target.
+func newSQLiteSink(target string) (OutputSink, error) {
+	db, err := sql.Open("sqlite3", target)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only supports a single writer
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &sqliteSink{path: target, db: db}, nil
+}
+
+// String implements the Stringer interface.
+func (s *sqliteSink) String() string {
+	return "SQLite sink: " + s.path
+}
+
+// Write implements the OutputSink interface.
+func (s *sqliteSink) Write(ctx context.Context, res *Result) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := insertSQLiteResult(ctx, tx, res); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertSQLiteResult(ctx context.Context, tx *sql.Tx, res *Result) error {
+	now := time.Now().UTC()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO names (name, domain, tag, sources, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT(name) DO UPDATE SET
+		tag = excluded.tag, sources = excluded.sources, last_seen = excluded.last_seen`,
+		res.Name, res.Domain, res.Tag, strings.Join(res.Sources, ","), now, now); err != nil {
+		return err
+	}
+
+	for _, a := range res.Addresses {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO addresses (name, address, cidr, asn, description)
+			VALUES (?, ?, ?, ?, ?) ON CONFLICT(name, address) DO UPDATE SET
+			cidr = excluded.cidr, asn = excluded.asn, description = excluded.description`,
+			res.Name, a.Address.String(), a.CIDRStr, a.ASN, a.Description); err != nil {
+			return err
+		}
+	}
+
+	for _, rr := range res.Records {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO records (name, type, ttl, data)
+			VALUES (?, ?, ?, ?) ON CONFLICT(name, type, data) DO UPDATE SET ttl = excluded.ttl`,
+			res.Name, rr.Type, rr.TTL, rr.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements the OutputSink interface.
+func (s *sqliteSink) Close() error {
+	return s.db.Close()
+}
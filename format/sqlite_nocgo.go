@@ -0,0 +1,19 @@
+//go:build !cgo
+// +build !cgo
+
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import "errors"
+
+func init() {
+	RegisterOutputSink("sqlite", newSQLiteSink)
+}
+
+// The SQLite driver is built from C sources, so the sink is only available in builds with cgo enabled.
+func newSQLiteSink(target string) (OutputSink, error) {
+	return nil, errors.New("the sqlite sink requires a build with cgo enabled")
+}
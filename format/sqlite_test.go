@@ -0,0 +1,48 @@
+//go:build cgo
+// +build cgo
+
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "amass.sqlite")
+
+	for i := 0; i < 2; i++ {
+		sink, err := NewOutputSink("sqlite:" + path)
+		if err != nil {
+			t.Fatalf("Failed to create the SQLite sink: %v", err)
+		}
+		if err := sink.Write(context.Background(), testSinkResult()); err != nil {
+			t.Fatalf("Failed to write the result: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Failed to close the SQLite sink: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open the SQLite database: %v", err)
+	}
+	defer db.Close()
+
+	for table, expected := range map[string]int{"names": 1, "addresses": 1, "records": 2} {
+		var count int
+
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			t.Errorf("Failed to count the rows in the %s table: %v", table, err)
+		} else if count != expected {
+			t.Errorf("Expected %d rows in the %s table, got %d", expected, table, count)
+		}
+	}
+}
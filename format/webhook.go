@@ -0,0 +1,80 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	amasshttp "github.com/OWASP/Amass/v3/net/http"
+)
+
+const webhookTimeout = 30 * time.Second
+
+// webhookSink has its own client, so the findings are never recorded to, or replayed from, the HTTP archive.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// Posts each finding as JSON to the URL provided as the target.
+func newWebhookSink(target string) (OutputSink, error) {
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("the webhook sink requires an HTTP or HTTPS URL: %s", target)
+	}
+	return &webhookSink{
+		url: target,
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+		},
+	}, nil
+}
+
+// String implements the Stringer interface.
+func (s *webhookSink) String() string {
+	return "Webhook sink: " + s.url
+}
+
+// Write implements the OutputSink interface.
+func (s *webhookSink) Write(ctx context.Context, res *Result) error {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", amasshttp.UserAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Drain the body so the connection can be reused by the following findings
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("%d: %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// Close implements the OutputSink interface.
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,548 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amassnet "github.com/OWASP/Amass/v3/net"
+	"github.com/miekg/dns"
+)
+
+// The URL schemes that identify the encrypted DNS resolvers.
+const (
+	DoHScheme = "https"
+	DoTScheme = "tls"
+)
+
+const (
+	dohMediaType     = "application/dns-message"
+	defaultDoHPath   = "/dns-query"
+	defaultDoTPort   = "853"
+	encryptedTimeout = 5 * time.Second
+	// The scheme used internally for the plain DNS resolvers reached through the forwarder
+	plainScheme  = "udp"
+	plainTimeout = 2 * time.Second
+	// The local listeners cannot always be bound to the same port for both networks
+	maxListenAttempts = 10
+	// The number of loopback addresses that can be selected for the local servers
+	maxLoopbackAddrs = 256 * 250
+)
+
+// The root certificates used to verify the encrypted resolvers. The system roots are used when nil.
+var encryptedRootCAs *x509.CertPool
+
+// ParseEncryptedResolver returns the URL of a DNS-over-HTTPS (https://host/dns-query) or
+// DNS-over-TLS (tls://host:853) resolver. The default path and port are added when missing.
+func ParseEncryptedResolver(addr string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(addr))
+	if err != nil {
+		return nil, err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("the encrypted resolver %s has no host", addr)
+	}
+
+	switch u.Scheme {
+	case DoHScheme:
+		if u.Path == "" || u.Path == "/" {
+			u.Path = defaultDoHPath
+		}
+	case DoTScheme:
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), defaultDoTPort)
+		}
+		u.Path = ""
+	default:
+		return nil, fmt.Errorf("the resolver %s does not use a supported encrypted DNS scheme", addr)
+	}
+	return u, nil
+}
+
+// IsEncryptedResolver returns true when the resolver is specified as a DNS-over-HTTPS or DNS-over-TLS URL.
+func IsEncryptedResolver(addr string) bool {
+	if !strings.Contains(addr, "://") {
+		return false
+	}
+
+	_, err := ParseEncryptedResolver(addr)
+	return err == nil
+}
+
+type upstream struct {
+	// The counters are accessed atomically and kept first for the alignment on 32-bit systems
+	queries  uint64
+	timeouts uint64
+	sync.Mutex
+	addr      string
+	url       *url.URL
+	qps       int
+	interval  time.Duration
+	next      time.Time
+	client    *http.Client
+	tlsConfig *tls.Config
+	connLock  sync.Mutex
+	conn      *dns.Conn
+}
+
+func newUpstream(addr string, u *url.URL, qps int) *upstream {
+	if qps <= 0 {
+		qps = 1
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: u.Hostname(),
+		RootCAs:    encryptedRootCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	up := &upstream{
+		addr:      addr,
+		url:       u,
+		qps:       qps,
+		interval:  time.Second / time.Duration(qps),
+		tlsConfig: tlsConfig,
+	}
+
+	if u.Scheme == DoHScheme {
+		up.client = &http.Client{
+			Timeout: encryptedTimeout,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				DialContext:         amassnet.DialContext,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: encryptedTimeout,
+				TLSClientConfig:     tlsConfig,
+				ForceAttemptHTTP2:   true,
+			},
+		}
+	}
+	return up
+}
+
+// Paces the queries sent to the upstream resolver according to the QPS of its protocol.
+func (u *upstream) wait(ctx context.Context) error {
+	u.Lock()
+	now := time.Now()
+	if u.next.Before(now) {
+		u.next = now
+	}
+	delay := u.next.Sub(now)
+	u.next = u.next.Add(u.interval)
+	u.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+	}
+	return nil
+}
+
+// Sends the query to the upstream resolver, counting the queries sent and those that failed.
+func (u *upstream) query(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	if err := u.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	atomic.AddUint64(&u.queries, 1)
+	resp, err := u.exchange(ctx, msg)
+	if err != nil {
+		atomic.AddUint64(&u.timeouts, 1)
+	}
+	return resp, err
+}
+
+func (u *upstream) exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	switch u.url.Scheme {
+	case DoHScheme:
+		return u.dohExchange(ctx, msg)
+	case DoTScheme:
+		return u.dotExchange(ctx, msg)
+	}
+	return u.plainExchange(ctx, msg)
+}
+
+// The truncated responses are requested again over TCP.
+func (u *upstream) plainExchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	client := &dns.Client{
+		Net:     "udp",
+		UDPSize: dns.DefaultMsgSize,
+		Timeout: plainTimeout,
+	}
+
+	r, _, err := client.ExchangeContext(ctx, msg, u.url.Host)
+	if err == nil && r.Truncated {
+		client.Net = "tcp"
+		r, _, err = client.ExchangeContext(ctx, msg, u.url.Host)
+	}
+	return r, err
+}
+
+func (u *upstream) dohExchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	m := msg.Copy()
+	// The message ID is zero to allow the responses to be cached
+	m.Id = 0
+	data, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", u.url.Host, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return nil, err
+	}
+	r.Id = msg.Id
+	return r, nil
+}
+
+// The queries are sent over a persistent connection that is established again after a failure.
+func (u *upstream) dotExchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	u.connLock.Lock()
+	defer u.connLock.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if u.conn == nil {
+			if u.conn, err = u.dial(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		var r *dns.Msg
+		_ = u.conn.SetDeadline(time.Now().Add(encryptedTimeout))
+		if err = u.conn.WriteMsg(msg); err == nil {
+			r, err = u.conn.ReadMsg()
+		}
+		if err == nil {
+			return r, nil
+		}
+
+		u.conn.Close()
+		u.conn = nil
+	}
+	return nil, err
+}
+
+func (u *upstream) dial(ctx context.Context) (*dns.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, encryptedTimeout)
+	defer cancel()
+
+	conn, err := amassnet.DialContext(ctx, "tcp", u.url.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	tconn := tls.Client(conn, u.tlsConfig)
+	if err := tconn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &dns.Conn{Conn: tconn}, nil
+}
+
+func (u *upstream) close() {
+	u.connLock.Lock()
+	defer u.connLock.Unlock()
+
+	if u.conn != nil {
+		u.conn.Close()
+		u.conn = nil
+	}
+	if u.client != nil {
+		u.client.CloseIdleConnections()
+	}
+}
+
+// Forwarder runs a local DNS server for each of the encrypted resolvers, which sends the queries
+// it receives to its encrypted resolver. This allows the resolver pools, which only send plain DNS
+// queries, to use DNS-over-HTTPS and DNS-over-TLS resolvers, and to track each of them separately.
+// Plain DNS resolvers can be reached through the forwarder as well, which counts the queries and
+// timeouts of each resolver, since the resolver pools do not report them.
+type Forwarder struct {
+	sync.Mutex
+	qps       int
+	upstreams []*upstream
+	idx       int
+	locals    []*localServer
+}
+
+// localServer is a DNS server on the loopback interface that forwards the queries it receives.
+type localServer struct {
+	addr    string
+	qps     int
+	servers []*dns.Server
+}
+
+// NewForwarder starts the local DNS servers on the loopback interface, which send the queries
+// to the provided resolvers at the QPS limits selected for each protocol. The plain DNS resolvers
+// are sent the queries at the qps limit. The resolver pools identify the resolvers by IP address,
+// so each local server has its own loopback address. When the system only routes 127.0.0.1,
+// a single local server sends the queries to any of the resolvers instead.
+func NewForwarder(qps, dohQPS, dotQPS int, resolvers ...string) (*Forwarder, error) {
+	f := new(Forwarder)
+
+	for _, r := range resolvers {
+		var u *url.URL
+		limit := qps
+
+		if strings.Contains(r, "://") {
+			var err error
+			if u, err = ParseEncryptedResolver(r); err != nil {
+				return nil, err
+			}
+
+			limit = dohQPS
+			if u.Scheme == DoTScheme {
+				limit = dotQPS
+			}
+		} else {
+			host := r
+			if _, _, err := net.SplitHostPort(r); err != nil {
+				// Add the default port number to the IP address
+				host = net.JoinHostPort(r, "53")
+			}
+			u = &url.URL{Scheme: plainScheme, Host: host}
+		}
+
+		up := newUpstream(r, u, limit)
+		f.upstreams = append(f.upstreams, up)
+		f.qps += up.qps
+	}
+	if len(f.upstreams) == 0 {
+		return nil, errors.New("no resolvers were provided to the forwarder")
+	}
+
+	for i, u := range f.upstreams {
+		if i >= maxLoopbackAddrs {
+			f.stopLocals()
+			break
+		}
+
+		ls, err := listenLocal(loopbackAddr(i), u.qps, u)
+		if err != nil {
+			f.stopLocals()
+			break
+		}
+		f.locals = append(f.locals, ls)
+	}
+	if len(f.locals) == 0 {
+		ls, err := listenLocal("127.0.0.1", f.qps, f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.locals = append(f.locals, ls)
+	}
+	return f, nil
+}
+
+// Returns the loopback address of the local server for the encrypted resolver at the index.
+// The addresses are selected away from 127.0.0.1 and 127.0.0.53, often used by local resolvers.
+func loopbackAddr(idx int) string {
+	return net.IPv4(127, 53, byte(idx/250), byte(idx%250+1)).String()
+}
+
+// Binds the UDP and TCP listeners to the same port, since truncated responses are queried again over TCP.
+func listenLocal(ip string, qps int, handler dns.Handler) (*localServer, error) {
+	var err error
+
+	for i := 0; i < maxListenAttempts; i++ {
+		var pc net.PacketConn
+		pc, err = net.ListenPacket("udp", net.JoinHostPort(ip, "0"))
+		if err != nil {
+			continue
+		}
+
+		var l net.Listener
+		l, err = net.Listen("tcp", pc.LocalAddr().String())
+		if err != nil {
+			pc.Close()
+			continue
+		}
+
+		ls := &localServer{
+			addr: pc.LocalAddr().String(),
+			qps:  qps,
+			servers: []*dns.Server{
+				{PacketConn: pc, Handler: handler},
+				{Listener: l, Handler: handler},
+			},
+		}
+		for _, srv := range ls.servers {
+			go func(s *dns.Server) { _ = s.ActivateAndServe() }(srv)
+		}
+		return ls, nil
+	}
+	return nil, fmt.Errorf("failed to start the resolvers forwarder on %s: %v", ip, err)
+}
+
+// Addrs returns the addresses of the local DNS servers, one for each resolver when the loopback
+// addresses are available, mapped to the number of queries per second they accept.
+func (f *Forwarder) Addrs() map[string]int {
+	addrs := make(map[string]int, len(f.locals))
+
+	for _, ls := range f.locals {
+		addrs[ls.addr] = ls.qps
+	}
+	return addrs
+}
+
+// QPS returns the number of queries per second that can be sent to the resolvers.
+func (f *Forwarder) QPS() int {
+	return f.qps
+}
+
+// Len returns the number of resolvers used by the forwarder.
+func (f *Forwarder) Len() int {
+	return len(f.upstreams)
+}
+
+// ResolverStats holds the number of queries sent to a resolver through the forwarder,
+// and the number of those queries that failed to obtain a response.
+type ResolverStats struct {
+	Resolver string
+	Queries  uint64
+	Timeouts uint64
+}
+
+// Stats returns the number of queries and timeouts of each resolver used by the forwarder.
+func (f *Forwarder) Stats() []ResolverStats {
+	stats := make([]ResolverStats, 0, len(f.upstreams))
+
+	for _, u := range f.upstreams {
+		stats = append(stats, ResolverStats{
+			Resolver: u.addr,
+			Queries:  atomic.LoadUint64(&u.queries),
+			Timeouts: atomic.LoadUint64(&u.timeouts),
+		})
+	}
+	return stats
+}
+
+// Close stops the local DNS servers and closes the connections to the resolvers.
+func (f *Forwarder) Close() {
+	f.stopLocals()
+	for _, u := range f.upstreams {
+		u.close()
+	}
+}
+
+func (f *Forwarder) stopLocals() {
+	for _, ls := range f.locals {
+		for _, srv := range ls.servers {
+			_ = srv.Shutdown()
+		}
+	}
+	f.locals = nil
+}
+
+// Returns the resolvers in the order they are tried for the next query.
+func (f *Forwarder) selectUpstreams() []*upstream {
+	f.Lock()
+	defer f.Unlock()
+
+	n := len(f.upstreams)
+	ups := make([]*upstream, 0, n)
+	for i := 0; i < n; i++ {
+		ups = append(ups, f.upstreams[(f.idx+i)%n])
+	}
+	f.idx = (f.idx + 1) % n
+	return ups
+}
+
+// ServeDNS implements the dns.Handler interface for the local server shared by the resolvers,
+// which tries the other resolvers when a query fails.
+func (f *Forwarder) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*encryptedTimeout)
+	defer cancel()
+
+	for _, u := range f.selectUpstreams() {
+		resp, err := u.query(ctx, req)
+		if err == nil {
+			writeResponse(w, req, resp)
+			return
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	writeFailure(w, req)
+}
+
+// ServeDNS implements the dns.Handler interface for the local server of the resolver. A failure
+// is reported to the resolver pool, which tracks the resolver as it would any other resolver.
+func (u *upstream) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*encryptedTimeout)
+	defer cancel()
+
+	if resp, err := u.query(ctx, req); err == nil {
+		writeResponse(w, req, resp)
+		return
+	}
+	writeFailure(w, req)
+}
+
+func writeResponse(w dns.ResponseWriter, req, resp *dns.Msg) {
+	resp.Id = req.Id
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); udp {
+		size := dns.MinMsgSize
+		if opt := req.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		resp.Truncate(size)
+	}
+	_ = w.WriteMsg(resp)
+}
+
+func writeFailure(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetRcode(req, dns.RcodeServerFailure)
+	_ = w.WriteMsg(m)
+}
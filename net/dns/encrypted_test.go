@@ -0,0 +1,185 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseEncryptedResolver(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{"https://dns.google/dns-query", "https://dns.google/dns-query"},
+		{"HTTPS://cloudflare-dns.com", "https://cloudflare-dns.com/dns-query"},
+		{"tls://1.1.1.1", "tls://1.1.1.1:853"},
+		{"tls://dns.quad9.net:8853", "tls://dns.quad9.net:8853"},
+		{"8.8.8.8", ""},
+		{"udp://8.8.8.8", ""},
+		{"tls://", ""},
+	}
+
+	for _, tt := range tests {
+		var got string
+		if u, err := ParseEncryptedResolver(tt.addr); err == nil {
+			got = u.String()
+		}
+		if got != tt.expected {
+			t.Errorf("ParseEncryptedResolver(%s) = %s, expected %s", tt.addr, got, tt.expected)
+		}
+		if IsEncryptedResolver(tt.addr) != (tt.expected != "") {
+			t.Errorf("IsEncryptedResolver(%s) returned the wrong result", tt.addr)
+		}
+	}
+}
+
+func answerA(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Answer = append(resp.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+		A:   net.ParseIP("192.0.2.1"),
+	})
+	_ = w.WriteMsg(resp)
+}
+
+func TestForwarder(t *testing.T) {
+	var dohHits, dotHits, plainHits int32
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&dohHits, 1)
+
+		body, _ := io.ReadAll(r.Body)
+		req := new(dns.Msg)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohMediaType || req.Unpack(body) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = append(resp.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		data, _ := resp.Pack()
+		w.Header().Set("Content-Type", dohMediaType)
+		_, _ = w.Write(data)
+	}))
+	defer doh.Close()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: doh.TLS.Certificates})
+	if err != nil {
+		t.Fatalf("Failed to start the DNS-over-TLS server: %v", err)
+	}
+	dot := &dns.Server{Listener: l, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		atomic.AddInt32(&dotHits, 1)
+		answerA(w, req)
+	})}
+	go func() { _ = dot.ActivateAndServe() }()
+	defer func() { _ = dot.Shutdown() }()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start the plain DNS server: %v", err)
+	}
+	plain := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		atomic.AddInt32(&plainHits, 1)
+		answerA(w, req)
+	})}
+	go func() { _ = plain.ActivateAndServe() }()
+	defer func() { _ = plain.Shutdown() }()
+
+	encryptedRootCAs = x509.NewCertPool()
+	encryptedRootCAs.AddCert(doh.Certificate())
+	defer func() { encryptedRootCAs = nil }()
+
+	unreachable := "tls://127.0.0.1:1"
+	fwd, err := NewForwarder(10, 100, 50, doh.URL+"/dns-query", "tls://"+l.Addr().String(), unreachable, pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to start the forwarder: %v", err)
+	}
+	defer fwd.Close()
+
+	if fwd.Len() != 4 || fwd.QPS() != 210 {
+		t.Errorf("The forwarder has the wrong resolvers or QPS: %d, %d", fwd.Len(), fwd.QPS())
+	}
+	if len(fwd.locals) != fwd.Len() {
+		t.Skip("The system does not provide a loopback address for each encrypted resolver")
+	}
+	addrs := fwd.Addrs()
+
+	// Each encrypted resolver is reached through its own loopback address
+	var failures int
+	for i, ls := range fwd.locals {
+		u := fwd.upstreams[i]
+		if addrs[ls.addr] != u.qps {
+			t.Errorf("The address of %s has the wrong QPS: %d", u.url, addrs[ls.addr])
+		}
+
+		for j, network := range []string{"udp", "tcp"} {
+			msg := new(dns.Msg)
+			msg.SetQuestion("www.owasp.org.", dns.TypeA)
+
+			c := &dns.Client{Net: network, Timeout: 3 * encryptedTimeout}
+			resp, _, err := c.Exchange(msg, ls.addr)
+			if err != nil {
+				t.Fatalf("Query %d over %s failed: %v", j, network, err)
+			}
+			if u.url.String() == unreachable {
+				if resp.Rcode == dns.RcodeServerFailure {
+					failures++
+				}
+				continue
+			}
+			if resp.Id != msg.Id || len(resp.Answer) != 1 || resp.Answer[0].(*dns.A).A.String() != "192.0.2.1" {
+				t.Errorf("Query %d over %s returned the wrong response: %v", j, network, resp)
+			}
+		}
+	}
+	if failures != 2 {
+		t.Errorf("The failures of the unreachable resolver were not reported: %d", failures)
+	}
+	if atomic.LoadInt32(&dohHits) != 2 || atomic.LoadInt32(&dotHits) != 2 || atomic.LoadInt32(&plainHits) != 2 {
+		t.Errorf("The queries were not sent to their resolvers: DoH %d, DoT %d, plain %d", dohHits, dotHits, plainHits)
+	}
+	// The queries and timeouts are counted for each resolver
+	for _, st := range fwd.Stats() {
+		var timeouts uint64
+		if st.Resolver == unreachable {
+			timeouts = 2
+		}
+		if st.Queries != 2 || st.Timeouts != timeouts {
+			t.Errorf("%s has the wrong number of queries or timeouts: %d, %d", st.Resolver, st.Queries, st.Timeouts)
+		}
+	}
+
+	// The shared local server requires the queries to be sent to the other resolvers
+	shared, err := listenLocal("127.0.0.1", fwd.QPS(), fwd)
+	if err != nil {
+		t.Fatalf("Failed to start the shared local server: %v", err)
+	}
+	fwd.locals = append(fwd.locals, shared)
+
+	for i := 0; i < 3; i++ {
+		msg := new(dns.Msg)
+		msg.SetQuestion("www.owasp.org.", dns.TypeA)
+
+		c := &dns.Client{Net: "udp", Timeout: 3 * encryptedTimeout}
+		resp, _, err := c.Exchange(msg, shared.addr)
+		if err != nil || len(resp.Answer) != 1 {
+			t.Errorf("Query %d through the shared local server failed: %v", i, err)
+		}
+	}
+}
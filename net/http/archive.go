@@ -0,0 +1,403 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotArchived is returned in replay mode for requests without a recorded response.
+var ErrNotArchived = errors.New("no response was recorded for the request")
+
+// The value written to the archive in place of the credentials.
+const archiveRedacted = "REDACTED"
+
+// The fields of the OAuth2 token requests and responses that carry the credentials.
+var tokenFields = []string{"access_token", "refresh_token", "id_token", "client_secret"}
+
+var (
+	archiveLock sync.Mutex
+	archive     *archiveTransport
+)
+
+// ArchiveEntry is a single request/response pair kept in an archive file. The method
+// defaults to GET and the status to 200 OK when entries are replayed.
+type ArchiveEntry struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Body   string      `json:"body,omitempty"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Data   string      `json:"response,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+func (e *ArchiveEntry) key() string {
+	method := e.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return method + " " + e.URL + "\n" + e.Body
+}
+
+// archiveTransport records the HTTP traffic sent through the base transport,
+// or replays the traffic from a previous recording without using the network.
+type archiveTransport struct {
+	sync.Mutex
+	path    string
+	base    http.RoundTripper
+	replay  bool
+	file    *os.File
+	enc     *json.Encoder
+	entries map[string][]*ArchiveEntry
+	// Replaces the credentials in the requests and responses
+	redact *strings.Replacer
+}
+
+// RecordResponses writes every request sent by the package, along with the response received,
+// into the archive file at the provided path. Existing files are truncated, and the file is only
+// readable by the user. The secrets, such as the API keys of the data sources, are redacted.
+func RecordResponses(path string, secrets ...string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	// The permissions of an existing file are not changed by opening it
+	if err := f.Chmod(0600); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return setArchive(&archiveTransport{
+		path:   path,
+		file:   f,
+		enc:    json.NewEncoder(f),
+		redact: newRedactor(secrets),
+	})
+}
+
+// ReplayResponses serves the requests sent by the package only from the archive file at the
+// provided path. The network is never used while the archive is being replayed. The secrets
+// are redacted from the requests, so they match the entries recorded with the same secrets.
+func ReplayResponses(path string, secrets ...string) error {
+	entries, err := ReadArchive(path)
+	if err != nil {
+		return err
+	}
+	return setArchive(newReplayTransport(path, entries, secrets))
+}
+
+// NewReplayTransport returns a transport that serves the requests only from the provided entries.
+// Unlike ReplayResponses, the package client is not changed, so the transport can be provided
+// with the requests that should not use the network while the others are sent as usual.
+func NewReplayTransport(entries []*ArchiveEntry) http.RoundTripper {
+	return newReplayTransport("in memory", entries, nil)
+}
+
+func newReplayTransport(path string, entries []*ArchiveEntry, secrets []string) *archiveTransport {
+	t := &archiveTransport{
+		path:    path,
+		replay:  true,
+		entries: make(map[string][]*ArchiveEntry),
+		redact:  newRedactor(secrets),
+	}
+
+	for _, e := range entries {
+		t.entries[e.key()] = append(t.entries[e.key()], e)
+	}
+	return t
+}
+
+// Archiving returns true when responses are being recorded to, or replayed from, an archive file.
+func Archiving() bool {
+	archiveLock.Lock()
+	defer archiveLock.Unlock()
+
+	return archive != nil
+}
+
+// Replaying returns true when responses are being served from an archive file.
+func Replaying() bool {
+	archiveLock.Lock()
+	defer archiveLock.Unlock()
+
+	return archive != nil && archive.replay
+}
+
+// CloseArchive stops recording or replaying HTTP responses and closes the archive file.
+func CloseArchive() error {
+	archiveLock.Lock()
+	defer archiveLock.Unlock()
+
+	if archive == nil {
+		return nil
+	}
+
+	var err error
+	DefaultClient.Transport = archive.base
+	if archive.file != nil {
+		err = archive.file.Close()
+	}
+	archive = nil
+	return err
+}
+
+func setArchive(t *archiveTransport) error {
+	archiveLock.Lock()
+	defer archiveLock.Unlock()
+
+	if archive != nil {
+		if t.file != nil {
+			_ = t.file.Close()
+		}
+		return fmt.Errorf("the HTTP archive %s is already in use", archive.path)
+	}
+
+	t.base = DefaultClient.Transport
+	DefaultClient.Transport = t
+	archive = t
+	return nil
+}
+
+// Returns the replacer for the secrets, including their escaped forms found in URLs.
+func newRedactor(secrets []string) *strings.Replacer {
+	set := make(map[string]struct{})
+	for _, s := range secrets {
+		if s == "" {
+			continue
+		}
+
+		set[s] = struct{}{}
+		set[url.QueryEscape(s)] = struct{}{}
+		set[url.PathEscape(s)] = struct{}{}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(set))
+	for s := range set {
+		values = append(values, s)
+	}
+	// The longer secrets are replaced first, since they can contain the shorter ones
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	oldnew := make([]string, 0, len(values)*2)
+	for _, s := range values {
+		oldnew = append(oldnew, s, archiveRedacted)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+func (t *archiveTransport) redacted(s string) string {
+	if t.redact == nil {
+		return s
+	}
+	return t.redact.Replace(s)
+}
+
+// Replaces the values of the OAuth2 token fields found in JSON objects or form encoded data, so the tokens
+// exchanged for the client credentials are not written to the archive. The replayed tokens are still accepted.
+func redactTokens(s string) string {
+	var found bool
+	for _, f := range tokenFields {
+		if strings.Contains(s, f) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return s
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &obj); err == nil {
+		var redacted bool
+		for _, f := range tokenFields {
+			if _, ok := obj[f]; ok {
+				obj[f] = json.RawMessage(`"` + archiveRedacted + `"`)
+				redacted = true
+			}
+		}
+		if !redacted {
+			return s
+		}
+		if b, err := json.Marshal(obj); err == nil {
+			return string(b)
+		}
+		return s
+	}
+
+	if vals, err := url.ParseQuery(s); err == nil {
+		var redacted bool
+		for _, f := range tokenFields {
+			if _, ok := vals[f]; ok {
+				vals.Set(f, archiveRedacted)
+				redacted = true
+			}
+		}
+		if redacted {
+			return vals.Encode()
+		}
+	}
+	return s
+}
+
+// ReadArchive returns the entries kept in the archive file at the provided path.
+func ReadArchive(path string) ([]*ArchiveEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*ArchiveEntry
+	reader := bufio.NewReader(f)
+	for num := 1; ; num++ {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e ArchiveEntry
+
+			if err := json.Unmarshal(line, &e); err != nil {
+				return nil, fmt.Errorf("%s: line %d: %v", path, num, err)
+			}
+			entries = append(entries, &e)
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *archiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body string
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		body = string(b)
+		req.Body = ioutil.NopCloser(strings.NewReader(body))
+	}
+
+	// The credentials are sent in the request, but never written to, or looked up in, the archive
+	entry := &ArchiveEntry{
+		Method: req.Method,
+		URL:    t.redacted(req.URL.String()),
+		Body:   redactTokens(t.redacted(body)),
+	}
+	if t.replay {
+		return t.replayEntry(req, entry)
+	}
+	return t.recordEntry(req, entry)
+}
+
+func (t *archiveTransport) recordEntry(req *http.Request, entry *ArchiveEntry) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = t.redacted(err.Error())
+		return nil, t.write(entry, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		entry.Error = err.Error()
+		return nil, t.write(entry, err)
+	}
+
+	entry.Status = resp.StatusCode
+	entry.Header = resp.Header
+	if t.redact != nil {
+		// Redirects and echoed requests can carry the credentials in the headers
+		entry.Header = resp.Header.Clone()
+		for _, values := range entry.Header {
+			for i, v := range values {
+				values[i] = t.redacted(v)
+			}
+		}
+	}
+	entry.Data = redactTokens(t.redacted(string(data)))
+	if err := t.write(entry, nil); err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+func (t *archiveTransport) write(entry *ArchiveEntry, rterr error) error {
+	t.Lock()
+	defer t.Unlock()
+
+	if err := t.enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write to the HTTP archive %s: %v", t.path, err)
+	}
+	return rterr
+}
+
+// Responses recorded more than once for the same request are served in the
+// order they were recorded, and the last one is repeated once the others are used.
+func (t *archiveTransport) replayEntry(req *http.Request, entry *ArchiveEntry) (*http.Response, error) {
+	t.Lock()
+	recorded := t.entries[entry.key()]
+	if len(recorded) > 1 {
+		t.entries[entry.key()] = recorded[1:]
+	}
+	t.Unlock()
+
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("%s %s: %w", entry.Method, entry.URL, ErrNotArchived)
+	}
+
+	e := recorded[0]
+	if e.Error != "" {
+		return nil, errors.New(e.Error)
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := e.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(e.Data)),
+		ContentLength: int64(len(e.Data)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,199 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	var count int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Count", "recorded")
+		_, _ = w.Write([]byte(r.Method + " " + string(body)))
+	}))
+
+	path := filepath.Join(t.TempDir(), "http.archive")
+	if err := RecordResponses(path); err != nil {
+		t.Fatalf("Failed to start the recording: %v", err)
+	}
+	if err := ReplayResponses(path); err == nil {
+		t.Errorf("A second archive was started while recording")
+	}
+
+	ctx := context.Background()
+	if page, err := RequestWebPage(ctx, ts.URL+"/page", nil, nil, nil); err != nil || page != "GET " {
+		t.Errorf("The recorded GET request returned %q: %v", page, err)
+	}
+	if page, err := RequestWebPage(ctx, ts.URL+"/page", strings.NewReader("data"), nil, nil); err != nil || page != "POST data" {
+		t.Errorf("The recorded POST request returned %q: %v", page, err)
+	}
+	if _, err := RequestWebPage(ctx, ts.URL+"/missing", nil, nil, nil); err == nil {
+		t.Errorf("The recorded request for a missing page did not fail")
+	}
+	if err := CloseArchive(); err != nil {
+		t.Fatalf("Failed to close the archive: %v", err)
+	}
+	ts.Close()
+
+	if err := ReplayResponses(path); err != nil {
+		t.Fatalf("Failed to start the replay: %v", err)
+	}
+	defer func() { _ = CloseArchive() }()
+
+	if !Replaying() {
+		t.Errorf("The replay mode was not reported")
+	}
+	if page, err := RequestWebPage(ctx, ts.URL+"/page", strings.NewReader("data"), nil, nil); err != nil || page != "POST data" {
+		t.Errorf("The replayed POST request returned %q: %v", page, err)
+	}
+	for i := 0; i < 2; i++ {
+		if page, err := RequestWebPage(ctx, ts.URL+"/page", nil, nil, nil); err != nil || page != "GET " {
+			t.Errorf("The replayed GET request returned %q: %v", page, err)
+		}
+	}
+	if _, err := RequestWebPage(ctx, ts.URL+"/missing", nil, nil, nil); err == nil || !strings.HasPrefix(err.Error(), "404") {
+		t.Errorf("The replayed request for a missing page returned the wrong error: %v", err)
+	}
+	if _, err := RequestWebPage(ctx, ts.URL+"/other", nil, nil, nil); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("The request missing from the archive returned the wrong error: %v", err)
+	}
+	if _, err := Crawl(ctx, ts.URL, []string{"owasp.org"}, 10); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("The crawler was not disabled during the replay: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected three requests to reach the server, got %d", count)
+	}
+}
+
+func TestArchiveRedactsSecrets(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.URL.Query().Get("key") + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "http.archive")
+	if err := RecordResponses(path, "s3cr3t+key", "t0ken"); err != nil {
+		t.Fatalf("Failed to start the recording: %v", err)
+	}
+
+	ctx := context.Background()
+	if page, err := RequestWebPage(ctx, ts.URL+"/?key=s3cr3t%2Bkey", strings.NewReader("token=t0ken"), nil, nil); err != nil || page != "s3cr3t+key token=t0ken" {
+		t.Errorf("The request was not sent with the credentials: %q: %v", page, err)
+	}
+	if err := CloseArchive(); err != nil {
+		t.Fatalf("Failed to close the archive: %v", err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("The archive was not created with restricted permissions: %v", info.Mode())
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the archive: %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") || strings.Contains(string(data), "t0ken") {
+		t.Errorf("The credentials were written to the archive: %s", data)
+	}
+
+	if err := ReplayResponses(path, "s3cr3t+key", "t0ken"); err != nil {
+		t.Fatalf("Failed to start the replay: %v", err)
+	}
+	defer func() { _ = CloseArchive() }()
+
+	if page, err := RequestWebPage(ctx, ts.URL+"/?key=s3cr3t%2Bkey", strings.NewReader("token=t0ken"), nil, nil); err != nil || page != "REDACTED token=REDACTED" {
+		t.Errorf("The replayed request returned %q: %v", page, err)
+	}
+}
+
+func TestArchiveRedactsTokens(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			// The credentials are accepted in the header or the form, depending on the auth style
+			id, secret, ok := r.BasicAuth()
+			if !ok {
+				_ = r.ParseForm()
+				id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+			}
+			if id != "client" || secret != "s3cr3t" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"t0k3n","refresh_token":"r3fr3sh","token_type":"bearer","expires_in":3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("Success"))
+	}))
+	defer ts.Close()
+
+	cc := &ClientCredentials{
+		TokenURL:     ts.URL + "/token",
+		ClientID:     "client",
+		ClientSecret: "s3cr3t",
+	}
+	defer InvalidateToken(cc)
+
+	path := filepath.Join(t.TempDir(), "http.archive")
+	if err := RecordResponses(path); err != nil {
+		t.Fatalf("Failed to start the recording: %v", err)
+	}
+
+	ctx := context.Background()
+	if resp, err := SendRequest(ctx, &Request{URL: ts.URL + "/api", OAuth2: cc}); err != nil || resp.Body != "Success" {
+		t.Errorf("The request was not authorized: %v", err)
+	}
+	if err := CloseArchive(); err != nil {
+		t.Fatalf("Failed to close the archive: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the archive: %v", err)
+	}
+	if !strings.Contains(string(data), "/token") {
+		t.Errorf("The token exchange was not written to the archive: %s", data)
+	}
+	for _, secret := range []string{"t0k3n", "r3fr3sh", "s3cr3t"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("The %s credential was written to the archive: %s", secret, data)
+		}
+	}
+
+	// The redacted token is obtained from the archive without using the network
+	InvalidateToken(cc)
+	if err := ReplayResponses(path); err != nil {
+		t.Fatalf("Failed to start the replay: %v", err)
+	}
+	defer func() { _ = CloseArchive() }()
+
+	if resp, err := SendRequest(ctx, &Request{URL: ts.URL + "/api", OAuth2: cc}); err != nil || resp.Body != "Success" {
+		t.Errorf("The replayed request failed: %v", err)
+	}
+	if tok, err := BearerToken(ctx, cc); err != nil || tok.AccessToken != "REDACTED" {
+		t.Errorf("The replayed token was not redacted: %v", err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	amassnet "github.com/OWASP/Amass/v3/net"
 	"github.com/OWASP/Amass/v3/net/dns"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/caffix/resolve"
 	"github.com/caffix/stringset"
 	"github.com/geziyor/geziyor"
 	"github.com/geziyor/geziyor/client"
@@ -109,43 +110,181 @@ func CheckCookie(urlString string, cookieName string) bool {
 	return found
 }
 
+// Request contains the parameters of an HTTP client request sent by Amass.
+type Request struct {
+	Method string
+	URL    string
+	Body   string
+	Header map[string]string
+	Auth   *BasicAuth
+	// The requests are authorized using bearer tokens when client credentials are provided
+	OAuth2 *ClientCredentials
+	// The transport used instead of the package client, such as the responses served to tests
+	Transport http.RoundTripper
+}
+
+// Response contains the status code, headers and body returned for a Request.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   string
+}
+
 // RequestWebPage returns a string containing the entire response for the provided URL when successful.
 func RequestWebPage(ctx context.Context, u string, body io.Reader, hvals map[string]string, auth *BasicAuth) (string, error) {
-	method := "GET"
+	req := &Request{
+		Method: http.MethodGet,
+		URL:    u,
+		Header: hvals,
+		Auth:   auth,
+	}
+
 	if body != nil {
-		method = "POST"
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+
+		req.Method = http.MethodPost
+		req.Body = string(b)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, u, body)
-	if err != nil {
+	resp, err := SendRequest(ctx, req)
+	if resp == nil {
 		return "", err
 	}
+	return resp.Body, err
+}
+
+// SendRequest sends the HTTP client request and returns the response. The method defaults to GET.
+// An error is returned along with the response when the status code does not indicate success.
+func SendRequest(ctx context.Context, r *Request) (*Response, error) {
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
 	req.Close = true
 
-	if auth != nil && auth.Username != "" && auth.Password != "" {
+	if auth := r.Auth; auth != nil && auth.Username != "" && auth.Password != "" {
 		req.SetBasicAuth(auth.Username, auth.Password)
 	}
 
 	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Accept", Accept)
 	req.Header.Set("Accept-Language", AcceptLang)
-	for k, v := range hvals {
+	for k, v := range r.Header {
 		req.Header.Set(k, v)
 	}
 
-	var in string
-	resp, err := DefaultClient.Do(req)
-	if err == nil {
-		defer func() { _ = resp.Body.Close() }()
+	client := DefaultClient
+	if r.Transport != nil {
+		client = &http.Client{
+			Timeout:   DefaultClient.Timeout,
+			Transport: r.Transport,
+		}
+	}
+
+	if r.OAuth2 != nil {
+		tok, err := bearerToken(ctx, r.OAuth2, client)
+		if err != nil {
+			return nil, err
+		}
+		tok.SetAuthHeader(req)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if r.OAuth2 != nil && resp.StatusCode == http.StatusUnauthorized {
+		// The token could have been revoked before it expired
+		InvalidateToken(r.OAuth2)
+	}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-			err = fmt.Errorf("%d: %s", resp.StatusCode, resp.Status)
+	result := &Response{
+		Status: resp.StatusCode,
+		Header: resp.Header,
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		err = fmt.Errorf("%d: %s", resp.StatusCode, resp.Status)
+	}
+	if b, err := ioutil.ReadAll(resp.Body); err == nil {
+		result.Body = string(b)
+	}
+	return result, err
+}
+
+// The status codes that indicate a transient failure worth sending the request again.
+var retryStatusCodes = map[int]struct{}{
+	http.StatusRequestTimeout:      {},
+	http.StatusTooManyRequests:     {},
+	http.StatusInternalServerError: {},
+	http.StatusBadGateway:          {},
+	http.StatusServiceUnavailable:  {},
+	http.StatusGatewayTimeout:      {},
+	522:                            {},
+	524:                            {},
+}
+
+const (
+	initialRetryDelay = time.Second
+	maximumRetryDelay = 30 * time.Second
+	maximumRetryAfter = 2 * time.Minute
+)
+
+// RetryDelay returns the time to wait before the request is sent again, based on the number of
+// attempts already made. The Retry-After header is honored when the server provides it. False is
+// returned when the status code does not indicate a transient failure, or the server asked for a
+// delay too long to be waited on.
+func RetryDelay(resp *Response, attempt int) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	if _, found := retryStatusCodes[resp.Status]; !found {
+		return 0, false
+	}
+
+	if delay, found := RetryAfter(resp); found {
+		if delay > maximumRetryAfter {
+			return 0, false
 		}
-		if b, err := ioutil.ReadAll(resp.Body); err == nil {
-			in = string(b)
+		if delay > 0 {
+			return delay, true
 		}
 	}
-	return in, err
+	return resolve.TruncatedExponentialBackoff(attempt, initialRetryDelay, maximumRetryDelay), true
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of the response,
+// which can provide the number of seconds to wait or the time to send the request again.
+func RetryAfter(resp *Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	after := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if after == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(after); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(after); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
 }
 
 // Crawl will spider the web page at the URL argument looking for DNS names within the scope provided.
@@ -155,6 +294,10 @@ func Crawl(ctx context.Context, u string, scope []string, max int) ([]string, er
 		return nil, fmt.Errorf("the context expired")
 	default:
 	}
+	// The crawler cannot be served from the archive
+	if Replaying() {
+		return nil, fmt.Errorf("%s: %w", u, ErrNotArchived)
+	}
 
 	results := stringset.New()
 	defer results.Close()
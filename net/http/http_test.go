@@ -137,6 +137,39 @@ func TestRequestWebPage(t *testing.T) {
 	}
 }
 
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		status int
+		after  string
+		retry  bool
+		delay  time.Duration
+	}{
+		{http.StatusOK, "", false, 0},
+		{http.StatusNotFound, "", false, 0},
+		{http.StatusTooManyRequests, "5", true, 5 * time.Second},
+		{http.StatusServiceUnavailable, time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), false, 0},
+		{http.StatusBadGateway, "", true, 0},
+	}
+
+	for _, test := range tests {
+		resp := &Response{Status: test.status, Header: make(http.Header)}
+		if test.after != "" {
+			resp.Header.Set("Retry-After", test.after)
+		}
+
+		delay, retry := RetryDelay(resp, 0)
+		if retry != test.retry {
+			t.Errorf("Status %d: expected retry to be %t", test.status, test.retry)
+		}
+		if test.delay > 0 && delay != test.delay {
+			t.Errorf("Status %d: expected a delay of %v, got %v", test.status, test.delay, delay)
+		}
+		if retry && (delay <= 0 || delay > maximumRetryAfter) {
+			t.Errorf("Status %d: the delay %v is out of range", test.status, delay)
+		}
+	}
+}
+
 func TestCrawl(t *testing.T) {
 	tests := []struct {
 		name  string
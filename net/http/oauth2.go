@@ -0,0 +1,119 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Tokens are refreshed when they will expire within this window of time.
+const tokenRefreshWindow = time.Minute
+
+// ClientCredentials contains the values required to obtain bearer tokens using the
+// OAuth2 client credentials grant.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type cachedToken struct {
+	sync.Mutex
+	token *oauth2.Token
+}
+
+var (
+	tokensLock sync.Mutex
+	tokens     = make(map[string]*cachedToken)
+)
+
+func (cc *ClientCredentials) key() string {
+	return cc.TokenURL + "\n" + cc.ClientID + "\n" + strings.Join(cc.Scopes, " ")
+}
+
+func cachedTokenEntry(cc *ClientCredentials) *cachedToken {
+	tokensLock.Lock()
+	defer tokensLock.Unlock()
+
+	key := cc.key()
+	if _, found := tokens[key]; !found {
+		tokens[key] = new(cachedToken)
+	}
+	return tokens[key]
+}
+
+// BearerToken returns an access token for the client credentials. Tokens are cached and shared
+// by all the requests using the same credentials, and a new token is obtained from the token
+// endpoint shortly before the cached token expires.
+func BearerToken(ctx context.Context, cc *ClientCredentials) (*oauth2.Token, error) {
+	return bearerToken(ctx, cc, DefaultClient)
+}
+
+func bearerToken(ctx context.Context, cc *ClientCredentials, client *http.Client) (*oauth2.Token, error) {
+	entry := cachedTokenEntry(cc)
+	// Only one request for a new token is sent at a time
+	entry.Lock()
+	defer entry.Unlock()
+
+	if tok := entry.token; tok != nil && tok.AccessToken != "" &&
+		(tok.Expiry.IsZero() || time.Until(tok.Expiry) > tokenRefreshWindow) {
+		return tok, nil
+	}
+
+	conf := &clientcredentials.Config{
+		ClientID:     cc.ClientID,
+		ClientSecret: cc.ClientSecret,
+		TokenURL:     cc.TokenURL,
+		Scopes:       cc.Scopes,
+	}
+	// The token requests are sent using the same client as the requests, so they can also be archived.
+	// The archive keeps the exchange with the tokens redacted, and the replayed tokens are still accepted.
+	tok, err := conf.Token(context.WithValue(ctx, oauth2.HTTPClient, client))
+	if err != nil {
+		return nil, err
+	}
+
+	entry.token = tok
+	return tok, nil
+}
+
+// InvalidateToken removes the cached token for the client credentials, so a new token
+// is obtained for the next request. This is useful after the API rejected the token.
+func InvalidateToken(cc *ClientCredentials) {
+	entry := cachedTokenEntry(cc)
+
+	entry.Lock()
+	defer entry.Unlock()
+	entry.token = nil
+}
+
+type tokenSource struct {
+	ctx context.Context
+	cc  *ClientCredentials
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (ts *tokenSource) Token() (*oauth2.Token, error) {
+	return BearerToken(ts.ctx, ts.cc)
+}
+
+// TokenSource returns an oauth2.TokenSource that provides the cached tokens for the client credentials.
+func TokenSource(ctx context.Context, cc *ClientCredentials) oauth2.TokenSource {
+	return &tokenSource{ctx: ctx, cc: cc}
+}
+
+// OAuth2Client returns an HTTP client that authorizes the requests using the tokens obtained for
+// the client credentials. The requests are sent using the transport of the package client.
+func OAuth2Client(ctx context.Context, cc *ClientCredentials) *http.Client {
+	return oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, DefaultClient), TokenSource(ctx, cc))
+}
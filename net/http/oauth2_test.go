@@ -0,0 +1,74 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestBearerToken(t *testing.T) {
+	var lock sync.Mutex
+	var issued int
+	expires := 3600
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if r.URL.Path == "/token" {
+			if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			issued++
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token":"token%d","token_type":"bearer","expires_in":%d}`, issued, expires)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != fmt.Sprintf("Bearer token%d", issued) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "Success")
+	}))
+	defer ts.Close()
+
+	cc := &ClientCredentials{
+		TokenURL:     ts.URL + "/token",
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if resp, err := SendRequest(ctx, &Request{URL: ts.URL + "/api", OAuth2: cc}); err != nil || resp.Body != "Success" {
+			t.Fatalf("The request was not authorized: %v", err)
+		}
+	}
+	lock.Lock()
+	if issued != 1 {
+		t.Errorf("Expected the token to be cached, but %d tokens were issued", issued)
+	}
+	expires = 30
+	lock.Unlock()
+
+	// Tokens expiring soon are refreshed before they are used
+	InvalidateToken(cc)
+	for i := 0; i < 2; i++ {
+		if tok, err := BearerToken(ctx, cc); err != nil || tok.AccessToken != fmt.Sprintf("token%d", i+2) {
+			t.Errorf("The token was not refreshed before it expired: %v", err)
+		}
+	}
+
+	cc.ClientSecret = "wrong"
+	if _, err := BearerToken(ctx, cc); err == nil {
+		t.Errorf("A token was returned for invalid client credentials")
+	}
+}
@@ -29,6 +29,7 @@ const (
 	RIR      = "rir"
 	EXTERNAL = "ext"
 	SCRAPE   = "scrape"
+	NSEC3    = "nsec3"
 )
 
 // Request Pub/Sub topics used across Amass.
@@ -332,6 +333,42 @@ func (o *Output) Complete(passive bool) bool {
 	return true
 }
 
+// SourceStats contains the activity of a data source during an enumeration.
+type SourceStats struct {
+	Source      string    `json:"source"`
+	Requests    int       `json:"requests"`
+	Errors      int       `json:"errors"`
+	LastError   string    `json:"last_error,omitempty"`
+	Names       int       `json:"names"`
+	UniqueNames int       `json:"unique_names"`
+	First       time.Time `json:"first_request"`
+	Last        time.Time `json:"last_activity"`
+	// The use of each credentials set configured for the data source
+	Credentials []*CredentialsStats `json:"credentials,omitempty"`
+}
+
+// CredentialsStats contains the requests a data source sent using a credentials set and its status.
+type CredentialsStats struct {
+	Name     string `json:"name"`
+	Requests int    `json:"requests"`
+	Status   string `json:"status"`
+}
+
+// DNSSECStatus contains the result of validating the DNSSEC chain of trust for a zone.
+type DNSSECStatus struct {
+	Zone      string `json:"zone"`
+	Status    string `json:"status"`
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+// Duration returns the time between the first request sent to the data source and its last activity.
+func (s *SourceStats) Duration() time.Duration {
+	if s.First.IsZero() || s.Last.Before(s.First) {
+		return 0
+	}
+	return s.Last.Sub(s.First)
+}
+
 // AddressInfo stores all network addressing info for the Output type.
 type AddressInfo struct {
 	Address     net.IP     `json:"ip"`
@@ -361,3 +398,20 @@ func SanitizeDNSRequest(req *DNSRequest) {
 	req.Domain = strings.TrimSpace(req.Domain)
 	req.Domain = strings.Trim(req.Domain, ".")
 }
+
+// RequestDomain returns the root domain name of the request, or an empty string for requests without one.
+func RequestDomain(req interface{}) string {
+	switch v := req.(type) {
+	case *DNSRequest:
+		return v.Domain
+	case *ResolvedRequest:
+		return v.Domain
+	case *SubdomainRequest:
+		return v.Domain
+	case *AddrRequest:
+		return v.Domain
+	case *WhoisRequest:
+		return v.Domain
+	}
+	return ""
+}
@@ -500,3 +500,24 @@ func TestASNRequestValid(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestDomain(t *testing.T) {
+	tests := []struct {
+		Req      interface{}
+		Expected string
+	}{
+		{&DNSRequest{Name: "www.owasp.org", Domain: "owasp.org"}, "owasp.org"},
+		{&ResolvedRequest{Name: "www.owasp.org", Domain: "owasp.org"}, "owasp.org"},
+		{&SubdomainRequest{Name: "dev.owasp.org", Domain: "owasp.org"}, "owasp.org"},
+		{&AddrRequest{Address: "104.22.27.77", Domain: "owasp.org"}, "owasp.org"},
+		{&WhoisRequest{Domain: "owasp.org"}, "owasp.org"},
+		{&ASNRequest{ASN: 13335}, ""},
+		{nil, ""},
+	}
+
+	for _, test := range tests {
+		if d := RequestDomain(test.Req); d != test.Expected {
+			t.Errorf("%T returned %q instead of %q", test.Req, d, test.Expected)
+		}
+	}
+}
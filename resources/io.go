@@ -11,10 +11,15 @@ import (
 	"io"
 	"io/fs"
 	"net"
+	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
+// ScriptModuleExt is the file extension of the Lua modules that can be required by ADS scripts.
+const ScriptModuleExt = ".lua"
+
 //go:embed scripts ip2asn-combined.tsv.gz alterations.txt namelist.txt user_agents.txt
 var resourceFS embed.FS
 
@@ -92,6 +97,40 @@ func GetDefaultScripts() ([]string, error) {
 	return scripts, ferr
 }
 
+// GetScriptModules returns the shared Lua modules embedded for the ADS scripts, keyed by module name.
+func GetScriptModules() (map[string]string, error) {
+	modules := make(map[string]string)
+	// The embedded file system always uses forward slashes
+	root := path.Join("scripts", "lib")
+
+	ferr := fs.WalkDir(resourceFS, root, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Is this file not a Lua module?
+		if d.IsDir() || path.Ext(d.Name()) != ScriptModuleExt {
+			return nil
+		}
+		// Get the module content
+		data, err := resourceFS.ReadFile(fpath)
+		if err != nil {
+			return err
+		}
+
+		modules[ScriptModuleName(strings.TrimPrefix(fpath, root+"/"))] = string(data)
+		return nil
+	})
+
+	return modules, ferr
+}
+
+// ScriptModuleName returns the name used to require the Lua module at the relative file path.
+func ScriptModuleName(rel string) string {
+	name := strings.TrimSuffix(filepath.ToSlash(rel), ScriptModuleExt)
+
+	return strings.ReplaceAll(name, "/", ".")
+}
+
 func GetResourceFile(path string) (io.Reader, error) {
 	file, err := resourceFS.Open(path)
 	if err != nil {
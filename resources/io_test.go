@@ -20,3 +20,15 @@ func TestGetDefaultScripts(t *testing.T) {
 	}
 }
 
+func TestGetScriptModules(t *testing.T) {
+	modules, err := GetScriptModules()
+	if err != nil {
+		t.Fatalf("GetScriptModules() error = %v", err)
+	}
+	if _, found := modules["utils"]; !found {
+		t.Errorf("GetScriptModules() did not return the utils module")
+	}
+	if name := ScriptModuleName("amass/helpers.lua"); name != "amass.helpers" {
+		t.Errorf("ScriptModuleName() = %s, want amass.helpers", name)
+	}
+}
@@ -0,0 +1,72 @@
+-- Copyright © by Jeff Foley 2017-2022. All rights reserved.
+-- Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+-- SPDX-License-Identifier: Apache-2.0
+
+-- Helper functions shared by the ADS scripts. Use local utils = require("utils") to access them.
+local utils = {}
+
+-- Returns a table containing the substrings of str separated by the delim character.
+function utils.split(str, delim)
+    local result = {}
+    local pattern = "[^%" .. delim .. "]+"
+
+    local matches = find(str, pattern)
+    if (matches == nil or #matches == 0) then
+        return result
+    end
+
+    for _, match in pairs(matches) do
+        table.insert(result, match)
+    end
+
+    return result
+end
+
+-- Joins the parts from the first to the last index using the sep string.
+function utils.partial_join(parts, sep, first, last)
+    if (first < 1 or last > #parts) then
+        return ""
+    end
+
+    local result = parts[first]
+    first = first + 1
+
+    for i=first,last do
+        result = result .. sep .. parts[i]
+    end
+
+    return result
+end
+
+-- Removes the leading and trailing whitespace from the string.
+function utils.trim_space(s)
+    if (s == nil) then
+        return ""
+    end
+
+    return s:match("^%s*(.-)%s*$")
+end
+
+-- Inserts the name into the table used as a set.
+function utils.set_insert(tb, name)
+    if name ~= "" then
+        tb[name] = true
+    end
+
+    return tb
+end
+
+-- Inserts the names in the list into the table used as a set.
+function utils.set_insert_many(tb, list)
+    if list == nil then
+        return tb
+    end
+
+    for _, v in pairs(list) do
+        tb[v] = true
+    end
+
+    return tb
+end
+
+return utils
@@ -17,6 +17,7 @@ import (
 	"github.com/OWASP/Amass/v3/config"
 	"github.com/OWASP/Amass/v3/limits"
 	amassnet "github.com/OWASP/Amass/v3/net"
+	amassdns "github.com/OWASP/Amass/v3/net/dns"
 	"github.com/OWASP/Amass/v3/requests"
 	"github.com/OWASP/Amass/v3/resources"
 	"github.com/caffix/netmap"
@@ -29,11 +30,13 @@ type LocalSystem struct {
 	Cfg               *config.Config
 	pool              *resolve.Resolvers
 	trusted           *resolve.Resolvers
+	forwarders        map[string]*amassdns.Forwarder
 	graphs            []*netmap.Graph
 	cache             *requests.ASNCache
 	done              chan struct{}
 	doneAlreadyClosed bool
 	addSource         chan service.Service
+	removeSource      chan service.Service
 	allSources        chan chan []service.Service
 }
 
@@ -49,21 +52,37 @@ func NewLocalSystem(cfg *config.Config) (*LocalSystem, error) {
 	}
 
 	max := int(float64(limits.GetFileLimit()) * 0.7)
-	trusted, num := trustedResolvers(cfg, max)
+	forwarders := make(map[string]*amassdns.Forwarder)
+	stopForwarders := func() {
+		for _, f := range forwarders {
+			f.Close()
+		}
+	}
+
+	trusted, num, fwd := trustedResolvers(cfg, max)
+	if fwd != nil {
+		forwarders["trusted"] = fwd
+	}
 	if trusted == nil || num == 0 {
+		stopForwarders()
 		return nil, errors.New("the system was unable to build the pool of trusted resolvers")
 	}
 	max -= num
 	if set {
-		cfg.MaxDNSQueries += num * cfg.TrustedQPS
+		cfg.MaxDNSQueries += poolQPS(num, cfg.TrustedQPS, fwd)
 	}
 
-	pool, num := untrustedResolvers(cfg, max)
+	pool, num, fwd := untrustedResolvers(cfg, max)
+	if fwd != nil {
+		forwarders["untrusted"] = fwd
+	}
 	if pool == nil || num == 0 {
+		trusted.Stop()
+		stopForwarders()
 		return nil, errors.New("the system was unable to build the pool of untrusted resolvers")
 	}
 	if set {
-		cfg.MaxDNSQueries += num * cfg.ResolversQPS
+		cfg.MaxDNSQueries += poolQPS(num, cfg.ResolversQPS, fwd)
 	} else {
 		pool.SetMaxQPS(cfg.MaxDNSQueries)
 	}
@@ -73,13 +92,15 @@ func NewLocalSystem(cfg *config.Config) (*LocalSystem, error) {
 	pool.SetRateTracker(rate)
 
 	sys := &LocalSystem{
-		Cfg:        cfg,
-		pool:       pool,
-		trusted:    trusted,
-		cache:      requests.NewASNCache(),
-		done:       make(chan struct{}, 2),
-		addSource:  make(chan service.Service),
-		allSources: make(chan chan []service.Service, 10),
+		Cfg:          cfg,
+		pool:         pool,
+		trusted:      trusted,
+		forwarders:   forwarders,
+		cache:        requests.NewASNCache(),
+		done:         make(chan struct{}, 2),
+		addSource:    make(chan service.Service),
+		removeSource: make(chan service.Service),
+		allSources:   make(chan chan []service.Service, 10),
 	}
 
 	// Load the ASN information into the cache
@@ -117,6 +138,17 @@ func (l *LocalSystem) TrustedResolvers() *resolve.Resolvers {
 	return l.trusted
 }
 
+// ResolverStats returns the number of queries and timeouts of each resolver reached through
+// a local forwarder, for the "untrusted" and "trusted" resolver pools.
+func (l *LocalSystem) ResolverStats() map[string][]amassdns.ResolverStats {
+	stats := make(map[string][]amassdns.ResolverStats, len(l.forwarders))
+
+	for pool, fwd := range l.forwarders {
+		stats[pool] = fwd.Stats()
+	}
+	return stats
+}
+
 // Cache implements the System interface.
 func (l *LocalSystem) Cache() *requests.ASNCache {
 	return l.cache
@@ -138,6 +170,12 @@ func (l *LocalSystem) AddAndStart(srv service.Service) error {
 	return err
 }
 
+// RemoveSource implements the System interface.
+func (l *LocalSystem) RemoveSource(srv service.Service) error {
+	l.removeSource <- srv
+	return nil
+}
+
 // DataSources implements the System interface.
 func (l *LocalSystem) DataSources() []service.Service {
 	ch := make(chan []service.Service, 2)
@@ -202,6 +240,9 @@ func (l *LocalSystem) Shutdown() error {
 
 	l.pool.Stop()
 	l.trusted.Stop()
+	for _, f := range l.forwarders {
+		f.Close()
+	}
 	l.cache = nil
 	return nil
 }
@@ -267,6 +308,13 @@ func (l *LocalSystem) manageDataSources() {
 			sort.Slice(dataSources, func(i, j int) bool {
 				return dataSources[i].String() < dataSources[j].String()
 			})
+		case remove := <-l.removeSource:
+			for i, src := range dataSources {
+				if src == remove {
+					dataSources = append(dataSources[:i], dataSources[i+1:]...)
+					break
+				}
+			}
 		case all := <-l.allSources:
 			all <- dataSources
 		}
@@ -299,27 +347,34 @@ func (l *LocalSystem) loadCacheData() error {
 	return nil
 }
 
-func trustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int) {
+func trustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int, *amassdns.Forwarder) {
 	pool := resolve.NewResolvers()
 	if cfg.MaxDNSQueries > 0 {
 		pool.SetMaxQPS(cfg.MaxDNSQueries / 2)
 	}
 
+	var fwd *amassdns.Forwarder
 	if len(cfg.TrustedResolvers) > 0 {
-		_ = pool.AddResolvers(cfg.TrustedQPS, cfg.TrustedResolvers...)
+		fwd = addResolvers(cfg, pool, cfg.TrustedQPS, cfg.TrustedResolvers)
 	} else {
 		_ = pool.AddResolvers(cfg.TrustedQPS, config.DefaultBaselineResolvers...)
 		pool.SetDetectionResolver(cfg.TrustedQPS, "8.8.8.8")
 	}
 
+	timeout := time.Second
+	if fwd != nil {
+		// The queries take longer to make it through the encrypted resolvers
+		timeout = 3 * time.Second
+	}
+
 	pool.SetLogger(cfg.Log)
-	pool.SetTimeout(time.Second)
-	return pool, pool.Len()
+	pool.SetTimeout(timeout)
+	return pool, pool.Len(), fwd
 }
 
-func untrustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int) {
+func untrustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int, *amassdns.Forwarder) {
 	if max <= 0 {
-		return nil, 0
+		return nil, 0, nil
 	}
 	if len(cfg.Resolvers) == 0 {
 		cfg.Resolvers = publicResolverAddrs(cfg)
@@ -329,6 +384,11 @@ func untrustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int) {
 		}
 	}
 	cfg.Resolvers = checkAddresses(cfg.Resolvers)
+	// The local server of each forwarded resolver uses a UDP and a TCP socket, and the queries
+	// sent to the resolver use another
+	if cfg.ResolverMetrics {
+		max /= 3
+	}
 
 	if len(cfg.Resolvers) > max {
 		cfg.Resolvers = cfg.Resolvers[:max]
@@ -339,7 +399,7 @@ func untrustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int) {
 	if cfg.MaxDNSQueries > 0 {
 		pool.SetMaxQPS(cfg.MaxDNSQueries / 2)
 	}
-	_ = pool.AddResolvers(cfg.ResolversQPS, cfg.Resolvers...)
+	fwd := addResolvers(cfg, pool, cfg.ResolversQPS, cfg.Resolvers)
 	pool.SetTimeout(3 * time.Second)
 	pool.SetThresholdOptions(&resolve.ThresholdOptions{
 		ThresholdValue:      20,
@@ -350,7 +410,58 @@ func untrustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int) {
 		CountQueryRefusals:  true,
 	})
 	pool.ClientSubnetCheck()
-	return pool, pool.Len()
+	return pool, pool.Len(), fwd
+}
+
+// Adds the resolvers to the pool. The queries for DNS-over-HTTPS and DNS-over-TLS resolvers
+// are sent to a local forwarder, since the pool only sends plain DNS queries. The queries for
+// the plain DNS resolvers are sent to the forwarder as well when the resolver metrics are
+// requested, since the pool does not report the queries and timeouts of each resolver.
+func addResolvers(cfg *config.Config, pool *resolve.Resolvers, qps int, addrs []string) *amassdns.Forwarder {
+	var plain, forwarded []string
+	for _, addr := range addrs {
+		if cfg.ResolverMetrics || amassdns.IsEncryptedResolver(addr) {
+			forwarded = append(forwarded, addr)
+		} else {
+			plain = append(plain, addr)
+		}
+	}
+
+	if len(plain) > 0 {
+		_ = pool.AddResolvers(qps, plain...)
+	}
+	if len(forwarded) == 0 {
+		return nil
+	}
+
+	fwd, err := amassdns.NewForwarder(qps, cfg.DoHQPS, cfg.DoTQPS, forwarded...)
+	if err != nil {
+		cfg.Log.Printf("%v", err)
+		return nil
+	}
+
+	// Each forwarded resolver has its own address, so a failing resolver is removed from the pool
+	// without the others, and the QPS of the pool follows the limits of the forwarded resolvers
+	for addr, qps := range fwd.Addrs() {
+		_ = pool.AddResolvers(qps, addr)
+	}
+	return fwd
+}
+
+// Returns the queries per second that can be sent by the pool of num resolvers, where the
+// resolvers reached through the forwarder are limited by the QPS of the forwarded resolvers.
+func poolQPS(num, qps int, fwd *amassdns.Forwarder) int {
+	if fwd == nil {
+		return num * qps
+	}
+	return forwardedPoolQPS(num, len(fwd.Addrs()), qps, fwd.QPS())
+}
+
+// The pool holds the addresses of the local servers, one for each forwarded resolver or a single
+// server shared by all of them, instead of the forwarded resolvers. The other resolvers in the
+// pool accept qps queries per second each.
+func forwardedPoolQPS(num, locals, qps, fwdQPS int) int {
+	return (num-locals)*qps + fwdQPS
 }
 
 func publicResolverAddrs(cfg *config.Config) []string {
@@ -369,6 +480,13 @@ func checkAddresses(addrs []string) []string {
 	ips := []string{}
 
 	for _, addr := range addrs {
+		if amassdns.IsEncryptedResolver(addr) {
+			if u, err := amassdns.ParseEncryptedResolver(addr); err == nil {
+				ips = append(ips, u.String())
+			}
+			continue
+		}
+
 		ip, port, err := net.SplitHostPort(addr)
 		if err != nil {
 			ip = addr
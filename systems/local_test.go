@@ -3,6 +3,9 @@ package systems
 import (
 	"reflect"
 	"testing"
+
+	"github.com/OWASP/Amass/v3/config"
+	"github.com/caffix/resolve"
 )
 
 func TestCheckAddresses(t *testing.T) {
@@ -41,6 +44,11 @@ func TestCheckAddresses(t *testing.T) {
 			addr:     []string{"192.168.61.221", "NotAnIP:80", "111.111.111.111:111"},
 			expected: []string{"192.168.61.221:53", "111.111.111.111:111"},
 		},
+		{
+			name:     "Encrypted resolver URLs",
+			addr:     []string{"https://dns.google", "tls://1.1.1.1", "tls://dns.quad9.net:8853", "quic://dns.adguard.com"},
+			expected: []string{"https://dns.google/dns-query", "tls://1.1.1.1:853", "tls://dns.quad9.net:8853"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -52,3 +60,67 @@ func TestCheckAddresses(t *testing.T) {
 		})
 	}
 }
+
+func TestAddResolvers(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.DoHQPS = 20
+	cfg.DoTQPS = 30
+
+	pool := resolve.NewResolvers()
+	defer pool.Stop()
+
+	fwd := addResolvers(cfg, pool, 10, []string{"192.0.2.1:53", "https://192.0.2.2/dns-query", "tls://192.0.2.3:853"})
+	if fwd == nil {
+		t.Fatal("The forwarder was not started for the encrypted resolvers")
+	}
+	defer fwd.Close()
+
+	// The QPS is the same whether the encrypted resolvers have their own local servers or share one
+	if qps := poolQPS(pool.Len(), 10, fwd); qps != 60 {
+		t.Errorf("The QPS of the encrypted resolvers was not used: %d", qps)
+	}
+	if qps := poolQPS(2, 10, nil); qps != 20 {
+		t.Errorf("Unexpected QPS for the plain resolvers: %d", qps)
+	}
+
+	if len(fwd.Addrs()) != fwd.Len() {
+		t.Skip("The system does not provide a loopback address for each encrypted resolver")
+	}
+	// The encrypted resolvers can be removed from the pool separately
+	if n := pool.Len(); n != 3 {
+		t.Errorf("Expected each resolver to be added to the pool, got %d", n)
+	}
+}
+
+func TestForwardedPoolQPS(t *testing.T) {
+	// Two plain resolvers and a local server for each of the two encrypted resolvers
+	if qps := forwardedPoolQPS(4, 2, 10, 50); qps != 70 {
+		t.Errorf("Unexpected QPS with a local server for each encrypted resolver: %d", qps)
+	}
+	// Two plain resolvers and a single local server shared by the encrypted resolvers
+	if qps := forwardedPoolQPS(3, 1, 10, 50); qps != 70 {
+		t.Errorf("Unexpected QPS with a shared local server: %d", qps)
+	}
+}
+
+func TestAddResolversWithMetrics(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.ResolverMetrics = true
+
+	pool := resolve.NewResolvers()
+	defer pool.Stop()
+
+	fwd := addResolvers(cfg, pool, 10, []string{"192.0.2.1:53", "192.0.2.2", "tls://192.0.2.3:853"})
+	if fwd == nil {
+		t.Fatal("The forwarder was not started for the resolvers")
+	}
+	defer fwd.Close()
+
+	// The plain resolvers are counted by the forwarder as well
+	if n := len(fwd.Stats()); n != 3 {
+		t.Errorf("Expected the forwarder to count the queries of each resolver, got %d", n)
+	}
+	if n := pool.Len(); n != len(fwd.Addrs()) {
+		t.Errorf("Expected the pool to send the queries through the forwarder, got %d resolvers", n)
+	}
+}
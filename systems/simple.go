@@ -49,6 +49,14 @@ func (ss *SimpleSystem) AddAndStart(srv service.Service) error {
 	return err
 }
 
+// RemoveSource implements the System interface.
+func (ss *SimpleSystem) RemoveSource(srv service.Service) error {
+	if ss.Service == srv {
+		ss.Service = nil
+	}
+	return nil
+}
+
 // DataSources implements the System interface.
 func (ss *SimpleSystem) DataSources() []service.Service { return []service.Service{ss.Service} }
 
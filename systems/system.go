@@ -35,6 +35,9 @@ type System interface {
 	// AddAndStart starts the provided data source and then appends it to the slice of sources
 	AddAndStart(srv service.Service) error
 
+	// RemoveSource removes the provided data source from the slice of sources managed by the System
+	RemoveSource(srv service.Service) error
+
 	// DataSources returns the slice of data sources managed by the System
 	DataSources() []service.Service
 